@@ -32,6 +32,13 @@ type BoshManifest struct {
 	Variables  []Variable             `yaml:"variables,omitempty" json:"variables,omitempty"`
 	Tags       map[string]interface{} `yaml:"tags,omitempty" json:"tags,omitempty"`
 	Features   BoshFeatures           `yaml:"features,omitempty" json:"features,omitempty"`
+
+	// Extra holds any keys the SDK does not model, so that they survive
+	// unmarshalling and re-marshalling a manifest. Every other type in the
+	// manifest has an equivalent field, except for the types that are
+	// comparable with ==. Those keep their unmodelled keys outside of the
+	// struct and return them from their Extra method.
+	Extra map[string]interface{} `yaml:",inline" json:"-"`
 }
 
 type BoshFeatures struct {
@@ -47,6 +54,29 @@ func BoolPointer(val bool) *bool {
 
 type PlacementRuleStemcell struct {
 	OS string `yaml:"os"`
+
+	extra *extraKeys
+}
+
+type placementRuleStemcellAlias PlacementRuleStemcell
+
+// Extra returns the keys the SDK does not model.
+func (s PlacementRuleStemcell) Extra() map[string]interface{} {
+	return s.extra.get()
+}
+
+func (s PlacementRuleStemcell) MarshalYAML() (interface{}, error) {
+	return withExtra[placementRuleStemcellAlias]{placementRuleStemcellAlias(s), s.extra.get()}, nil
+}
+
+func (s *PlacementRuleStemcell) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v withExtra[placementRuleStemcellAlias]
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	*s = PlacementRuleStemcell(v.Modelled)
+	s.extra = newExtraKeys(v.Extra)
+	return nil
 }
 
 type PlacementRule struct {
@@ -56,13 +86,15 @@ type PlacementRule struct {
 	InstanceGroups []string                `yaml:"instance_groups,omitempty"`
 	Networks       []string                `yaml:"networks,omitempty"`
 	Teams          []string                `yaml:"teams,omitempty"`
+	Extra          map[string]interface{}  `yaml:",inline" json:"-"`
 }
 
 type Addon struct {
-	Name    string        `yaml:"name"`
-	Jobs    []Job         `yaml:"jobs"`
-	Include PlacementRule `yaml:"include,omitempty"`
-	Exclude PlacementRule `yaml:"exclude,omitempty"`
+	Name    string                 `yaml:"name"`
+	Jobs    []Job                  `yaml:"jobs"`
+	Include PlacementRule          `yaml:"include,omitempty"`
+	Exclude PlacementRule          `yaml:"exclude,omitempty"`
+	Extra   map[string]interface{} `yaml:",inline" json:"-"`
 }
 
 // Variable represents a variable in the `variables` block of a BOSH manifest
@@ -81,28 +113,110 @@ type Variable struct {
 	//
 	// Requires BOSH v267+
 	Consumes *VariableConsumes `yaml:"consumes,omitempty"`
+
+	Extra map[string]interface{} `yaml:",inline" json:"-"`
 }
 
 type VariableConsumes struct {
-	AlternativeName VariableConsumesLink `yaml:"alternative_name,omitempty"`
-	CommonName      VariableConsumesLink `yaml:"common_name,omitempty"`
+	AlternativeName VariableConsumesLink   `yaml:"alternative_name,omitempty"`
+	CommonName      VariableConsumesLink   `yaml:"common_name,omitempty"`
+	Extra           map[string]interface{} `yaml:",inline" json:"-"`
 }
 
 type VariableConsumesLink struct {
 	From       string                 `yaml:"from"`
 	Properties map[string]interface{} `yaml:"properties,omitempty"`
+	Extra      map[string]interface{} `yaml:",inline" json:"-"`
 }
 
 type Release struct {
 	Name    string `yaml:"name"`
 	Version string `yaml:"version"`
+	URL     string `yaml:"url,omitempty"`
+	SHA1    string `yaml:"sha1,omitempty"`
+	// Stemcell is the stemcell a compiled release was compiled against.
+	Stemcell *ReleaseStemcell `yaml:"stemcell,omitempty"`
+
+	extra *extraKeys
+}
+
+type releaseAlias Release
+
+// Extra returns the keys the SDK does not model.
+func (r Release) Extra() map[string]interface{} {
+	return r.extra.get()
+}
+
+func (r Release) MarshalYAML() (interface{}, error) {
+	return withExtra[releaseAlias]{releaseAlias(r), r.extra.get()}, nil
+}
+
+func (r *Release) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v withExtra[releaseAlias]
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	*r = Release(v.Modelled)
+	r.extra = newExtraKeys(v.Extra)
+	return nil
+}
+
+type ReleaseStemcell struct {
+	OS      string `yaml:"os"`
+	Version string `yaml:"version"`
+
+	extra *extraKeys
+}
+
+type releaseStemcellAlias ReleaseStemcell
+
+// Extra returns the keys the SDK does not model.
+func (s ReleaseStemcell) Extra() map[string]interface{} {
+	return s.extra.get()
+}
+
+func (s ReleaseStemcell) MarshalYAML() (interface{}, error) {
+	return withExtra[releaseStemcellAlias]{releaseStemcellAlias(s), s.extra.get()}, nil
+}
+
+func (s *ReleaseStemcell) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v withExtra[releaseStemcellAlias]
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	*s = ReleaseStemcell(v.Modelled)
+	s.extra = newExtraKeys(v.Extra)
+	return nil
 }
 
 type Stemcell struct {
 	Alias   string `yaml:"alias"`
-	OS      string `yaml:"os"`
+	OS      string `yaml:"os,omitempty"`
 	Version string `yaml:"version"`
 	Name    string `yaml:"name,omitempty"`
+
+	extra *extraKeys
+}
+
+type stemcellAlias Stemcell
+
+// Extra returns the keys the SDK does not model.
+func (s Stemcell) Extra() map[string]interface{} {
+	return s.extra.get()
+}
+
+func (s Stemcell) MarshalYAML() (interface{}, error) {
+	return withExtra[stemcellAlias]{stemcellAlias(s), s.extra.get()}, nil
+}
+
+func (s *Stemcell) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v withExtra[stemcellAlias]
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	*s = Stemcell(v.Modelled)
+	s.extra = newExtraKeys(v.Extra)
+	return nil
 }
 
 type InstanceGroup struct {
@@ -110,10 +224,11 @@ type InstanceGroup struct {
 	Lifecycle          string    `yaml:"lifecycle,omitempty"`
 	Instances          int       `yaml:"instances"`
 	Jobs               []Job     `yaml:"jobs,omitempty"`
-	VMType             string    `yaml:"vm_type"`
+	VMType             string    `yaml:"vm_type,omitempty"`
 	VMExtensions       []string  `yaml:"vm_extensions,omitempty"`
 	Stemcell           string    `yaml:"stemcell"`
 	PersistentDiskType string    `yaml:"persistent_disk_type,omitempty"`
+	PersistentDisk     int       `yaml:"persistent_disk,omitempty"`
 	AZs                []string  `yaml:"azs,omitempty"`
 	Networks           []Network `yaml:"networks"`
 	// DEPRECATED: BOSH deprecated instance_group level "properties". Use Job properties instead.
@@ -121,16 +236,42 @@ type InstanceGroup struct {
 	MigratedFrom []Migration            `yaml:"migrated_from,omitempty"`
 	Env          map[string]interface{} `yaml:"env,omitempty"`
 	Update       *Update                `yaml:"update,omitempty"`
+	Extra        map[string]interface{} `yaml:",inline" json:"-"`
 }
 
 type Migration struct {
 	Name string `yaml:"name"`
+	AZ   string `yaml:"az,omitempty"`
+
+	extra *extraKeys
+}
+
+type migrationAlias Migration
+
+// Extra returns the keys the SDK does not model.
+func (m Migration) Extra() map[string]interface{} {
+	return m.extra.get()
+}
+
+func (m Migration) MarshalYAML() (interface{}, error) {
+	return withExtra[migrationAlias]{migrationAlias(m), m.extra.get()}, nil
+}
+
+func (m *Migration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v withExtra[migrationAlias]
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	*m = Migration(v.Modelled)
+	m.extra = newExtraKeys(v.Extra)
+	return nil
 }
 
 type Network struct {
-	Name      string   `yaml:"name"`
-	StaticIPs []string `yaml:"static_ips,omitempty"`
-	Default   []string `yaml:"default,omitempty"`
+	Name      string                 `yaml:"name"`
+	StaticIPs []string               `yaml:"static_ips,omitempty"`
+	Default   []string               `yaml:"default,omitempty"`
+	Extra     map[string]interface{} `yaml:",inline" json:"-"`
 }
 
 // MaxInFlightValue holds a value of one of these types:
@@ -155,31 +296,41 @@ type Update struct {
 	VmStrategy      string           `yaml:"vm_strategy,omitempty"`
 	// See bosh.SerialUpdate and bosh.ParallelUpdate
 	InitialDeployAZUpdateStrategy UpdateStrategy `yaml:"initial_deploy_az_update_strategy,omitempty"`
+
+	extra *extraKeys
 }
 
 type updateAlias Update
 
+// Extra returns the keys the SDK does not model.
+func (u *Update) Extra() map[string]interface{} {
+	if u == nil {
+		return nil
+	}
+	return u.extra.get()
+}
+
 func (u *Update) MarshalYAML() (interface{}, error) {
-	if u != nil {
-		if err := ValidateMaxInFlight(u.MaxInFlight); err != nil {
-			return []byte{}, err
-		}
+	if u == nil {
+		return (*updateAlias)(u), nil
 	}
 
-	return (*updateAlias)(u), nil
+	if err := ValidateMaxInFlight(u.MaxInFlight); err != nil {
+		return []byte{}, err
+	}
+
+	return withExtra[updateAlias]{updateAlias(*u), u.extra.get()}, nil
 }
 
 func (u *Update) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	err := unmarshal((*updateAlias)(u))
-	if err != nil {
+	var v withExtra[updateAlias]
+	if err := unmarshal(&v); err != nil {
 		return err
 	}
+	*u = Update(v.Modelled)
+	u.extra = newExtraKeys(v.Extra)
 
-	if u != nil {
-		return ValidateMaxInFlight(u.MaxInFlight)
-	}
-
-	return nil
+	return ValidateMaxInFlight(u.MaxInFlight)
 }
 
 func ValidateMaxInFlight(m MaxInFlightValue) error {
@@ -16,6 +16,7 @@
 package bosh_test
 
 import (
+	"encoding/json"
 	"errors"
 	"io"
 	"os"
@@ -403,6 +404,87 @@ var _ = Describe("(de)serialising BOSH manifests", func() {
 		Entry("a non percentage string", "some instances", errors.New("MaxInFlight must be either an integer or a percentage. Got some instances")),
 	)
 
+	DescribeTable(
+		"round-tripping real-world manifests",
+		func(fixture string) {
+			original, err := os.ReadFile(filepath.Join("fixtures", "round_trip", fixture))
+			Expect(err).NotTo(HaveOccurred())
+
+			var manifest bosh.BoshManifest
+			Expect(yaml.Unmarshal(original, &manifest)).To(Succeed())
+
+			remarshalled, err := yaml.Marshal(manifest)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remarshalled).To(MatchYAML(original))
+		},
+		Entry("a service instance with unmodelled keys", "redis.yml"),
+		Entry("a manifest with addons and unmodelled keys", "addons.yml"),
+	)
+
+	It("captures unmodelled keys in Extra", func() {
+		original, err := os.ReadFile(filepath.Join("fixtures", "round_trip", "redis.yml"))
+		Expect(err).NotTo(HaveOccurred())
+
+		var manifest bosh.BoshManifest
+		Expect(yaml.Unmarshal(original, &manifest)).To(Succeed())
+
+		Expect(manifest.Extra).To(HaveKey("exodus"))
+		Expect(manifest.Releases[0].SHA1).To(Equal("6b1d8c2ed6a5a4f1a2b0e0f3a5c7d9e1b3f5a7c9"))
+		Expect(manifest.Releases[1].Stemcell).To(Equal(&bosh.ReleaseStemcell{OS: "ubuntu-jammy", Version: "1.200"}))
+		Expect(manifest.InstanceGroups[0].PersistentDisk).To(Equal(10240))
+		Expect(manifest.InstanceGroups[1].Extra).To(HaveKey("vm_resources"))
+		Expect(manifest.InstanceGroups[0].Update.Extra()).To(HaveKeyWithValue("strategy", "legacy"))
+		Expect(manifest.Variables[2].Consumes.AlternativeName.Extra).To(HaveKeyWithValue("link_name_hint", "primary"))
+		Expect(manifest.Features.ExtraFeatures).To(HaveKeyWithValue("use_tmpfs_config", true))
+	})
+
+	It("omits an empty vm_type and stemcell os", func() {
+		content, err := yaml.Marshal(bosh.BoshManifest{
+			Stemcells:      []bosh.Stemcell{{Alias: "default", Name: "bosh-google-kvm-ubuntu-jammy-go_agent", Version: "1.200"}},
+			InstanceGroups: []bosh.InstanceGroup{{Name: "redis", Extra: map[string]interface{}{"vm_resources": map[string]interface{}{"cpu": 1}}}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).NotTo(ContainSubstring("vm_type"))
+		Expect(string(content)).NotTo(ContainSubstring("os:"))
+	})
+
+	It("keeps comparable types comparable and leaves Extra out of JSON", func() {
+		Expect(bosh.Release{Name: "redis", Version: "1"} == bosh.Release{Name: "redis", Version: "1"}).To(BeTrue())
+		Expect(bosh.Migration{Name: "redis", AZ: "z1"} == bosh.Migration{Name: "redis", AZ: "z1"}).To(BeTrue())
+
+		var stemcells []bosh.Stemcell
+		Expect(yaml.Unmarshal([]byte("- alias: default\n  os: ubuntu-jammy\n  version: latest\n  api_version: 3\n"), &stemcells)).To(Succeed())
+		Expect(stemcells[0] == stemcells[0]).To(BeTrue())
+		Expect(stemcells[0].Extra()).To(HaveKeyWithValue("api_version", 3))
+
+		content, err := yaml.Marshal(stemcells)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(ContainSubstring("api_version: 3"))
+
+		content, err = json.Marshal(bosh.InstanceGroup{Name: "redis", Extra: map[string]interface{}{"persistent_disk_pool": "fast"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).NotTo(ContainSubstring("Extra"))
+		Expect(string(content)).NotTo(ContainSubstring("persistent_disk_pool"))
+	})
+
+	It("emits keys set in Extra", func() {
+		manifest := bosh.BoshManifest{
+			Name: "a-deployment",
+			InstanceGroups: []bosh.InstanceGroup{{
+				Name:     "redis",
+				Networks: []bosh.Network{{Name: "default", Extra: map[string]interface{}{"nic_group": 1}}},
+				Extra:    map[string]interface{}{"persistent_disk_pool": "fast"},
+			}},
+			Extra: map[string]interface{}{"exodus": map[string]interface{}{"key": "value"}},
+		}
+
+		content, err := yaml.Marshal(manifest)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(ContainSubstring("persistent_disk_pool: fast"))
+		Expect(string(content)).To(ContainSubstring("nic_group: 1"))
+		Expect(string(content)).To(ContainSubstring("exodus:\n  key: value"))
+	})
+
 	When("a stemcell name has been configured", func() {
 		It("correctly handles a manifest including a stemcell name property", func() {
 			tmpl, err := template.ParseFiles(filepath.Join("fixtures", "manifest_template.yml"))
@@ -0,0 +1,332 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bosh
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// Change is a single difference between two manifests. Path uses ops-file
+// syntax, with list items identified by name (or alias, for stemcells), e.g.
+// /instance_groups/name=redis/jobs/name=redis/properties/port
+type Change struct {
+	Path     string
+	Kind     ChangeKind
+	Previous interface{}
+	Current  interface{}
+
+	segments []pathSegment
+}
+
+type ManifestDiff struct {
+	Changes []Change
+}
+
+type pathSegment struct {
+	key        string
+	matchKey   string
+	matchValue string
+}
+
+// DiffManifests reports what changes between previous and current. A nil
+// previous manifest is treated as empty, so everything in current is added.
+func DiffManifests(previous *BoshManifest, current BoshManifest) (ManifestDiff, error) {
+	previousTree := interface{}(map[interface{}]interface{}{})
+	if previous != nil {
+		var err error
+		if previousTree, err = toTree(*previous); err != nil {
+			return ManifestDiff{}, fmt.Errorf("converting previous manifest: %s", err)
+		}
+	}
+
+	currentTree, err := toTree(current)
+	if err != nil {
+		return ManifestDiff{}, fmt.Errorf("converting current manifest: %s", err)
+	}
+
+	var diff ManifestDiff
+	diff.compare(nil, previousTree, currentTree)
+	return diff, nil
+}
+
+func (d ManifestDiff) HasChanges() bool {
+	return len(d.Changes) > 0
+}
+
+// Filter returns the changes at or below pattern, where "*" in pattern
+// matches any single path segment, e.g. /instance_groups/*/instances
+func (d ManifestDiff) Filter(pattern string) ManifestDiff {
+	patternSegments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+
+	var filtered ManifestDiff
+	for _, change := range d.Changes {
+		if len(change.segments) < len(patternSegments) {
+			continue
+		}
+		matches := true
+		for i, patternSegment := range patternSegments {
+			if patternSegment != "*" && patternSegment != change.segments[i].String() {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			filtered.Changes = append(filtered.Changes, change)
+		}
+	}
+	return filtered
+}
+
+// String renders the diff in the style of `bosh deploy`.
+func (d ManifestDiff) String() string {
+	var out strings.Builder
+	var printed []pathSegment
+
+	for _, change := range d.Changes {
+		parents := change.segments[:len(change.segments)-1]
+		last := change.segments[len(change.segments)-1]
+
+		common := 0
+		for common < len(printed) && common < len(parents) && printed[common] == parents[common] {
+			common++
+		}
+		for i := common; i < len(parents); i++ {
+			writeLines(&out, " ", indentAt(change.segments, i), parents[i].contextLine())
+		}
+		printed = parents
+
+		indent := indentAt(change.segments, len(parents))
+		if change.Kind != Added {
+			writeLines(&out, "-", indent, last.render(change.Previous))
+		}
+		if change.Kind != Removed {
+			writeLines(&out, "+", indent, last.render(change.Current))
+		}
+	}
+	return out.String()
+}
+
+func (d *ManifestDiff) compare(path []pathSegment, previous, current interface{}) {
+	if reflect.DeepEqual(previous, current) {
+		return
+	}
+
+	previousMap, previousIsMap := previous.(map[interface{}]interface{})
+	currentMap, currentIsMap := current.(map[interface{}]interface{})
+	if previousIsMap && currentIsMap {
+		for _, key := range sortedKeys(previousMap, currentMap) {
+			previousValue, inPrevious := previousMap[key]
+			currentValue, inCurrent := currentMap[key]
+			d.compareEntry(appendSegment(path, pathSegment{key: fmt.Sprint(key)}), previousValue, inPrevious, currentValue, inCurrent)
+		}
+		return
+	}
+
+	previousList, previousIsList := previous.([]interface{})
+	currentList, currentIsList := current.([]interface{})
+	if previousIsList && currentIsList {
+		if identity := listIdentity(previousList, currentList); identity != "" {
+			d.compareNamedLists(path, identity, previousList, currentList)
+			return
+		}
+	}
+
+	d.add(path, Changed, previous, current)
+}
+
+func (d *ManifestDiff) compareEntry(path []pathSegment, previous interface{}, inPrevious bool, current interface{}, inCurrent bool) {
+	switch {
+	case !inPrevious:
+		d.add(path, Added, nil, current)
+	case !inCurrent:
+		d.add(path, Removed, previous, nil)
+	default:
+		d.compare(path, previous, current)
+	}
+}
+
+func (d *ManifestDiff) compareNamedLists(path []pathSegment, identity string, previous, current []interface{}) {
+	previousByName := map[string]interface{}{}
+	for _, item := range previous {
+		previousByName[itemName(item, identity)] = item
+	}
+	currentNames := map[string]bool{}
+
+	for _, item := range current {
+		name := itemName(item, identity)
+		currentNames[name] = true
+		previousItem, inPrevious := previousByName[name]
+		d.compareEntry(appendSegment(path, pathSegment{matchKey: identity, matchValue: name}), previousItem, inPrevious, item, true)
+	}
+	for _, item := range previous {
+		if name := itemName(item, identity); !currentNames[name] {
+			d.add(appendSegment(path, pathSegment{matchKey: identity, matchValue: name}), Removed, item, nil)
+		}
+	}
+}
+
+func (d *ManifestDiff) add(path []pathSegment, kind ChangeKind, previous, current interface{}) {
+	d.Changes = append(d.Changes, Change{
+		Path:     pathString(path),
+		Kind:     kind,
+		Previous: previous,
+		Current:  current,
+		segments: path,
+	})
+}
+
+func pathString(path []pathSegment) string {
+	var segments []string
+	for _, segment := range path {
+		segments = append(segments, segment.String())
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+func (s pathSegment) String() string {
+	escape := strings.NewReplacer("~", "~0", "/", "~1").Replace
+	if s.matchKey != "" {
+		return s.matchKey + "=" + escape(s.matchValue)
+	}
+	return escape(s.key)
+}
+
+func (s pathSegment) contextLine() string {
+	if s.matchKey != "" {
+		return fmt.Sprintf("- %s: %s", s.matchKey, s.matchValue)
+	}
+	return s.key + ":"
+}
+
+func (s pathSegment) render(value interface{}) string {
+	var rendered []byte
+	if s.matchKey != "" {
+		rendered, _ = yaml.Marshal([]interface{}{value})
+	} else {
+		rendered, _ = yaml.Marshal(map[string]interface{}{s.key: value})
+	}
+	return strings.TrimSuffix(string(rendered), "\n")
+}
+
+// indentAt returns the indentation of the i-th segment of a path. List items
+// are indented at the same level as the key holding the list.
+func indentAt(segments []pathSegment, i int) int {
+	indent := 0
+	for j := 1; j <= i; j++ {
+		if segments[j].matchKey == "" || segments[j-1].matchKey != "" {
+			indent += 2
+		}
+	}
+	return indent
+}
+
+func writeLines(out *strings.Builder, marker string, indent int, text string) {
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(out, "%s %s%s\n", marker, strings.Repeat(" ", indent), line)
+	}
+}
+
+func toTree(manifest BoshManifest) (tree interface{}, err error) {
+	defer func() {
+		// yaml.Marshal panics on some invalid values, e.g. duplicate keys
+		if v := recover(); v != nil {
+			err = fmt.Errorf("%v", v)
+		}
+	}()
+
+	manifestYAML, err := yaml.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.Unmarshal(manifestYAML, &tree)
+	return tree, err
+}
+
+func appendSegment(path []pathSegment, segments ...pathSegment) []pathSegment {
+	return append(path[:len(path):len(path)], segments...)
+}
+
+// sortedKeys returns the keys of maps ordered by their string form. Keys
+// keep their type, as YAML keys need not be strings.
+func sortedKeys(maps ...map[interface{}]interface{}) []interface{} {
+	seen := map[interface{}]bool{}
+	var keys []interface{}
+	for _, m := range maps {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ki, kj := fmt.Sprint(keys[i]), fmt.Sprint(keys[j])
+		if ki != kj {
+			return ki < kj
+		}
+		return fmt.Sprintf("%T", keys[i]) < fmt.Sprintf("%T", keys[j])
+	})
+	return keys
+}
+
+// listIdentity returns the key that uniquely identifies the items of both
+// lists, or "" when the items cannot be matched up.
+func listIdentity(lists ...[]interface{}) string {
+	for _, identity := range []string{"name", "alias"} {
+		if identifiedBy(identity, lists...) {
+			return identity
+		}
+	}
+	return ""
+}
+
+func identifiedBy(identity string, lists ...[]interface{}) bool {
+	for _, list := range lists {
+		seen := map[string]bool{}
+		for _, item := range list {
+			m, ok := item.(map[interface{}]interface{})
+			if !ok {
+				return false
+			}
+			if _, ok := m[identity]; !ok {
+				return false
+			}
+			name := itemName(item, identity)
+			if seen[name] {
+				return false
+			}
+			seen[name] = true
+		}
+	}
+	return true
+}
+
+func itemName(item interface{}, identity string) string {
+	return fmt.Sprint(item.(map[interface{}]interface{})[identity])
+}
@@ -0,0 +1,209 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bosh_test
+
+import (
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DiffManifests", func() {
+	var previous, current bosh.BoshManifest
+
+	manifest := func() bosh.BoshManifest {
+		return bosh.BoshManifest{
+			Name:      "redis",
+			Releases:  []bosh.Release{{Name: "redis", Version: "1.0.0"}},
+			Stemcells: []bosh.Stemcell{{Alias: "default", OS: "ubuntu-jammy", Version: "1.1"}},
+			InstanceGroups: []bosh.InstanceGroup{
+				{
+					Name:               "redis",
+					Instances:          3,
+					VMType:             "small",
+					Stemcell:           "default",
+					PersistentDiskType: "10GB",
+					AZs:                []string{"z1"},
+					Networks:           []bosh.Network{{Name: "default"}},
+					Jobs: []bosh.Job{
+						{Name: "redis", Release: "redis", Properties: map[string]interface{}{"port": 6379}},
+					},
+				},
+			},
+			Update: &bosh.Update{Canaries: 1, CanaryWatchTime: "1000-30000", UpdateWatchTime: "1000-30000", MaxInFlight: 1},
+		}
+	}
+
+	BeforeEach(func() {
+		previous = manifest()
+		current = manifest()
+	})
+
+	diff := func() bosh.ManifestDiff {
+		d, err := bosh.DiffManifests(&previous, current)
+		Expect(err).NotTo(HaveOccurred())
+		return d
+	}
+
+	It("reports no changes for identical manifests", func() {
+		Expect(diff().HasChanges()).To(BeFalse())
+	})
+
+	It("reports every top-level key as added when there is no previous manifest", func() {
+		d, err := bosh.DiffManifests(nil, current)
+		Expect(err).NotTo(HaveOccurred())
+
+		var paths []string
+		for _, change := range d.Changes {
+			Expect(change.Kind).To(Equal(bosh.Added))
+			paths = append(paths, change.Path)
+		}
+		Expect(paths).To(Equal([]string{"/instance_groups", "/name", "/releases", "/stemcells", "/update"}))
+	})
+
+	It("reports changed instance group properties by instance group name", func() {
+		current.InstanceGroups[0].Instances = 1
+		current.InstanceGroups[0].PersistentDiskType = "5GB"
+
+		Expect(diff().Changes).To(ConsistOf(
+			matchChange(bosh.Change{Path: "/instance_groups/name=redis/instances", Kind: bosh.Changed, Previous: 3, Current: 1}),
+			matchChange(bosh.Change{Path: "/instance_groups/name=redis/persistent_disk_type", Kind: bosh.Changed, Previous: "10GB", Current: "5GB"}),
+		))
+	})
+
+	It("reports added and removed instance groups", func() {
+		current.InstanceGroups[0].Name = "redis-server"
+
+		Expect(diff().Changes).To(ConsistOf(
+			matchChange(bosh.Change{Path: "/instance_groups/name=redis-server", Kind: bosh.Added}),
+			matchChange(bosh.Change{Path: "/instance_groups/name=redis", Kind: bosh.Removed}),
+		))
+	})
+
+	It("reports added jobs and changed job properties", func() {
+		current.InstanceGroups[0].Jobs[0].Properties["port"] = 6380
+		current.InstanceGroups[0].Jobs = append(current.InstanceGroups[0].Jobs, bosh.Job{Name: "sentinel", Release: "redis"})
+
+		Expect(diff().Changes).To(ConsistOf(
+			matchChange(bosh.Change{Path: "/instance_groups/name=redis/jobs/name=redis/properties/port", Kind: bosh.Changed, Previous: 6379, Current: 6380}),
+			matchChange(bosh.Change{Path: "/instance_groups/name=redis/jobs/name=sentinel", Kind: bosh.Added}),
+		))
+	})
+
+	It("reports changes under non-string keys", func() {
+		previous.InstanceGroups[0].Jobs[0].Properties["ports"] = map[interface{}]interface{}{1: "x"}
+		current.InstanceGroups[0].Jobs[0].Properties["ports"] = map[interface{}]interface{}{1: "y"}
+
+		Expect(diff().Changes).To(ConsistOf(
+			matchChange(bosh.Change{Path: "/instance_groups/name=redis/jobs/name=redis/properties/ports/1", Kind: bosh.Changed, Previous: "x", Current: "y"}),
+		))
+	})
+
+	It("reports changed releases, stemcells, variables, update blocks and features", func() {
+		useDNS := true
+		current.Releases[0].Version = "1.1.0"
+		current.Stemcells[0].Version = "1.2"
+		current.Variables = []bosh.Variable{{Name: "admin_password", Type: "password"}}
+		current.Update.Canaries = 2
+		current.Features.UseDNSAddresses = &useDNS
+
+		Expect(diff().Changes).To(ConsistOf(
+			matchChange(bosh.Change{Path: "/features", Kind: bosh.Added}),
+			matchChange(bosh.Change{Path: "/releases/name=redis/version", Kind: bosh.Changed, Previous: "1.0.0", Current: "1.1.0"}),
+			matchChange(bosh.Change{Path: "/stemcells/alias=default/version", Kind: bosh.Changed, Previous: "1.1", Current: "1.2"}),
+			matchChange(bosh.Change{Path: "/update/canaries", Kind: bosh.Changed, Previous: 1, Current: 2}),
+			matchChange(bosh.Change{Path: "/variables", Kind: bosh.Added}),
+		))
+	})
+
+	It("reports unnamed lists as a whole", func() {
+		current.InstanceGroups[0].AZs = []string{"z1", "z2"}
+
+		Expect(diff().Changes).To(ConsistOf(
+			matchChange(bosh.Change{Path: "/instance_groups/name=redis/azs", Kind: bosh.Changed, Previous: []interface{}{"z1"}, Current: []interface{}{"z1", "z2"}}),
+		))
+	})
+
+	It("ignores reordered named lists", func() {
+		previous.Releases = append(previous.Releases, bosh.Release{Name: "bpm", Version: "1"})
+		current.Releases = []bosh.Release{{Name: "bpm", Version: "1"}, current.Releases[0]}
+
+		Expect(diff().HasChanges()).To(BeFalse())
+	})
+
+	It("filters changes by path, with wildcards", func() {
+		current.InstanceGroups[0].Instances = 1
+		current.InstanceGroups[0].Jobs[0].Properties["port"] = 6380
+		current.Releases[0].Version = "1.1.0"
+
+		instances := diff().Filter("/instance_groups/*/instances")
+		Expect(instances.Changes).To(HaveLen(1))
+		Expect(instances.Changes[0].Path).To(Equal("/instance_groups/name=redis/instances"))
+
+		Expect(diff().Filter("/instance_groups").Changes).To(HaveLen(2))
+		Expect(diff().Filter("/stemcells").HasChanges()).To(BeFalse())
+	})
+
+	It("renders changes like bosh deploy", func() {
+		current.InstanceGroups[0].Instances = 1
+		current.InstanceGroups[0].Jobs[0].Properties["port"] = 6380
+		current.InstanceGroups = append(current.InstanceGroups, bosh.InstanceGroup{
+			Name:      "errand",
+			Lifecycle: "errand",
+			Instances: 1,
+			Stemcell:  "default",
+			Networks:  []bosh.Network{{Name: "default"}},
+		})
+		current.Releases[0].Version = "1.1.0"
+
+		Expect(diff().String()).To(Equal(
+			`  instance_groups:
+  - name: redis
+-   instances: 3
++   instances: 1
+    jobs:
+    - name: redis
+      properties:
+-       port: 6379
++       port: 6380
++ - instances: 1
++   lifecycle: errand
++   name: errand
++   networks:
++   - name: default
++   stemcell: default
+  releases:
+  - name: redis
+-   version: 1.0.0
++   version: 1.1.0
+`))
+	})
+})
+
+func matchChange(expected bosh.Change) OmegaMatcher {
+	fields := []OmegaMatcher{
+		WithTransform(func(c bosh.Change) string { return c.Path }, Equal(expected.Path)),
+		WithTransform(func(c bosh.Change) bosh.ChangeKind { return c.Kind }, Equal(expected.Kind)),
+	}
+	if expected.Kind == bosh.Changed {
+		fields = append(fields,
+			WithTransform(func(c bosh.Change) interface{} { return c.Previous }, Equal(expected.Previous)),
+			WithTransform(func(c bosh.Change) interface{} { return c.Current }, Equal(expected.Current)),
+		)
+	}
+	return And(fields...)
+}
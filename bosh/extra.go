@@ -0,0 +1,44 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bosh
+
+// extraKeys holds the keys the SDK does not model for types that are
+// comparable with ==. It is referenced by pointer, because a map field would
+// make those types incomparable.
+type extraKeys struct {
+	keys map[string]interface{}
+}
+
+func newExtraKeys(keys map[string]interface{}) *extraKeys {
+	if len(keys) == 0 {
+		return nil
+	}
+	return &extraKeys{keys: keys}
+}
+
+func (e *extraKeys) get() map[string]interface{} {
+	if e == nil {
+		return nil
+	}
+	return e.keys
+}
+
+// withExtra is how a comparable type is marshalled: T is an alias of the type,
+// without its YAML methods, and Extra holds the keys kept outside of it.
+type withExtra[T any] struct {
+	Modelled T                      `yaml:",inline"`
+	Extra    map[string]interface{} `yaml:",inline"`
+}
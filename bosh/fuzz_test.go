@@ -0,0 +1,103 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bosh_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+)
+
+func addManifestFixtures(f *testing.F) {
+	for _, fixture := range []string{"manifest.yml", "round_trip/redis.yml", "round_trip/addons.yml"} {
+		manifest, err := os.ReadFile(filepath.Join("fixtures", fixture))
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(manifest)
+	}
+}
+
+// FuzzBoshManifestYAMLRoundTrip checks that manifests marshal the same once
+// unmarshalled again, and that validating and diffing them does not panic.
+func FuzzBoshManifestYAMLRoundTrip(f *testing.F) {
+	addManifestFixtures(f)
+	f.Add([]byte("update: {canaries: 1, max_in_flight: 10%}\ninstance_groups: [{name: a, update: {max_in_flight: 2}}]"))
+
+	f.Fuzz(func(t *testing.T, document []byte) {
+		var manifest bosh.BoshManifest
+		if err := yaml.Unmarshal(document, &manifest); err != nil {
+			return
+		}
+		_ = manifest.Validate()
+
+		marshalled, err := yaml.Marshal(manifest)
+		if err != nil {
+			return
+		}
+		var roundTripped bosh.BoshManifest
+		if err := yaml.Unmarshal(marshalled, &roundTripped); err != nil {
+			t.Fatalf("unmarshalling marshalled manifest:\n%s\n%s", marshalled, err)
+		}
+		remarshalled, err := yaml.Marshal(roundTripped)
+		if err != nil {
+			t.Fatalf("marshalling round tripped manifest: %s", err)
+		}
+		if !bytes.Equal(marshalled, remarshalled) {
+			t.Fatalf("manifest changed on a round trip:\n%s\n%s", marshalled, remarshalled)
+		}
+
+		diff, err := bosh.DiffManifests(&manifest, roundTripped)
+		if err != nil {
+			t.Fatalf("diffing round tripped manifest: %s", err)
+		}
+		if diff.HasChanges() {
+			t.Fatalf("round tripped manifest differs:\n%s", diff)
+		}
+	})
+}
+
+func FuzzDiffManifests(f *testing.F) {
+	redis, err := os.ReadFile(filepath.Join("fixtures", "round_trip", "redis.yml"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	addons, err := os.ReadFile(filepath.Join("fixtures", "round_trip", "addons.yml"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(redis, addons)
+	f.Add([]byte("instance_groups: [{name: a}, {name: a}]"), []byte("instance_groups: [{name: a, jobs: [{name: b}]}]"))
+	f.Add([]byte("name: a"), []byte(""))
+
+	f.Fuzz(func(t *testing.T, previousDocument, currentDocument []byte) {
+		var previous, current bosh.BoshManifest
+		if yaml.Unmarshal(previousDocument, &previous) != nil || yaml.Unmarshal(currentDocument, &current) != nil {
+			return
+		}
+		diff, err := bosh.DiffManifests(&previous, current)
+		if err != nil {
+			return
+		}
+		_ = diff.String()
+		_ = diff.Filter("/instance_groups/*")
+	})
+}
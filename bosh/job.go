@@ -22,18 +22,21 @@ type Job struct {
 	Consumes                  map[string]interface{}     `yaml:"consumes,omitempty"`
 	CustomProviderDefinitions []CustomProviderDefinition `yaml:"custom_provider_definitions,omitempty"`
 	Properties                map[string]interface{}     `yaml:"properties,omitempty"`
+	Extra                     map[string]interface{}     `yaml:",inline" json:"-"`
 }
 
 type CustomProviderDefinition struct {
-	Name       string   `yaml:"name"`
-	Type       string   `yaml:"type"`
-	Properties []string `yaml:"properties,omitempty"`
+	Name       string                 `yaml:"name"`
+	Type       string                 `yaml:"type"`
+	Properties []string               `yaml:"properties,omitempty"`
+	Extra      map[string]interface{} `yaml:",inline" json:"-"`
 }
 
 type ProvidesLink struct {
-	As      string  `yaml:"as,omitempty"`
-	Shared  bool    `yaml:"shared,omitempty"`
-	Aliases []Alias `yaml:"aliases,omitempty"`
+	As      string                 `yaml:"as,omitempty"`
+	Shared  bool                   `yaml:"shared,omitempty"`
+	Aliases []Alias                `yaml:"aliases,omitempty"`
+	Extra   map[string]interface{} `yaml:",inline" json:"-"`
 }
 
 type Alias struct {
@@ -41,12 +44,58 @@ type Alias struct {
 	HealthFilter       string `yaml:"health_filter,omitempty"`
 	InitialHealthCheck string `yaml:"initial_health_check,omitempty"`
 	PlaceHolderType    string `yaml:"placeholder_type,omitempty"`
+
+	extra *extraKeys
+}
+
+type aliasAlias Alias
+
+// Extra returns the keys the SDK does not model.
+func (a Alias) Extra() map[string]interface{} {
+	return a.extra.get()
+}
+
+func (a Alias) MarshalYAML() (interface{}, error) {
+	return withExtra[aliasAlias]{aliasAlias(a), a.extra.get()}, nil
+}
+
+func (a *Alias) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v withExtra[aliasAlias]
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	*a = Alias(v.Modelled)
+	a.extra = newExtraKeys(v.Extra)
+	return nil
 }
 
 type ConsumesLink struct {
 	From       string `yaml:"from,omitempty"`
 	Deployment string `yaml:"deployment,omitempty"`
 	Network    string `yaml:"network,omitempty"`
+
+	extra *extraKeys
+}
+
+type consumesLinkAlias ConsumesLink
+
+// Extra returns the keys the SDK does not model.
+func (c ConsumesLink) Extra() map[string]interface{} {
+	return c.extra.get()
+}
+
+func (c ConsumesLink) MarshalYAML() (interface{}, error) {
+	return withExtra[consumesLinkAlias]{consumesLinkAlias(c), c.extra.get()}, nil
+}
+
+func (c *ConsumesLink) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v withExtra[consumesLinkAlias]
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	*c = ConsumesLink(v.Modelled)
+	c.extra = newExtraKeys(v.Extra)
+	return nil
 }
 
 func (j Job) AddCustomProviderDefinition(name, providerType string, properties []string) Job {
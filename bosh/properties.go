@@ -0,0 +1,58 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bosh
+
+import "strings"
+
+// JobProperties returns the properties of job in instanceGroup, and whether
+// the manifest has the job.
+func (m BoshManifest) JobProperties(instanceGroup, job string) (map[string]interface{}, bool) {
+	for _, group := range m.InstanceGroups {
+		if group.Name != instanceGroup {
+			continue
+		}
+		for _, j := range group.Jobs {
+			if j.Name == job {
+				return j.Properties, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// JobProperty returns the property of job in instanceGroup at the dotted
+// path property, such as "redis.password", and whether it is set.
+func (m BoshManifest) JobProperty(instanceGroup, job, property string) (interface{}, bool) {
+	properties, ok := m.JobProperties(instanceGroup, job)
+	if !ok {
+		return nil, false
+	}
+	var value interface{} = properties
+	for _, key := range strings.Split(property, ".") {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			value, ok = node[key]
+		case map[interface{}]interface{}:
+			value, ok = node[key]
+		default:
+			ok = false
+		}
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
@@ -0,0 +1,62 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bosh_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+	"gopkg.in/yaml.v2"
+)
+
+var _ = Describe("job properties", func() {
+	var manifest bosh.BoshManifest
+
+	BeforeEach(func() {
+		Expect(yaml.Unmarshal([]byte(`
+name: a-deployment
+instance_groups:
+- name: redis
+  jobs:
+  - name: redis
+    properties:
+      port: 6379
+      redis:
+        password: a-password
+`), &manifest)).To(Succeed())
+	})
+
+	It("returns the properties of a job", func() {
+		properties, ok := manifest.JobProperties("redis", "redis")
+		Expect(ok).To(BeTrue())
+		Expect(properties).To(HaveKeyWithValue("port", 6379))
+	})
+
+	It("returns a property at a dotted path", func() {
+		password, ok := manifest.JobProperty("redis", "redis", "redis.password")
+		Expect(ok).To(BeTrue())
+		Expect(password).To(Equal("a-password"))
+	})
+
+	It("reports properties and jobs that are missing", func() {
+		_, ok := manifest.JobProperty("redis", "redis", "redis.username")
+		Expect(ok).To(BeFalse())
+		_, ok = manifest.JobProperty("redis", "redis", "port.number")
+		Expect(ok).To(BeFalse())
+		_, ok = manifest.JobProperties("redis", "sentinel")
+		Expect(ok).To(BeFalse())
+	})
+})
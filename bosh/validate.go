@@ -0,0 +1,224 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bosh
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FieldError is a single problem found by Validate, at an ops-file style path.
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidationErrors is returned by Validate and holds every problem found.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	var messages []string
+	for _, fieldError := range e {
+		messages = append(messages, fieldError.Error())
+	}
+	return fmt.Sprintf("invalid manifest: %s", strings.Join(messages, "; "))
+}
+
+func (e ValidationErrors) Unwrap() []error {
+	var errs []error
+	for _, fieldError := range e {
+		errs = append(errs, fieldError)
+	}
+	return errs
+}
+
+var variableReference = regexp.MustCompile(`\(\(([^()]+)\)\)`)
+
+// Validate checks that the manifest is internally consistent: stemcell
+// aliases, releases, migrated_from entries, ((variables)) and the CAs of
+// certificate variables that are referenced must be declared, names must be
+// unique and instance groups must have AZs and networks. It returns
+// ValidationErrors, or nil.
+//
+// References to absolute credential paths such as ((/some/path)) and to ODB
+// managed secrets such as ((odb_secret:name)) are not checked.
+func (m BoshManifest) Validate() error {
+	var errs ValidationErrors
+	addError := func(path []pathSegment, format string, args ...interface{}) {
+		errs = append(errs, FieldError{Path: pathString(path), Message: fmt.Sprintf(format, args...)})
+	}
+
+	stemcells := map[string]bool{}
+	for _, stemcell := range m.Stemcells {
+		stemcells[stemcell.Alias] = true
+	}
+	releases := map[string]bool{}
+	for _, release := range m.Releases {
+		releases[release.Name] = true
+	}
+	instanceGroups := map[string]int{}
+	for _, instanceGroup := range m.InstanceGroups {
+		instanceGroups[instanceGroup.Name]++
+	}
+
+	validateJobs := func(path []pathSegment, jobs []Job) {
+		jobNames := map[string]int{}
+		for i, job := range jobs {
+			jobPath := appendSegment(path, itemSegment("jobs", job.Name, i)...)
+			if jobNames[job.Name]++; jobNames[job.Name] == 2 {
+				addError(jobPath, "job name '%s' is used more than once", job.Name)
+			}
+			if !releases[job.Release] {
+				addError(appendSegment(jobPath, pathSegment{key: "release"}), "release '%s' is not declared in releases", job.Release)
+			}
+		}
+	}
+
+	seenInstanceGroups := map[string]int{}
+	for i, instanceGroup := range m.InstanceGroups {
+		path := itemSegment("instance_groups", instanceGroup.Name, i)
+
+		if instanceGroup.Name == "" {
+			addError(path, "instance group name must not be empty")
+		} else if seenInstanceGroups[instanceGroup.Name]++; seenInstanceGroups[instanceGroup.Name] == 2 {
+			addError(path, "instance group name '%s' is used more than once", instanceGroup.Name)
+		}
+
+		if !stemcells[instanceGroup.Stemcell] {
+			addError(appendSegment(path, pathSegment{key: "stemcell"}), "stemcell alias '%s' is not declared in stemcells", instanceGroup.Stemcell)
+		}
+		if len(instanceGroup.AZs) == 0 {
+			addError(appendSegment(path, pathSegment{key: "azs"}), "must not be empty")
+		}
+		for j, az := range instanceGroup.AZs {
+			if az == "" {
+				addError(appendSegment(path, pathSegment{key: "azs"}, pathSegment{key: strconv.Itoa(j)}), "must not be empty")
+			}
+		}
+		if len(instanceGroup.Networks) == 0 {
+			addError(appendSegment(path, pathSegment{key: "networks"}), "must not be empty")
+		}
+		for j, network := range instanceGroup.Networks {
+			if network.Name == "" {
+				addError(appendSegment(path, itemSegment("networks", "", j)...), "network name must not be empty")
+			}
+		}
+
+		validateJobs(path, instanceGroup.Jobs)
+
+		for j, migration := range instanceGroup.MigratedFrom {
+			migrationPath := appendSegment(path, itemSegment("migrated_from", migration.Name, j)...)
+			switch {
+			case migration.Name == "":
+				addError(migrationPath, "migrated_from name must not be empty")
+			case migration.Name != instanceGroup.Name && instanceGroups[migration.Name] > 0:
+				addError(migrationPath, "cannot migrate from instance group '%s' as it is still in the manifest", migration.Name)
+			}
+		}
+	}
+
+	for i, addon := range m.Addons {
+		validateJobs(itemSegment("addons", addon.Name, i), addon.Jobs)
+	}
+
+	if m.Update != nil && m.Update.MaxInFlight != nil {
+		if err := ValidateMaxInFlight(m.Update.MaxInFlight); err != nil {
+			addError([]pathSegment{{key: "update"}, {key: "max_in_flight"}}, "%s", err)
+		}
+	}
+
+	variableTypes := map[string]string{}
+	for _, variable := range m.Variables {
+		variableTypes[variable.Name] = variable.Type
+	}
+	for i, variable := range m.Variables {
+		ca, ok := variable.Options["ca"]
+		if variable.Type != CertificateVariableType || !ok {
+			continue
+		}
+		path := appendSegment(itemSegment("variables", variable.Name, i), pathSegment{key: "options"}, pathSegment{key: "ca"})
+		caName, isString := ca.(string)
+		switch caType, declared := variableTypes[caName]; {
+		case !isString:
+			addError(path, "must be the name of a certificate variable")
+		case strings.HasPrefix(caName, "/"):
+		case !declared:
+			addError(path, "ca '%s' is not declared in variables", caName)
+		case caType != CertificateVariableType:
+			addError(path, "ca '%s' is not a certificate variable", caName)
+		}
+	}
+
+	tree, err := toTree(m)
+	if err != nil {
+		addError(nil, "cannot be marshalled: %s", err)
+		return errs
+	}
+	variables := map[string]bool{}
+	for _, variable := range m.Variables {
+		variables[variable.Name] = true
+	}
+	walkStrings(nil, tree, func(path []pathSegment, value string) {
+		for _, match := range variableReference.FindAllStringSubmatch(value, -1) {
+			name := strings.TrimSpace(match[1])
+			if strings.HasPrefix(name, "/") || strings.HasPrefix(name, "!") || strings.HasPrefix(name, "odb_secret:") {
+				continue
+			}
+			if baseName := strings.SplitN(name, ".", 2)[0]; !variables[baseName] {
+				addError(path, "variable '%s' is not declared in variables", baseName)
+			}
+		}
+	})
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// itemSegment returns the path to a list item, by name where it has one.
+func itemSegment(list, name string, index int) []pathSegment {
+	if name == "" {
+		return []pathSegment{{key: list}, {key: strconv.Itoa(index)}}
+	}
+	return []pathSegment{{key: list}, {matchKey: "name", matchValue: name}}
+}
+
+func walkStrings(path []pathSegment, node interface{}, visit func([]pathSegment, string)) {
+	switch n := node.(type) {
+	case string:
+		visit(path, n)
+	case map[interface{}]interface{}:
+		for _, key := range sortedKeys(n) {
+			walkStrings(appendSegment(path, pathSegment{key: fmt.Sprint(key)}), n[key], visit)
+		}
+	case []interface{}:
+		identity := listIdentity(n)
+		for i, item := range n {
+			segment := pathSegment{key: strconv.Itoa(i)}
+			if identity != "" {
+				segment = pathSegment{matchKey: identity, matchValue: itemName(item, identity)}
+			}
+			walkStrings(appendSegment(path, segment), item, visit)
+		}
+	}
+}
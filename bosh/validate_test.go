@@ -0,0 +1,171 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bosh_test
+
+import (
+	"errors"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validate", func() {
+	var manifest bosh.BoshManifest
+
+	BeforeEach(func() {
+		manifest = bosh.BoshManifest{
+			Name:      "redis",
+			Releases:  []bosh.Release{{Name: "redis", Version: "1.0.0"}},
+			Stemcells: []bosh.Stemcell{{Alias: "default", OS: "ubuntu-jammy", Version: "1.1"}},
+			InstanceGroups: []bosh.InstanceGroup{
+				{
+					Name:         "redis",
+					Instances:    1,
+					Stemcell:     "default",
+					AZs:          []string{"z1"},
+					Networks:     []bosh.Network{{Name: "default"}},
+					MigratedFrom: []bosh.Migration{{Name: "redis-server"}},
+					Jobs: []bosh.Job{
+						{
+							Name:    "redis",
+							Release: "redis",
+							Properties: map[string]interface{}{
+								"password":    "((admin_password))",
+								"ca":          "((tls.ca))",
+								"certificate": "((/shared/certificate))",
+								"secret":      "((odb_secret:secret))",
+							},
+						},
+					},
+				},
+			},
+			Variables: []bosh.Variable{{Name: "admin_password", Type: "password"}, {Name: "tls", Type: "certificate"}},
+			Update:    &bosh.Update{Canaries: 1, MaxInFlight: "50%"},
+		}
+	})
+
+	validationErrors := func() bosh.ValidationErrors {
+		err := manifest.Validate()
+		Expect(err).To(HaveOccurred())
+		var errs bosh.ValidationErrors
+		Expect(errors.As(err, &errs)).To(BeTrue())
+		return errs
+	}
+
+	It("accepts a consistent manifest", func() {
+		Expect(manifest.Validate()).To(Succeed())
+	})
+
+	It("rejects undeclared stemcell aliases and releases", func() {
+		manifest.InstanceGroups[0].Stemcell = "jammy"
+		manifest.InstanceGroups[0].Jobs[0].Release = "redis-release"
+		manifest.Addons = []bosh.Addon{{Name: "dns", Jobs: []bosh.Job{{Name: "bosh-dns", Release: "bosh-dns"}}}}
+
+		Expect(validationErrors()).To(ConsistOf(
+			bosh.FieldError{Path: "/instance_groups/name=redis/stemcell", Message: "stemcell alias 'jammy' is not declared in stemcells"},
+			bosh.FieldError{Path: "/instance_groups/name=redis/jobs/name=redis/release", Message: "release 'redis-release' is not declared in releases"},
+			bosh.FieldError{Path: "/addons/name=dns/jobs/name=bosh-dns/release", Message: "release 'bosh-dns' is not declared in releases"},
+		))
+	})
+
+	It("rejects duplicate instance group and job names", func() {
+		manifest.InstanceGroups[0].Jobs = append(manifest.InstanceGroups[0].Jobs, bosh.Job{Name: "redis", Release: "redis"})
+		manifest.InstanceGroups = append(manifest.InstanceGroups, bosh.InstanceGroup{
+			Name:     "redis",
+			Stemcell: "default",
+			AZs:      []string{"z1"},
+			Networks: []bosh.Network{{Name: "default"}},
+		})
+
+		Expect(validationErrors()).To(ConsistOf(
+			bosh.FieldError{Path: "/instance_groups/name=redis/jobs/name=redis", Message: "job name 'redis' is used more than once"},
+			bosh.FieldError{Path: "/instance_groups/name=redis", Message: "instance group name 'redis' is used more than once"},
+		))
+	})
+
+	It("rejects empty AZs and networks", func() {
+		manifest.InstanceGroups[0].AZs = nil
+		manifest.InstanceGroups[0].Networks = nil
+
+		Expect(validationErrors()).To(ConsistOf(
+			bosh.FieldError{Path: "/instance_groups/name=redis/azs", Message: "must not be empty"},
+			bosh.FieldError{Path: "/instance_groups/name=redis/networks", Message: "must not be empty"},
+		))
+	})
+
+	It("rejects migrated_from entries that point at nothing", func() {
+		manifest.InstanceGroups[0].MigratedFrom = []bosh.Migration{{}, {Name: "sentinel"}}
+		manifest.InstanceGroups = append(manifest.InstanceGroups, bosh.InstanceGroup{
+			Name:     "sentinel",
+			Stemcell: "default",
+			AZs:      []string{"z1"},
+			Networks: []bosh.Network{{Name: "default"}},
+		})
+
+		Expect(validationErrors()).To(ConsistOf(
+			bosh.FieldError{Path: "/instance_groups/name=redis/migrated_from/0", Message: "migrated_from name must not be empty"},
+			bosh.FieldError{Path: "/instance_groups/name=redis/migrated_from/name=sentinel", Message: "cannot migrate from instance group 'sentinel' as it is still in the manifest"},
+		))
+	})
+
+	It("rejects undeclared variables", func() {
+		manifest.Variables = nil
+		manifest.Properties = map[string]interface{}{"url": "https://((host)):((port))"}
+
+		Expect(validationErrors()).To(ConsistOf(
+			bosh.FieldError{Path: "/instance_groups/name=redis/jobs/name=redis/properties/ca", Message: "variable 'tls' is not declared in variables"},
+			bosh.FieldError{Path: "/instance_groups/name=redis/jobs/name=redis/properties/password", Message: "variable 'admin_password' is not declared in variables"},
+			bosh.FieldError{Path: "/properties/url", Message: "variable 'host' is not declared in variables"},
+			bosh.FieldError{Path: "/properties/url", Message: "variable 'port' is not declared in variables"},
+		))
+	})
+
+	It("checks variables under non-string keys", func() {
+		manifest.Properties = map[string]interface{}{"ports": map[interface{}]interface{}{1: "((port))"}}
+
+		Expect(validationErrors()).To(ContainElement(
+			bosh.FieldError{Path: "/properties/ports/1", Message: "variable 'port' is not declared in variables"},
+		))
+	})
+
+	It("rejects certificate variables signed by a CA that is not declared", func() {
+		manifest.Variables = append(manifest.Variables,
+			bosh.CertificateVariable("server", bosh.CertificateOptions{CA: "missing_ca"}),
+			bosh.CertificateVariable("client", bosh.CertificateOptions{CA: "admin_password"}),
+			bosh.CertificateVariable("shared", bosh.CertificateOptions{CA: "/shared/ca"}),
+			bosh.CertificateVariable("signed", bosh.CertificateOptions{CA: "tls"}),
+			bosh.Variable{Name: "invalid", Type: "certificate", Options: map[string]interface{}{"ca": 1}},
+		)
+
+		Expect(validationErrors()).To(ConsistOf(
+			bosh.FieldError{Path: "/variables/name=server/options/ca", Message: "ca 'missing_ca' is not declared in variables"},
+			bosh.FieldError{Path: "/variables/name=client/options/ca", Message: "ca 'admin_password' is not a certificate variable"},
+			bosh.FieldError{Path: "/variables/name=invalid/options/ca", Message: "must be the name of a certificate variable"},
+		))
+	})
+
+	It("reports every problem in the error message", func() {
+		manifest.InstanceGroups[0].Stemcell = "jammy"
+		manifest.InstanceGroups[0].AZs = nil
+
+		Expect(manifest.Validate()).To(MatchError(
+			"invalid manifest: /instance_groups/name=redis/stemcell: stemcell alias 'jammy' is not declared in stemcells; " +
+				"/instance_groups/name=redis/azs: must not be empty",
+		))
+	})
+})
@@ -0,0 +1,157 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bosh
+
+import (
+	"reflect"
+	"strings"
+)
+
+// The types of variable BOSH generates with CredHub.
+const (
+	PasswordVariableType    = "password"
+	CertificateVariableType = "certificate"
+	RSAVariableType         = "rsa"
+	SSHVariableType         = "ssh"
+	UserVariableType        = "user"
+)
+
+// ExtendedKeyUsage is a purpose a certificate variable may be used for,
+// as listed in its extended_key_usage option.
+type ExtendedKeyUsage string
+
+const (
+	ServerAuth      ExtendedKeyUsage = "server_auth"
+	ClientAuth      ExtendedKeyUsage = "client_auth"
+	CodeSigning     ExtendedKeyUsage = "code_signing"
+	EmailProtection ExtendedKeyUsage = "email_protection"
+	Timestamping    ExtendedKeyUsage = "timestamping"
+)
+
+// KeyUsage is an operation the key of a certificate variable may be used
+// for, as listed in its key_usage option.
+type KeyUsage string
+
+const (
+	DigitalSignature KeyUsage = "digital_signature"
+	NonRepudiation   KeyUsage = "non_repudiation"
+	KeyEncipherment  KeyUsage = "key_encipherment"
+	DataEncipherment KeyUsage = "data_encipherment"
+	KeyAgreement     KeyUsage = "key_agreement"
+	KeyCertSign      KeyUsage = "key_cert_sign"
+	CRLSign          KeyUsage = "crl_sign"
+	EncipherOnly     KeyUsage = "encipher_only"
+	DecipherOnly     KeyUsage = "decipher_only"
+)
+
+// PasswordOptions are the generation options of password variables. Zero
+// values are left to the CredHub defaults.
+type PasswordOptions struct {
+	Length         int  `yaml:"length,omitempty"`
+	ExcludeUpper   bool `yaml:"exclude_upper,omitempty"`
+	ExcludeLower   bool `yaml:"exclude_lower,omitempty"`
+	ExcludeNumber  bool `yaml:"exclude_number,omitempty"`
+	IncludeSpecial bool `yaml:"include_special,omitempty"`
+}
+
+// CertificateOptions are the generation options of certificate variables.
+// CA is the name of the certificate variable signing this one, and Duration
+// is in days.
+type CertificateOptions struct {
+	IsCA             bool               `yaml:"is_ca,omitempty"`
+	CA               string             `yaml:"ca,omitempty"`
+	SelfSign         bool               `yaml:"self_sign,omitempty"`
+	CommonName       string             `yaml:"common_name,omitempty"`
+	AlternativeNames []string           `yaml:"alternative_names,omitempty"`
+	Organization     string             `yaml:"organization,omitempty"`
+	OrganizationUnit string             `yaml:"organization_unit,omitempty"`
+	Locality         string             `yaml:"locality,omitempty"`
+	State            string             `yaml:"state,omitempty"`
+	Country          string             `yaml:"country,omitempty"`
+	ExtendedKeyUsage []ExtendedKeyUsage `yaml:"extended_key_usage,omitempty"`
+	KeyUsage         []KeyUsage         `yaml:"key_usage,omitempty"`
+	KeyLength        int                `yaml:"key_length,omitempty"`
+	Duration         int                `yaml:"duration,omitempty"`
+}
+
+// RSAOptions are the generation options of rsa variables.
+type RSAOptions struct {
+	KeyLength int `yaml:"key_length,omitempty"`
+}
+
+// SSHOptions are the generation options of ssh variables. SSHComment is
+// appended to the generated public key.
+type SSHOptions struct {
+	KeyLength  int    `yaml:"key_length,omitempty"`
+	SSHComment string `yaml:"ssh_comment,omitempty"`
+}
+
+// UserOptions are the generation options of user variables. The password
+// options apply to the generated password.
+type UserOptions struct {
+	Username       string `yaml:"username,omitempty"`
+	Length         int    `yaml:"length,omitempty"`
+	ExcludeUpper   bool   `yaml:"exclude_upper,omitempty"`
+	ExcludeLower   bool   `yaml:"exclude_lower,omitempty"`
+	ExcludeNumber  bool   `yaml:"exclude_number,omitempty"`
+	IncludeSpecial bool   `yaml:"include_special,omitempty"`
+}
+
+// PasswordVariable returns a password variable named name.
+func PasswordVariable(name string, options PasswordOptions) Variable {
+	return newVariable(name, PasswordVariableType, options)
+}
+
+// CertificateVariable returns a certificate variable named name. Validate
+// checks that its CA, if any, is declared.
+func CertificateVariable(name string, options CertificateOptions) Variable {
+	return newVariable(name, CertificateVariableType, options)
+}
+
+// RSAVariable returns an rsa key pair variable named name.
+func RSAVariable(name string, options RSAOptions) Variable {
+	return newVariable(name, RSAVariableType, options)
+}
+
+// SSHVariable returns an ssh key pair variable named name.
+func SSHVariable(name string, options SSHOptions) Variable {
+	return newVariable(name, SSHVariableType, options)
+}
+
+// UserVariable returns a user variable named name, with a generated
+// password.
+func UserVariable(name string, options UserOptions) Variable {
+	return newVariable(name, UserVariableType, options)
+}
+
+// newVariable sets Variable.Options to the options that are set, keyed by
+// their yaml tags. Options is nil when no option is set.
+func newVariable(name, variableType string, options interface{}) Variable {
+	variable := Variable{Name: name, Type: variableType}
+
+	value := reflect.ValueOf(options)
+	for i := 0; i < value.NumField(); i++ {
+		if value.Field(i).IsZero() {
+			continue
+		}
+		if variable.Options == nil {
+			variable.Options = map[string]interface{}{}
+		}
+		key, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("yaml"), ",")
+		variable.Options[key] = value.Field(i).Interface()
+	}
+	return variable
+}
@@ -0,0 +1,85 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bosh_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+	"gopkg.in/yaml.v2"
+)
+
+var _ = Describe("typed variables", func() {
+	It("serialises to the same YAML as variables written by hand", func() {
+		typed := []bosh.Variable{
+			bosh.PasswordVariable("admin_password", bosh.PasswordOptions{Length: 40, IncludeSpecial: true}),
+			bosh.CertificateVariable("ca", bosh.CertificateOptions{IsCA: true, CommonName: "redis-ca", Duration: 365}),
+			bosh.CertificateVariable("tls", bosh.CertificateOptions{
+				CA:               "ca",
+				CommonName:       "redis.example.com",
+				AlternativeNames: []string{"redis.service.internal", "10.0.0.1"},
+				ExtendedKeyUsage: []bosh.ExtendedKeyUsage{bosh.ServerAuth, bosh.ClientAuth},
+				KeyUsage:         []bosh.KeyUsage{bosh.DigitalSignature},
+			}),
+			bosh.RSAVariable("signing_key", bosh.RSAOptions{KeyLength: 4096}),
+			bosh.SSHVariable("ssh_key", bosh.SSHOptions{}),
+			bosh.UserVariable("admin", bosh.UserOptions{Username: "admin"}),
+		}
+
+		var handWritten []bosh.Variable
+		Expect(yaml.Unmarshal([]byte(`
+- name: admin_password
+  type: password
+  options:
+    length: 40
+    include_special: true
+- name: ca
+  type: certificate
+  options:
+    is_ca: true
+    common_name: redis-ca
+    duration: 365
+- name: tls
+  type: certificate
+  options:
+    ca: ca
+    common_name: redis.example.com
+    alternative_names: [redis.service.internal, 10.0.0.1]
+    extended_key_usage: [server_auth, client_auth]
+    key_usage: [digital_signature]
+- name: signing_key
+  type: rsa
+  options:
+    key_length: 4096
+- name: ssh_key
+  type: ssh
+- name: admin
+  type: user
+  options:
+    username: admin
+`), &handWritten)).To(Succeed())
+
+		typedYAML, err := yaml.Marshal(typed)
+		Expect(err).NotTo(HaveOccurred())
+		handWrittenYAML, err := yaml.Marshal(handWritten)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(typedYAML)).To(Equal(string(handWrittenYAML)))
+	})
+
+	It("leaves the options out when none are set", func() {
+		Expect(bosh.SSHVariable("ssh_key", bosh.SSHOptions{})).To(Equal(bosh.Variable{Name: "ssh_key", Type: "ssh"}))
+	})
+})
@@ -0,0 +1,92 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// service-adapter-conformance checks a service adapter binary behaves as
+// ODB expects, and exits with 1 when it does not.
+//
+// Usage:
+//
+//	service-adapter-conformance [-fixtures <fixtures-JSON-path>] [-json] [-timeout <duration>] <adapter-path>
+//
+// The fixtures file holds the input params to invoke the adapter with, in
+// the format of conformance.Fixtures. Actions without input params in it
+// are invoked with those of conformance.DefaultFixtures.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/conformance"
+)
+
+func main() {
+	fixturesPath := flag.String("fixtures", "", "path to a JSON file of conformance fixtures")
+	jsonOutput := flag.Bool("json", false, "write the report as JSON")
+	timeout := flag.Duration("timeout", conformance.DefaultTimeout, "timeout of each invocation of the adapter")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <adapter-path>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	fixtures := conformance.DefaultFixtures()
+	if *fixturesPath != "" {
+		fixtures = conformance.Fixtures{}
+		data, err := os.ReadFile(*fixturesPath)
+		if err != nil {
+			fail("reading fixtures: %s", err)
+		}
+		if err := json.Unmarshal(data, &fixtures); err != nil {
+			fail("unmarshalling fixtures: %s", err)
+		}
+		fixtures = fixtures.WithDefaults()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	suite := conformance.Suite{AdapterPath: flag.Arg(0), Fixtures: fixtures, Timeout: *timeout}
+	report := suite.Run(ctx)
+
+	var err error
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		fail("writing report: %s", err)
+	}
+	if !report.Passed() {
+		stop()
+		os.Exit(1)
+	}
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
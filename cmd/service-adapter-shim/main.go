@@ -0,0 +1,51 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// service-adapter-shim is installed in place of a service adapter binary and
+// forwards each ODB invocation to a service adapter running in server mode.
+//
+// The server is located using the ODB_SERVICE_ADAPTER_SERVER_NETWORK and
+// ODB_SERVICE_ADAPTER_SERVER_ADDRESS environment variables, defaulting to the
+// unix socket at server.DefaultAddress.
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/server"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	client := server.NewClient(
+		envOrDefault(server.NetworkEnvVar, server.DefaultNetwork),
+		envOrDefault(server.AddressEnvVar, server.DefaultAddress),
+	)
+	exitCode := server.Forward(ctx, client, os.Args, os.Stdin, os.Stdout, os.Stderr)
+
+	stop()
+	os.Exit(exitCode)
+}
+
+func envOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,444 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package conformance checks a service adapter binary behaves as ODB
+// expects: for every subcommand, with input params passed as legacy
+// positional arguments and as JSON via stdin, with the exit codes and
+// output formats of the service adapter interface.
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+const DefaultTimeout = 30 * time.Second
+
+// Actions are the subcommands ODB invokes, in the order they are checked.
+var Actions = []string{"generate-manifest", "create-binding", "delete-binding", "dashboard-url", "generate-plan-schemas"}
+
+// exitCodes are the codes ODB understands from each subcommand besides 0.
+var exitCodes = map[string][]int{
+	"generate-manifest": {
+		serviceadapter.ErrorExitCode,
+		serviceadapter.NotImplementedExitCode,
+	},
+	"create-binding": {
+		serviceadapter.ErrorExitCode,
+		serviceadapter.NotImplementedExitCode,
+		serviceadapter.AppGuidNotProvidedErrorExitCode,
+		serviceadapter.BindingAlreadyExistsErrorExitCode,
+	},
+	"delete-binding": {
+		serviceadapter.ErrorExitCode,
+		serviceadapter.NotImplementedExitCode,
+		serviceadapter.BindingNotFoundErrorExitCode,
+	},
+	"dashboard-url":         {serviceadapter.ErrorExitCode, serviceadapter.NotImplementedExitCode},
+	"generate-plan-schemas": {serviceadapter.ErrorExitCode, serviceadapter.NotImplementedExitCode},
+}
+
+// Fixtures are the input params the adapter is invoked with. The adapter
+// is expected to succeed with Input, and to fail with the exit code of the
+// error each other fixture is named after. Cases without a fixture are
+// skipped.
+type Fixtures struct {
+	Input serviceadapter.InputParams `json:"input"`
+
+	BindingAlreadyExists *serviceadapter.CreateBindingJSONParams `json:"binding_already_exists,omitempty"`
+	AppGuidNotProvided   *serviceadapter.CreateBindingJSONParams `json:"app_guid_not_provided,omitempty"`
+	BindingNotFound      *serviceadapter.DeleteBindingJSONParams `json:"binding_not_found,omitempty"`
+}
+
+// DefaultFixtures returns input params any adapter should accept: a new
+// service instance with a single instance group, and a binding to it.
+func DefaultFixtures() Fixtures {
+	plan := toJSON(serviceadapter.Plan{
+		InstanceGroups: []serviceadapter.InstanceGroup{{
+			Name:      "conformance",
+			VMType:    "default",
+			Networks:  []string{"default"},
+			AZs:       []string{"z1"},
+			Instances: 1,
+		}},
+		Properties: serviceadapter.Properties{},
+	})
+	manifest := toYAML(bosh.BoshManifest{
+		Name:      "service-instance_conformance",
+		Releases:  []bosh.Release{{Name: "conformance", Version: "latest"}},
+		Stemcells: []bosh.Stemcell{{Alias: "default", OS: "ubuntu-jammy", Version: "latest"}},
+		InstanceGroups: []bosh.InstanceGroup{{
+			Name:      "conformance",
+			Instances: 1,
+			VMType:    "default",
+			Stemcell:  "default",
+			AZs:       []string{"z1"},
+			Networks:  []bosh.Network{{Name: "default"}},
+		}},
+	})
+	bindingParams := toJSON(serviceadapter.RequestParameters{
+		"app_guid":   "conformance-app-guid",
+		"plan_id":    "conformance-plan-id",
+		"service_id": "conformance-service-id",
+	})
+	bindingVMs := toJSON(bosh.BoshVMs{"conformance": []string{"10.0.0.1"}})
+
+	return Fixtures{Input: serviceadapter.InputParams{
+		GenerateManifest: serviceadapter.GenerateManifestJSONParams{
+			ServiceDeployment: toJSON(serviceadapter.ServiceDeployment{
+				DeploymentName: "service-instance_conformance",
+				Releases:       serviceadapter.ServiceReleases{{Name: "conformance", Version: "latest", Jobs: []string{"conformance"}}},
+				Stemcells:      []serviceadapter.Stemcell{{OS: "ubuntu-jammy", Version: "latest"}},
+			}),
+			Plan:              plan,
+			RequestParameters: toJSON(serviceadapter.RequestParameters{"plan_id": "conformance-plan-id", "parameters": map[string]interface{}{}}),
+			PreviousManifest:  "",
+			PreviousPlan:      "null",
+		},
+		CreateBinding: serviceadapter.CreateBindingJSONParams{
+			BindingId:         "conformance-binding-id",
+			BoshVms:           bindingVMs,
+			Manifest:          manifest,
+			RequestParameters: bindingParams,
+		},
+		DeleteBinding: serviceadapter.DeleteBindingJSONParams{
+			BindingId:         "conformance-binding-id",
+			BoshVms:           bindingVMs,
+			Manifest:          manifest,
+			RequestParameters: bindingParams,
+		},
+		DashboardUrl: serviceadapter.DashboardUrlJSONParams{
+			InstanceId: "conformance",
+			Plan:       plan,
+			Manifest:   manifest,
+		},
+		GeneratePlanSchemas: serviceadapter.GeneratePlanSchemasJSONParams{Plan: plan},
+	}}
+}
+
+// WithDefaults fills the input params of the actions f has none for with
+// those of DefaultFixtures.
+func (f Fixtures) WithDefaults() Fixtures {
+	defaults := DefaultFixtures().Input
+	if f.Input.GenerateManifest == (serviceadapter.GenerateManifestJSONParams{}) {
+		f.Input.GenerateManifest = defaults.GenerateManifest
+	}
+	if f.Input.CreateBinding == (serviceadapter.CreateBindingJSONParams{}) {
+		f.Input.CreateBinding = defaults.CreateBinding
+	}
+	if f.Input.DeleteBinding == (serviceadapter.DeleteBindingJSONParams{}) {
+		f.Input.DeleteBinding = defaults.DeleteBinding
+	}
+	if f.Input.DashboardUrl == (serviceadapter.DashboardUrlJSONParams{}) {
+		f.Input.DashboardUrl = defaults.DashboardUrl
+	}
+	if f.Input.GeneratePlanSchemas == (serviceadapter.GeneratePlanSchemasJSONParams{}) {
+		f.Input.GeneratePlanSchemas = defaults.GeneratePlanSchemas
+	}
+	return f
+}
+
+// Suite runs the conformance cases against the adapter binary at
+// AdapterPath.
+type Suite struct {
+	AdapterPath string
+	Fixtures    Fixtures
+
+	// Env is added to the environment of the adapter.
+	Env []string
+
+	// Timeout bounds each invocation of the adapter, DefaultTimeout if zero.
+	Timeout time.Duration
+}
+
+type invocation struct {
+	stdout   string
+	stderr   string
+	exitCode int
+}
+
+// Run invokes the adapter for every case and reports the outcomes. Cases of
+// actions the adapter does not implement are reported as NotImplemented,
+// provided it exits with NotImplementedExitCode.
+func (s Suite) Run(ctx context.Context) Report {
+	report := Report{Adapter: s.AdapterPath}
+
+	noSubcommand := s.invoke(ctx, nil, "")
+	report.add(s.expectExitCode(noSubcommand, "", "no subcommand", serviceadapter.ErrorExitCode))
+	unknownSubcommand := s.invoke(ctx, []string{"conformance-unknown-subcommand"}, "")
+	report.add(s.expectExitCode(unknownSubcommand, "", "unknown subcommand", serviceadapter.ErrorExitCode))
+
+	for _, action := range Actions {
+		stdinInput, err := json.Marshal(inputFor(action, s.Fixtures.Input))
+		if err != nil {
+			report.add(Result{Case: "stdin JSON", Action: action, Status: Failed, Message: err.Error()})
+			continue
+		}
+		stdinJSON := s.invoke(ctx, []string{action}, string(stdinInput))
+		if stdinJSON.exitCode == serviceadapter.NotImplementedExitCode {
+			report.addNotImplemented(action, s.notImplementedCases(ctx, action)...)
+			continue
+		}
+		report.add(s.expectOutput(stdinJSON, action, "stdin JSON", false))
+
+		positional := s.invoke(ctx, append([]string{action}, positionalArgs(action, s.Fixtures.Input)...), "")
+		report.add(s.expectOutput(positional, action, "positional arguments", true))
+
+		missingArgs := s.invoke(ctx, []string{action, "conformance-missing-args"}, "")
+		report.add(s.expectExitCode(missingArgs, action, "missing positional arguments", serviceadapter.ErrorExitCode))
+
+		invalidJSON := s.invoke(ctx, []string{action}, "{not json")
+		report.add(s.expectExitCode(invalidJSON, action, "invalid stdin JSON", serviceadapter.ErrorExitCode))
+
+		report.add(s.expectedErrorCases(ctx, action)...)
+	}
+	return report
+}
+
+// notImplementedCases checks an action the adapter does not implement
+// exits with NotImplementedExitCode for either protocol.
+func (s Suite) notImplementedCases(ctx context.Context, action string) []Result {
+	positional := s.invoke(ctx, append([]string{action}, positionalArgs(action, s.Fixtures.Input)...), "")
+	result := s.expectExitCode(positional, action, "not implemented with positional arguments", serviceadapter.NotImplementedExitCode)
+	if result.Status == Passed {
+		return nil
+	}
+	return []Result{result}
+}
+
+func (s Suite) expectedErrorCases(ctx context.Context, action string) []Result {
+	type errorCase struct {
+		name     string
+		input    interface{}
+		exitCode int
+	}
+	var cases []errorCase
+	switch action {
+	case "create-binding":
+		cases = []errorCase{
+			{"binding already exists", s.Fixtures.BindingAlreadyExists, serviceadapter.BindingAlreadyExistsErrorExitCode},
+			{"app GUID not provided", s.Fixtures.AppGuidNotProvided, serviceadapter.AppGuidNotProvidedErrorExitCode},
+		}
+	case "delete-binding":
+		cases = []errorCase{
+			{"binding not found", s.Fixtures.BindingNotFound, serviceadapter.BindingNotFoundErrorExitCode},
+		}
+	}
+
+	var results []Result
+	for _, c := range cases {
+		var input serviceadapter.InputParams
+		switch params := c.input.(type) {
+		case *serviceadapter.CreateBindingJSONParams:
+			if params == nil {
+				results = append(results, Result{Case: c.name, Action: action, Status: Skipped, Message: "no fixture"})
+				continue
+			}
+			input.CreateBinding = *params
+		case *serviceadapter.DeleteBindingJSONParams:
+			if params == nil {
+				results = append(results, Result{Case: c.name, Action: action, Status: Skipped, Message: "no fixture"})
+				continue
+			}
+			input.DeleteBinding = *params
+		}
+		stdinInput, _ := json.Marshal(input)
+		results = append(results, s.expectExitCode(s.invoke(ctx, []string{action}, string(stdinInput)), action, c.name, c.exitCode))
+	}
+	return results
+}
+
+func (s Suite) invoke(ctx context.Context, args []string, stdin string) invocation {
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.AdapterPath, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if s.Env != nil {
+		cmd.Env = append(cmd.Environ(), s.Env...)
+	}
+
+	err := cmd.Run()
+	result := invocation{stdout: stdout.String(), stderr: stderr.String()}
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		result.exitCode = -1
+		result.stderr += fmt.Sprintf("\ntimed out after %s", timeout)
+	case errors.As(err, &exitErr):
+		result.exitCode = exitErr.ExitCode()
+	case err != nil:
+		result.exitCode = -1
+		result.stderr += "\n" + err.Error()
+	}
+	return result
+}
+
+func (s Suite) expectExitCode(inv invocation, action, name string, exitCode int) Result {
+	result := Result{Case: name, Action: action, Status: Passed}
+	if inv.exitCode != exitCode {
+		result.Status = Failed
+		result.Message = fmt.Sprintf("expected exit code %d, got %d%s", exitCode, inv.exitCode, describeFailure(inv, action))
+	}
+	return result
+}
+
+func (s Suite) expectOutput(inv invocation, action, name string, positional bool) Result {
+	result := Result{Case: name, Action: action, Status: Passed}
+	if inv.exitCode != 0 {
+		result.Status = Failed
+		result.Message = fmt.Sprintf("expected exit code 0, got %d%s", inv.exitCode, describeFailure(inv, action))
+		return result
+	}
+	if err := s.checkOutput(action, inv.stdout, positional); err != nil {
+		result.Status = Failed
+		result.Message = fmt.Sprintf("unexpected output: %s\nstdout:\n%s", err, inv.stdout)
+	}
+	return result
+}
+
+// checkOutput checks stdout has the format ODB parses for action.
+func (s Suite) checkOutput(action, stdout string, positional bool) error {
+	switch action {
+	case "generate-manifest":
+		manifestYAML := stdout
+		if !positional {
+			var output serviceadapter.MarshalledGenerateManifest
+			if err := strictUnmarshal(stdout, &output); err != nil {
+				return err
+			}
+			manifestYAML = output.Manifest
+		}
+		var manifest bosh.BoshManifest
+		if err := yaml.Unmarshal([]byte(manifestYAML), &manifest); err != nil {
+			return fmt.Errorf("manifest is not valid YAML: %s", err)
+		}
+		var serviceDeployment serviceadapter.ServiceDeployment
+		if err := json.Unmarshal([]byte(s.Fixtures.Input.GenerateManifest.ServiceDeployment), &serviceDeployment); err == nil && manifest.Name != serviceDeployment.DeploymentName {
+			return fmt.Errorf("manifest name %q is not the deployment name %q", manifest.Name, serviceDeployment.DeploymentName)
+		}
+	case "create-binding":
+		var binding serviceadapter.Binding
+		if err := strictUnmarshal(stdout, &binding); err != nil {
+			return err
+		}
+		if binding.Credentials == nil {
+			return errors.New("binding has no credentials")
+		}
+	case "dashboard-url":
+		var dashboardURL serviceadapter.DashboardUrl
+		if err := strictUnmarshal(stdout, &dashboardURL); err != nil {
+			return err
+		}
+		if dashboardURL.DashboardUrl == "" {
+			return errors.New("dashboard_url is empty")
+		}
+	case "generate-plan-schemas":
+		var schema serviceadapter.PlanSchema
+		return strictUnmarshal(stdout, &schema)
+	}
+	return nil
+}
+
+func strictUnmarshal(data string, v interface{}) error {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("not the expected JSON: %s", err)
+	}
+	return nil
+}
+
+func describeFailure(inv invocation, action string) string {
+	var description strings.Builder
+	if inv.exitCode > 0 && action != "" && !slices.Contains(exitCodes[action], inv.exitCode) {
+		fmt.Fprintf(&description, " (exit code %d is not one ODB understands from %s)", inv.exitCode, action)
+	}
+	if stderr := strings.TrimSpace(inv.stderr); stderr != "" {
+		fmt.Fprintf(&description, "\nstderr:\n%s", stderr)
+	}
+	return description.String()
+}
+
+// inputFor returns the input params ODB passes to action via stdin.
+func inputFor(action string, input serviceadapter.InputParams) serviceadapter.InputParams {
+	switch action {
+	case "generate-manifest":
+		return serviceadapter.InputParams{GenerateManifest: input.GenerateManifest}
+	case "create-binding":
+		return serviceadapter.InputParams{CreateBinding: input.CreateBinding}
+	case "delete-binding":
+		return serviceadapter.InputParams{DeleteBinding: input.DeleteBinding}
+	case "dashboard-url":
+		return serviceadapter.InputParams{DashboardUrl: input.DashboardUrl}
+	default:
+		return serviceadapter.InputParams{GeneratePlanSchemas: input.GeneratePlanSchemas}
+	}
+}
+
+// positionalArgs returns the arguments legacy versions of ODB pass to
+// action.
+func positionalArgs(action string, input serviceadapter.InputParams) []string {
+	switch action {
+	case "generate-manifest":
+		p := input.GenerateManifest
+		return []string{p.ServiceDeployment, p.Plan, p.RequestParameters, p.PreviousManifest, p.PreviousPlan}
+	case "create-binding":
+		p := input.CreateBinding
+		return []string{p.BindingId, p.BoshVms, p.Manifest, p.RequestParameters}
+	case "delete-binding":
+		p := input.DeleteBinding
+		return []string{p.BindingId, p.BoshVms, p.Manifest, p.RequestParameters}
+	case "dashboard-url":
+		p := input.DashboardUrl
+		return []string{p.InstanceId, p.Plan, p.Manifest}
+	default:
+		return []string{"-plan-json", input.GeneratePlanSchemas.Plan}
+	}
+}
+
+func toJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+func toYAML(v interface{}) string {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
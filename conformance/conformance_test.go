@@ -0,0 +1,151 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conformance_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/conformance"
+	"github.com/pivotal-cf/on-demand-services-sdk/integration_tests/testharness/testvariables"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+var _ = Describe("Suite", func() {
+	var suite conformance.Suite
+
+	BeforeEach(func() {
+		// the test harness always generates a manifest named deployment-name
+		serviceDeployment := serviceadapter.ServiceDeployment{
+			DeploymentName: "deployment-name",
+			Releases:       serviceadapter.ServiceReleases{{Name: "a-release", Version: "latest", Jobs: []string{"a-job"}}},
+			Stemcells:      []serviceadapter.Stemcell{{OS: "Windows", Version: "3.1"}},
+		}
+		fixtures := conformance.DefaultFixtures()
+		serviceDeploymentJSON, err := json.Marshal(serviceDeployment)
+		Expect(err).NotTo(HaveOccurred())
+		fixtures.Input.GenerateManifest.ServiceDeployment = string(serviceDeploymentJSON)
+
+		suite = conformance.Suite{AdapterPath: adapterBin, Fixtures: fixtures}
+	})
+
+	resultOf := func(report conformance.Report, name string) conformance.Result {
+		for _, result := range report.Results {
+			if result.Name() == name {
+				return result
+			}
+		}
+		Fail("no result for " + name)
+		return conformance.Result{}
+	}
+
+	It("passes every case of an adapter that follows the protocol", func() {
+		report := suite.Run(context.Background())
+
+		Expect(report.Passed()).To(BeTrue())
+		Expect(report.Count(conformance.Passed)).To(Equal(22))
+		Expect(report.Count(conformance.Skipped)).To(Equal(3))
+		Expect(resultOf(report, "generate-manifest: positional arguments").Status).To(Equal(conformance.Passed))
+		Expect(resultOf(report, "delete-binding: binding not found")).To(Equal(conformance.Result{
+			Case:    "binding not found",
+			Action:  "delete-binding",
+			Status:  conformance.Skipped,
+			Message: "no fixture",
+		}))
+	})
+
+	It("reports outputs ODB would reject", func() {
+		suite.Fixtures.Input.GenerateManifest = conformance.DefaultFixtures().Input.GenerateManifest
+
+		report := suite.Run(context.Background())
+
+		Expect(report.Passed()).To(BeFalse())
+		Expect(resultOf(report, "generate-manifest: stdin JSON").Status).To(Equal(conformance.Failed))
+		Expect(resultOf(report, "generate-manifest: stdin JSON").Message).To(HavePrefix(`unexpected output: manifest name "deployment-name" is not the deployment name "service-instance_conformance"`))
+	})
+
+	It("checks the exit codes of expected errors", func() {
+		fixtures := conformance.DefaultFixtures()
+		suite.Fixtures.BindingAlreadyExists = &fixtures.Input.CreateBinding
+		suite.Env = []string{testvariables.OperationFailsKey + "=" + testvariables.ErrBindingAlreadyExists}
+
+		report := suite.Run(context.Background())
+
+		Expect(resultOf(report, "create-binding: binding already exists").Status).To(Equal(conformance.Passed))
+		stdinJSON := resultOf(report, "create-binding: stdin JSON")
+		Expect(stdinJSON.Status).To(Equal(conformance.Failed))
+		Expect(stdinJSON.Message).To(HavePrefix("expected exit code 0, got 49\nstderr:\n"))
+	})
+
+	It("reports the actions an adapter does not implement", func() {
+		suite.Env = []string{testvariables.DoNotImplementInterfacesKey + "=true"}
+
+		report := suite.Run(context.Background())
+
+		Expect(report.Passed()).To(BeTrue())
+		Expect(report.Count(conformance.NotImplemented)).To(Equal(len(conformance.Actions)))
+		Expect(resultOf(report, "dashboard-url: all cases").Status).To(Equal(conformance.NotImplemented))
+	})
+
+	It("fails every case when the adapter cannot be run", func() {
+		suite.AdapterPath = "/does/not/exist"
+
+		report := suite.Run(context.Background())
+
+		Expect(report.Count(conformance.Passed)).To(BeZero())
+		Expect(resultOf(report, "no subcommand").Message).To(ContainSubstring("expected exit code 1, got -1"))
+	})
+})
+
+var _ = Describe("Report", func() {
+	It("writes a line per case and a summary", func() {
+		report := conformance.Report{Adapter: "/path/to/adapter", Results: []conformance.Result{
+			{Case: "no subcommand", Status: conformance.Passed},
+			{Case: "stdin JSON", Action: "create-binding", Status: conformance.Failed, Message: "expected exit code 0, got 1\nstderr:\nboom"},
+			{Case: "all cases", Action: "dashboard-url", Status: conformance.NotImplemented},
+		}}
+
+		output := new(bytes.Buffer)
+		Expect(report.WriteText(output)).To(Succeed())
+
+		Expect(output.String()).To(Equal(`conformance of /path/to/adapter
+  PASSED          no subcommand
+  FAILED          create-binding: stdin JSON
+      expected exit code 0, got 1
+      stderr:
+      boom
+  NOT IMPLEMENTED dashboard-url: all cases
+1 passed, 1 failed, 0 skipped, 1 not implemented
+`))
+	})
+})
+
+var _ = Describe("Fixtures", func() {
+	It("fills the input params of the actions it has none for", func() {
+		fixtures := conformance.Fixtures{Input: serviceadapter.InputParams{
+			DashboardUrl: serviceadapter.DashboardUrlJSONParams{InstanceId: "my-instance"},
+		}}
+
+		fixtures = fixtures.WithDefaults()
+
+		Expect(fixtures.Input.DashboardUrl).To(Equal(serviceadapter.DashboardUrlJSONParams{InstanceId: "my-instance"}))
+		Expect(fixtures.Input.GenerateManifest).To(Equal(conformance.DefaultFixtures().Input.GenerateManifest))
+	})
+})
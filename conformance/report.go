@@ -0,0 +1,99 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conformance
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type Status string
+
+const (
+	Passed         Status = "passed"
+	Failed         Status = "failed"
+	Skipped        Status = "skipped"
+	NotImplemented Status = "not implemented"
+)
+
+type Result struct {
+	Case    string `json:"case"`
+	Action  string `json:"action,omitempty"`
+	Status  Status `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+func (r Result) Name() string {
+	if r.Action == "" {
+		return r.Case
+	}
+	return r.Action + ": " + r.Case
+}
+
+// Report holds the result of every case, in the order they ran.
+type Report struct {
+	Adapter string   `json:"adapter"`
+	Results []Result `json:"results"`
+}
+
+// Passed tells whether no case failed.
+func (r Report) Passed() bool {
+	return r.Count(Failed) == 0
+}
+
+func (r Report) Count(status Status) int {
+	count := 0
+	for _, result := range r.Results {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// WriteText writes a line per case, with the messages of failures, and a
+// summary.
+func (r Report) WriteText(w io.Writer) error {
+	var text strings.Builder
+	fmt.Fprintf(&text, "conformance of %s\n", r.Adapter)
+	for _, result := range r.Results {
+		fmt.Fprintf(&text, "  %-15s %s\n", strings.ToUpper(string(result.Status)), result.Name())
+		if result.Status == Failed || result.Status == Skipped {
+			for _, line := range strings.Split(result.Message, "\n") {
+				fmt.Fprintf(&text, "      %s\n", line)
+			}
+		}
+	}
+	fmt.Fprintf(&text, "%d passed, %d failed, %d skipped, %d not implemented\n",
+		r.Count(Passed), r.Count(Failed), r.Count(Skipped), r.Count(NotImplemented))
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+func (r *Report) add(results ...Result) {
+	r.Results = append(r.Results, results...)
+}
+
+// addNotImplemented reports action as not implemented, unless the adapter
+// failed to say so consistently.
+func (r *Report) addNotImplemented(action string, failures ...Result) {
+	if len(failures) > 0 {
+		r.add(failures...)
+		return
+	}
+	r.add(Result{Case: "all cases", Action: action, Status: NotImplemented})
+}
@@ -0,0 +1,106 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+// Adapter invokes a service adapter subcommand, passing input via stdin as
+// ODB does, and returns its stdout.
+type Adapter interface {
+	Invoke(ctx context.Context, action string, input serviceadapter.InputParams) ([]byte, error)
+}
+
+// AdapterError is returned when the adapter exits with a non-zero code.
+type AdapterError struct {
+	Action   string
+	ExitCode int
+	Stdout   string
+	Stderr   string
+}
+
+func (e AdapterError) Error() string {
+	message := strings.TrimSpace(e.Stdout)
+	if message == "" {
+		message = strings.TrimSpace(e.Stderr)
+	}
+	return fmt.Sprintf("%s failed with exit code %d: %s", e.Action, e.ExitCode, message)
+}
+
+// InProcessAdapter invokes Handler without starting a process.
+type InProcessAdapter struct {
+	Handler serviceadapter.CommandLineHandler
+}
+
+func (a InProcessAdapter) Invoke(ctx context.Context, action string, input serviceadapter.InputParams) ([]byte, error) {
+	stdin, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	err = a.Handler.HandleWithContext(ctx, []string{"service-adapter", action}, &stdout, &stderr, bytes.NewReader(stdin))
+	if err != nil {
+		exitCode := serviceadapter.ErrorExitCode
+		var cliErr serviceadapter.CLIHandlerError
+		if errors.As(err, &cliErr) {
+			exitCode = cliErr.ExitCode
+		}
+		return nil, AdapterError{Action: action, ExitCode: exitCode, Stdout: stdout.String(), Stderr: stderr.String()}
+	}
+	return stdout.Bytes(), nil
+}
+
+// BinaryAdapter runs the adapter binary at Path, with Env added to its
+// environment.
+type BinaryAdapter struct {
+	Path string
+	Env  []string
+}
+
+func (a BinaryAdapter) Invoke(ctx context.Context, action string, input serviceadapter.InputParams) ([]byte, error) {
+	stdin, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, a.Path, action)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if a.Env != nil {
+		cmd.Env = append(cmd.Environ(), a.Env...)
+	}
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil, AdapterError{Action: action, ExitCode: exitErr.ExitCode(), Stdout: stdout.String(), Stderr: stderr.String()}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("running %s: %s", action, err)
+	}
+	return stdout.Bytes(), nil
+}
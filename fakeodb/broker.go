@@ -0,0 +1,463 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fakeodb is a local stand-in for the On-Demand Service Broker, to
+// test the lifecycle of service instances with a service adapter without
+// BOSH or a broker. It invokes the adapter as ODB does at each step of the
+// lifecycle, feeding the previous manifest, plan, secrets and configs
+// forward, and stores the secrets of ((odb_secret:...)) placeholders as
+// CredHub would.
+package fakeodb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+const DefaultServiceID = "service-id"
+
+var (
+	odbSecretPattern       = regexp.MustCompile(`\(\(` + serviceadapter.ODBSecretPrefix + `:([^()\s]+)\)\)`)
+	credhubSecretReference = regexp.MustCompile(`\(\((/[^()\s]+)\)\)`)
+)
+
+// Instance is a service instance as the broker knows it.
+type Instance struct {
+	ID             string
+	PlanID         string
+	DeploymentName string
+
+	// Manifest is the manifest as deployed: ((odb_secret:name))
+	// placeholders are replaced by references to the secrets stored.
+	Manifest     string
+	Configs      serviceadapter.BOSHConfigs
+	DashboardURL string
+	Bindings     map[string]serviceadapter.Binding
+}
+
+// BoshManifest unmarshals the deployed manifest.
+func (i *Instance) BoshManifest() (bosh.BoshManifest, error) {
+	var manifest bosh.BoshManifest
+	err := yaml.Unmarshal([]byte(i.Manifest), &manifest)
+	return manifest, err
+}
+
+// Broker provisions service instances of the plans in Plans, deploying
+// the releases and stemcells of ServiceDeployment. Change them between
+// steps to simulate a new version of the service offering.
+type Broker struct {
+	Adapter           Adapter
+	ServiceID         string
+	ServiceDeployment serviceadapter.ServiceDeployment
+	Plans             map[string]serviceadapter.Plan
+
+	mu        sync.Mutex
+	instances map[string]*Instance
+	// secrets stands for CredHub, by path
+	secrets map[string]interface{}
+}
+
+func New(adapter Adapter, serviceDeployment serviceadapter.ServiceDeployment, plans map[string]serviceadapter.Plan) *Broker {
+	return &Broker{
+		Adapter:           adapter,
+		ServiceID:         DefaultServiceID,
+		ServiceDeployment: serviceDeployment,
+		Plans:             plans,
+	}
+}
+
+// Instance returns a copy of the service instance, if it exists.
+func (b *Broker) Instance(instanceID string) (Instance, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	instance, ok := b.instances[instanceID]
+	if !ok {
+		return Instance{}, false
+	}
+	return instance.copy(), true
+}
+
+// Secret returns the value stored for a ((odb_secret:name)) placeholder of
+// the service instance's manifest.
+func (b *Broker) Secret(instanceID, name string) (interface{}, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	value, ok := b.secrets[b.secretPath("service-instance_"+instanceID, name)]
+	return value, ok
+}
+
+// Provision generates the manifest of a new service instance and its
+// dashboard URL, if the adapter implements dashboard-url.
+func (b *Broker) Provision(ctx context.Context, instanceID, planID string, parameters map[string]interface{}) (Instance, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, exists := b.instances[instanceID]; exists {
+		return Instance{}, fmt.Errorf("instance %s already exists", instanceID)
+	}
+	plan, err := b.plan(planID)
+	if err != nil {
+		return Instance{}, err
+	}
+
+	instance := &Instance{
+		ID:             instanceID,
+		PlanID:         planID,
+		DeploymentName: "service-instance_" + instanceID,
+		Bindings:       map[string]serviceadapter.Binding{},
+	}
+	requestParams := b.requestParams(planID, parameters)
+	requestParams["organization_guid"] = "organization-guid"
+	requestParams["space_guid"] = "space-guid"
+	if err := b.generateManifest(ctx, instance, plan, nil, requestParams); err != nil {
+		return Instance{}, err
+	}
+
+	dashboardURL, err := b.dashboardURL(ctx, instance, plan)
+	if err != nil {
+		return Instance{}, err
+	}
+	instance.DashboardURL = dashboardURL
+
+	if b.instances == nil {
+		b.instances = map[string]*Instance{}
+	}
+	b.instances[instanceID] = instance
+	return instance.copy(), nil
+}
+
+// Update regenerates the manifest of a service instance with a new plan,
+// or its current plan if planID is empty, and new arbitrary parameters.
+func (b *Broker) Update(ctx context.Context, instanceID, planID string, parameters map[string]interface{}) (Instance, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	instance, err := b.instance(instanceID)
+	if err != nil {
+		return Instance{}, err
+	}
+	previousPlan, err := b.plan(instance.PlanID)
+	if err != nil {
+		return Instance{}, err
+	}
+	if planID == "" {
+		planID = instance.PlanID
+	}
+	plan, err := b.plan(planID)
+	if err != nil {
+		return Instance{}, err
+	}
+
+	requestParams := b.requestParams(planID, parameters)
+	requestParams["previous_values"] = map[string]interface{}{
+		"plan_id":    instance.PlanID,
+		"service_id": b.ServiceID,
+	}
+	updated := instance.copy()
+	updated.PlanID = planID
+	if err := b.generateManifest(ctx, &updated, plan, &previousPlan, requestParams); err != nil {
+		return Instance{}, err
+	}
+	*instance = updated
+	return instance.copy(), nil
+}
+
+// Upgrade regenerates the manifest of a service instance without changing
+// its plan or parameters, as upgrade-all-service-instances does, typically
+// after ServiceDeployment changed.
+func (b *Broker) Upgrade(ctx context.Context, instanceID string) (Instance, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	instance, err := b.instance(instanceID)
+	if err != nil {
+		return Instance{}, err
+	}
+	plan, err := b.plan(instance.PlanID)
+	if err != nil {
+		return Instance{}, err
+	}
+
+	upgraded := instance.copy()
+	if err := b.generateManifest(ctx, &upgraded, plan, &plan, serviceadapter.RequestParameters{}); err != nil {
+		return Instance{}, err
+	}
+	*instance = upgraded
+	return instance.copy(), nil
+}
+
+// Bind creates a binding with the secrets of the instance's manifest
+// resolved.
+func (b *Broker) Bind(ctx context.Context, instanceID, bindingID string, parameters map[string]interface{}) (serviceadapter.Binding, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	instance, err := b.instance(instanceID)
+	if err != nil {
+		return serviceadapter.Binding{}, err
+	}
+	if _, exists := instance.Bindings[bindingID]; exists {
+		return serviceadapter.Binding{}, fmt.Errorf("binding %s already exists", bindingID)
+	}
+
+	requestParams := b.requestParams(instance.PlanID, parameters)
+	requestParams["app_guid"] = "app-guid"
+	requestParams["bind_resource"] = map[string]interface{}{"app_guid": "app-guid"}
+	input, err := b.bindingInput(instance, bindingID, requestParams)
+	if err != nil {
+		return serviceadapter.Binding{}, err
+	}
+
+	stdout, err := b.Adapter.Invoke(ctx, "create-binding", serviceadapter.InputParams{CreateBinding: serviceadapter.CreateBindingJSONParams(input)})
+	if err != nil {
+		return serviceadapter.Binding{}, err
+	}
+	var binding serviceadapter.Binding
+	if err := json.Unmarshal(stdout, &binding); err != nil {
+		return serviceadapter.Binding{}, fmt.Errorf("unmarshalling binding: %s", err)
+	}
+	instance.Bindings[bindingID] = binding
+	return binding, nil
+}
+
+// Unbind deletes a binding.
+func (b *Broker) Unbind(ctx context.Context, instanceID, bindingID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	instance, err := b.instance(instanceID)
+	if err != nil {
+		return err
+	}
+	if _, exists := instance.Bindings[bindingID]; !exists {
+		return fmt.Errorf("binding %s not found", bindingID)
+	}
+
+	input, err := b.bindingInput(instance, bindingID, b.requestParams(instance.PlanID, nil))
+	if err != nil {
+		return err
+	}
+	if _, err := b.Adapter.Invoke(ctx, "delete-binding", serviceadapter.InputParams{DeleteBinding: input}); err != nil {
+		return err
+	}
+	delete(instance.Bindings, bindingID)
+	return nil
+}
+
+// Deprovision deletes a service instance without bindings and its
+// secrets. The adapter is not invoked, as ODB only deletes the deployment.
+func (b *Broker) Deprovision(ctx context.Context, instanceID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	instance, err := b.instance(instanceID)
+	if err != nil {
+		return err
+	}
+	if len(instance.Bindings) > 0 {
+		return fmt.Errorf("instance %s has bindings", instanceID)
+	}
+
+	for path := range b.secrets {
+		if strings.HasPrefix(path, b.secretPath(instance.DeploymentName, "")) {
+			delete(b.secrets, path)
+		}
+	}
+	delete(b.instances, instanceID)
+	return nil
+}
+
+func (b *Broker) generateManifest(ctx context.Context, instance *Instance, plan serviceadapter.Plan, previousPlan *serviceadapter.Plan, requestParams serviceadapter.RequestParameters) error {
+	serviceDeployment := b.ServiceDeployment
+	serviceDeployment.DeploymentName = instance.DeploymentName
+
+	input := serviceadapter.GenerateManifestJSONParams{
+		ServiceDeployment: toJSON(serviceDeployment),
+		Plan:              toJSON(plan),
+		RequestParameters: toJSON(requestParams),
+		PreviousManifest:  instance.Manifest,
+		PreviousPlan:      toJSON(previousPlan),
+		PreviousSecrets:   optionalJSON(b.manifestSecrets(instance.Manifest)),
+		PreviousConfigs:   optionalJSON(instance.Configs),
+	}
+	stdout, err := b.Adapter.Invoke(ctx, "generate-manifest", serviceadapter.InputParams{GenerateManifest: input})
+	if err != nil {
+		return err
+	}
+
+	var output serviceadapter.MarshalledGenerateManifest
+	if err := json.Unmarshal(stdout, &output); err != nil {
+		return fmt.Errorf("unmarshalling generate-manifest output: %s", err)
+	}
+	manifest, err := b.storeSecrets(instance.DeploymentName, output)
+	if err != nil {
+		return err
+	}
+	instance.Manifest = manifest
+	instance.Configs = output.Configs
+	return nil
+}
+
+// storeSecrets stores the secrets of the manifest's ((odb_secret:name))
+// placeholders and replaces them with references to the stored secrets.
+func (b *Broker) storeSecrets(deploymentName string, output serviceadapter.MarshalledGenerateManifest) (string, error) {
+	var missing []string
+	manifest := odbSecretPattern.ReplaceAllStringFunc(output.Manifest, func(placeholder string) string {
+		name := odbSecretPattern.FindStringSubmatch(placeholder)[1]
+		value, ok := output.ODBManagedSecrets[name]
+		if !ok {
+			missing = append(missing, name)
+			return placeholder
+		}
+		path := b.secretPath(deploymentName, name)
+		if b.secrets == nil {
+			b.secrets = map[string]interface{}{}
+		}
+		b.secrets[path] = value
+		return "((" + path + "))"
+	})
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return "", fmt.Errorf("manifest references ODB managed secrets the adapter did not generate: %v", missing)
+	}
+	return manifest, nil
+}
+
+// manifestSecrets resolves the references of manifest to stored secrets.
+func (b *Broker) manifestSecrets(manifest string) serviceadapter.ManifestSecrets {
+	if manifest == "" {
+		return nil
+	}
+	secrets := serviceadapter.ManifestSecrets{}
+	for _, match := range credhubSecretReference.FindAllStringSubmatch(manifest, -1) {
+		value, ok := b.secrets[match[1]]
+		if !ok {
+			continue
+		}
+		if s, isString := value.(string); isString {
+			secrets[match[0]] = s
+		} else {
+			secrets[match[0]] = toJSON(value)
+		}
+	}
+	return secrets
+}
+
+func (b *Broker) dashboardURL(ctx context.Context, instance *Instance, plan serviceadapter.Plan) (string, error) {
+	stdout, err := b.Adapter.Invoke(ctx, "dashboard-url", serviceadapter.InputParams{DashboardUrl: serviceadapter.DashboardUrlJSONParams{
+		InstanceId: instance.ID,
+		Plan:       toJSON(plan),
+		Manifest:   instance.Manifest,
+	}})
+	if adapterErr, ok := err.(AdapterError); ok && adapterErr.ExitCode == serviceadapter.NotImplementedExitCode {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var dashboardURL serviceadapter.DashboardUrl
+	if err := json.Unmarshal(stdout, &dashboardURL); err != nil {
+		return "", fmt.Errorf("unmarshalling dashboard URL: %s", err)
+	}
+	return dashboardURL.DashboardUrl, nil
+}
+
+func (b *Broker) bindingInput(instance *Instance, bindingID string, requestParams serviceadapter.RequestParameters) (serviceadapter.DeleteBindingJSONParams, error) {
+	manifest, err := instance.BoshManifest()
+	if err != nil {
+		return serviceadapter.DeleteBindingJSONParams{}, fmt.Errorf("unmarshalling manifest: %s", err)
+	}
+	return serviceadapter.DeleteBindingJSONParams{
+		BindingId:         bindingID,
+		BoshVms:           toJSON(topology(manifest)),
+		Manifest:          instance.Manifest,
+		RequestParameters: toJSON(requestParams),
+		Secrets:           optionalJSON(b.manifestSecrets(instance.Manifest)),
+	}, nil
+}
+
+// topology makes up addresses for the instances of every instance group.
+func topology(manifest bosh.BoshManifest) bosh.BoshVMs {
+	vms := bosh.BoshVMs{}
+	for g, instanceGroup := range manifest.InstanceGroups {
+		vms[instanceGroup.Name] = []string{}
+		for i := 0; i < instanceGroup.Instances; i++ {
+			vms[instanceGroup.Name] = append(vms[instanceGroup.Name], fmt.Sprintf("10.0.%d.%d", g, i+1))
+		}
+	}
+	return vms
+}
+
+func (b *Broker) requestParams(planID string, parameters map[string]interface{}) serviceadapter.RequestParameters {
+	if parameters == nil {
+		parameters = map[string]interface{}{}
+	}
+	return serviceadapter.RequestParameters{
+		"plan_id":    planID,
+		"service_id": b.ServiceID,
+		"parameters": parameters,
+	}
+}
+
+func (b *Broker) plan(planID string) (serviceadapter.Plan, error) {
+	plan, ok := b.Plans[planID]
+	if !ok {
+		return serviceadapter.Plan{}, fmt.Errorf("plan %s not found", planID)
+	}
+	return plan, nil
+}
+
+func (b *Broker) instance(instanceID string) (*Instance, error) {
+	instance, ok := b.instances[instanceID]
+	if !ok {
+		return nil, fmt.Errorf("instance %s not found", instanceID)
+	}
+	return instance, nil
+}
+
+func (b *Broker) secretPath(deploymentName, name string) string {
+	return fmt.Sprintf("/odb/%s/%s/%s", b.ServiceID, deploymentName, name)
+}
+
+func (i *Instance) copy() Instance {
+	copied := *i
+	copied.Bindings = map[string]serviceadapter.Binding{}
+	for id, binding := range i.Bindings {
+		copied.Bindings[id] = binding
+	}
+	return copied
+}
+
+func toJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+// optionalJSON leaves out optional input params that are not set, as ODB
+// does.
+func optionalJSON(v interface{}) string {
+	if data := toJSON(v); data != "null" {
+		return data
+	}
+	return ""
+}
@@ -0,0 +1,278 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeodb_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+	"github.com/pivotal-cf/on-demand-services-sdk/fakeodb"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter/fakes"
+)
+
+var _ = Describe("Broker", func() {
+	var (
+		ctx                   context.Context
+		fakeManifestGenerator *fakes.FakeManifestGenerator
+		fakeBinder            *fakes.FakeBinder
+		fakeDashboard         *fakes.FakeDashboardUrlGenerator
+		broker                *fakeodb.Broker
+		generatedPasswords    int
+	)
+
+	plan := func(instances int) serviceadapter.Plan {
+		return serviceadapter.Plan{InstanceGroups: []serviceadapter.InstanceGroup{{
+			Name:      "db",
+			VMType:    "small",
+			Networks:  []string{"default"},
+			AZs:       []string{"z1"},
+			Instances: instances,
+		}}}
+	}
+
+	secret := func(instanceID string) interface{} {
+		value, stored := broker.Secret(instanceID, "admin_password")
+		Expect(stored).To(BeTrue())
+		return value
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		generatedPasswords = 0
+
+		fakeManifestGenerator = new(fakes.FakeManifestGenerator)
+		fakeManifestGenerator.GenerateManifestStub = func(params serviceadapter.GenerateManifestParams) (serviceadapter.GenerateManifestOutput, error) {
+			// keeps the password of the previous deployment, as adapters should
+			password := ""
+			for reference, value := range params.PreviousSecrets {
+				if strings.HasSuffix(reference, "/admin_password))") {
+					password = value
+				}
+			}
+			if password == "" {
+				generatedPasswords++
+				password = fmt.Sprintf("generated-password-%d", generatedPasswords)
+			}
+
+			return serviceadapter.GenerateManifestOutput{
+				Manifest: bosh.BoshManifest{
+					Name:     params.ServiceDeployment.DeploymentName,
+					Releases: []bosh.Release{{Name: "db", Version: params.ServiceDeployment.Releases[0].Version}},
+					InstanceGroups: []bosh.InstanceGroup{{
+						Name:      "db",
+						Instances: params.Plan.InstanceGroups[0].Instances,
+						Properties: map[string]interface{}{
+							"admin_password": "((odb_secret:admin_password))",
+							"tls":            params.RequestParams.ArbitraryParams()["tls"],
+						},
+					}},
+				},
+				ODBManagedSecrets: serviceadapter.ODBManagedSecrets{"admin_password": password},
+				Configs:           serviceadapter.BOSHConfigs{"cloud": "generation: " + fmt.Sprint(fakeManifestGenerator.GenerateManifestCallCount())},
+			}, nil
+		}
+
+		fakeBinder = new(fakes.FakeBinder)
+		fakeBinder.CreateBindingStub = func(params serviceadapter.CreateBindingParams) (serviceadapter.Binding, error) {
+			credentials := map[string]interface{}{"hosts": params.DeploymentTopology["db"]}
+			for reference, value := range params.Secrets {
+				credentials[reference] = value
+			}
+			return serviceadapter.Binding{Credentials: credentials}, nil
+		}
+		fakeDashboard = new(fakes.FakeDashboardUrlGenerator)
+		fakeDashboard.DashboardUrlReturns(serviceadapter.DashboardUrl{DashboardUrl: "https://dashboard.example.com"}, nil)
+
+		broker = fakeodb.New(
+			fakeodb.InProcessAdapter{Handler: serviceadapter.CommandLineHandler{
+				ManifestGenerator:     fakeManifestGenerator,
+				Binder:                fakeBinder,
+				DashboardURLGenerator: fakeDashboard,
+			}},
+			serviceadapter.ServiceDeployment{
+				Releases:  serviceadapter.ServiceReleases{{Name: "db", Version: "1.0", Jobs: []string{"db"}}},
+				Stemcells: []serviceadapter.Stemcell{{OS: "ubuntu-jammy", Version: "1"}},
+			},
+			map[string]serviceadapter.Plan{"small": plan(1), "large": plan(3)},
+		)
+	})
+
+	It("provisions service instances with their secrets stored", func() {
+		instance, err := broker.Provision(ctx, "instance-1", "small", map[string]interface{}{"tls": true})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(instance.DeploymentName).To(Equal("service-instance_instance-1"))
+		Expect(instance.DashboardURL).To(Equal("https://dashboard.example.com"))
+		Expect(instance.Configs).To(Equal(serviceadapter.BOSHConfigs{"cloud": "generation: 1"}))
+		manifest, err := instance.BoshManifest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest.InstanceGroups[0].Properties).To(Equal(map[string]interface{}{
+			"admin_password": "((/odb/service-id/service-instance_instance-1/admin_password))",
+			"tls":            true,
+		}))
+		Expect(secret("instance-1")).To(Equal("generated-password-1"))
+
+		params := fakeManifestGenerator.GenerateManifestArgsForCall(0)
+		Expect(params.ServiceDeployment.DeploymentName).To(Equal("service-instance_instance-1"))
+		Expect(params.PreviousManifest).To(BeNil())
+		Expect(params.PreviousPlan).To(BeNil())
+		Expect(params.PreviousSecrets).To(BeEmpty())
+		Expect(params.RequestParams).To(HaveKeyWithValue("plan_id", "small"))
+		Expect(params.RequestParams).To(HaveKeyWithValue("organization_guid", "organization-guid"))
+
+		_, err = broker.Provision(ctx, "instance-1", "small", nil)
+		Expect(err).To(MatchError("instance instance-1 already exists"))
+	})
+
+	It("feeds the previous manifest, plan, secrets and configs forward", func() {
+		_, err := broker.Provision(ctx, "instance-1", "small", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		instance, err := broker.Update(ctx, "instance-1", "large", map[string]interface{}{"tls": true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instance.PlanID).To(Equal("large"))
+
+		params := fakeManifestGenerator.GenerateManifestArgsForCall(1)
+		Expect(params.Plan).To(Equal(plan(3)))
+		Expect(*params.PreviousPlan).To(Equal(plan(1)))
+		Expect(params.PreviousManifest.InstanceGroups[0].Instances).To(Equal(1))
+		Expect(params.PreviousSecrets).To(Equal(serviceadapter.ManifestSecrets{
+			"((/odb/service-id/service-instance_instance-1/admin_password))": "generated-password-1",
+		}))
+		Expect(params.PreviousConfigs).To(Equal(serviceadapter.BOSHConfigs{"cloud": "generation: 1"}))
+		Expect(params.RequestParams["previous_values"]).To(Equal(map[string]interface{}{"plan_id": "small", "service_id": "service-id"}))
+
+		broker.ServiceDeployment.Releases[0].Version = "2.0"
+		instance, err = broker.Upgrade(ctx, "instance-1")
+		Expect(err).NotTo(HaveOccurred())
+
+		params = fakeManifestGenerator.GenerateManifestArgsForCall(2)
+		Expect(params.ServiceDeployment.Releases[0].Version).To(Equal("2.0"))
+		Expect(*params.PreviousPlan).To(Equal(params.Plan))
+		Expect(params.RequestParams).To(BeEmpty())
+		Expect(params.PreviousConfigs).To(Equal(serviceadapter.BOSHConfigs{"cloud": "generation: 2"}))
+		manifest, err := instance.BoshManifest()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest.Releases[0].Version).To(Equal("2.0"))
+		Expect(secret("instance-1")).To(Equal("generated-password-1"))
+	})
+
+	It("leaves the instance as it was when the adapter fails", func() {
+		provisioned, err := broker.Provision(ctx, "instance-1", "small", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		fakeManifestGenerator.GenerateManifestStub = nil
+		fakeManifestGenerator.GenerateManifestReturns(serviceadapter.GenerateManifestOutput{}, errors.New("cannot scale down"))
+		_, err = broker.Update(ctx, "instance-1", "large", nil)
+
+		Expect(err).To(MatchError("generate-manifest failed with exit code 1: cannot scale down"))
+		Expect(err).To(BeAssignableToTypeOf(fakeodb.AdapterError{}))
+		instance, _ := broker.Instance("instance-1")
+		Expect(instance).To(Equal(provisioned))
+	})
+
+	It("fails when the manifest references secrets the adapter did not generate", func() {
+		fakeManifestGenerator.GenerateManifestStub = nil
+		fakeManifestGenerator.GenerateManifestReturns(serviceadapter.GenerateManifestOutput{Manifest: bosh.BoshManifest{
+			Name:       "service-instance_instance-1",
+			Properties: map[string]interface{}{"password": "((odb_secret:missing))"},
+		}}, nil)
+
+		_, err := broker.Provision(ctx, "instance-1", "small", nil)
+
+		Expect(err).To(MatchError("manifest references ODB managed secrets the adapter did not generate: [missing]"))
+		_, exists := broker.Instance("instance-1")
+		Expect(exists).To(BeFalse())
+	})
+
+	It("binds and unbinds with the secrets resolved", func() {
+		_, err := broker.Provision(ctx, "instance-1", "large", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		binding, err := broker.Bind(ctx, "instance-1", "binding-1", map[string]interface{}{"role": "admin"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(binding.Credentials).To(Equal(map[string]interface{}{
+			"hosts": []interface{}{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+			"((/odb/service-id/service-instance_instance-1/admin_password))": "generated-password-1",
+		}))
+		params := fakeBinder.CreateBindingArgsForCall(0)
+		Expect(params.BindingID).To(Equal("binding-1"))
+		Expect(params.RequestParams.ArbitraryParams()).To(Equal(map[string]interface{}{"role": "admin"}))
+		Expect(params.RequestParams).To(HaveKeyWithValue("app_guid", "app-guid"))
+
+		instance, _ := broker.Instance("instance-1")
+		Expect(instance.Bindings).To(HaveKey("binding-1"))
+		_, err = broker.Bind(ctx, "instance-1", "binding-1", nil)
+		Expect(err).To(MatchError("binding binding-1 already exists"))
+		Expect(broker.Deprovision(ctx, "instance-1")).To(MatchError("instance instance-1 has bindings"))
+
+		Expect(broker.Unbind(ctx, "instance-1", "binding-1")).To(Succeed())
+		Expect(fakeBinder.DeleteBindingArgsForCall(0).Secrets).To(HaveLen(1))
+		Expect(broker.Unbind(ctx, "instance-1", "binding-1")).To(MatchError("binding binding-1 not found"))
+	})
+
+	It("deprovisions service instances and deletes their secrets", func() {
+		_, err := broker.Provision(ctx, "instance-1", "small", nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = broker.Provision(ctx, "instance-2", "small", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(broker.Deprovision(ctx, "instance-1")).To(Succeed())
+
+		_, exists := broker.Instance("instance-1")
+		Expect(exists).To(BeFalse())
+		_, stored := broker.Secret("instance-1", "admin_password")
+		Expect(stored).To(BeFalse())
+		Expect(secret("instance-2")).To(Equal("generated-password-2"))
+		Expect(broker.Deprovision(ctx, "instance-1")).To(MatchError("instance instance-1 not found"))
+	})
+
+	It("provisions without a dashboard URL when the adapter does not generate them", func() {
+		broker.Adapter = fakeodb.InProcessAdapter{Handler: serviceadapter.CommandLineHandler{
+			ManifestGenerator: fakeManifestGenerator,
+			Binder:            fakeBinder,
+		}}
+
+		instance, err := broker.Provision(ctx, "instance-1", "small", nil)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instance.DashboardURL).To(BeEmpty())
+	})
+
+	It("drives adapter binaries", func() {
+		broker.Adapter = fakeodb.BinaryAdapter{Path: adapterBin}
+
+		instance, err := broker.Provision(ctx, "instance-1", "small", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instance.DashboardURL).To(Equal("http://dashboard.com"))
+
+		binding, err := broker.Bind(ctx, "instance-1", "binding-1", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(binding.Credentials).To(Equal(map[string]interface{}{"binding": "this binds"}))
+		Expect(broker.Unbind(ctx, "instance-1", "binding-1")).To(Succeed())
+
+		broker.Adapter = fakeodb.BinaryAdapter{Path: adapterBin, Env: []string{"OPERATION_FAILS=true"}}
+		_, err = broker.Upgrade(ctx, "instance-1")
+		Expect(err).To(MatchError("generate-manifest failed with exit code 1: some message to the user"))
+	})
+})
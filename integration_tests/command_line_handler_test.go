@@ -21,10 +21,12 @@ import (
 	"fmt"
 	"io"
 	"os/exec"
+	"syscall"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 	"github.com/onsi/gomega/gexec"
 	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
 	"github.com/pivotal-cf/on-demand-services-sdk/integration_tests/testharness/testvariables"
@@ -152,14 +154,14 @@ var _ = Describe("Command line handler", func() {
 		exitCode = startPassingCommandAndGetExitCode([]string{})
 
 		Expect(exitCode).To(Equal(1))
-		Expect(stderr.String()).To(Equal("[odb-sdk] the following commands are supported: create-binding, dashboard-url, delete-binding, generate-manifest, generate-plan-schemas\n"))
+		Expect(stderr.String()).To(Equal("[odb-sdk] the following commands are supported: capabilities, create-binding, dashboard-url, delete-binding, generate-manifest, generate-plan-schemas, replay\n"))
 	})
 
 	It("logs and exits with 1 if called with a non-existing subcommand", func() {
 		exitCode = startPassingCommandAndGetExitCode([]string{"non-existing-subcommand"})
 
 		Expect(exitCode).To(Equal(1))
-		Expect(stderr.String()).To(ContainSubstring(`[odb-sdk] unknown subcommand: non-existing-subcommand. The following commands are supported: create-binding, dashboard-url, delete-binding, generate-manifest, generate-plan-schemas`))
+		Expect(stderr.String()).To(ContainSubstring(`[odb-sdk] unknown subcommand: non-existing-subcommand. The following commands are supported: capabilities, create-binding, dashboard-url, delete-binding, generate-manifest, generate-plan-schemas, replay`))
 	})
 
 	Describe("generate-manifest subcommand", func() {
@@ -203,6 +205,26 @@ var _ = Describe("Command line handler", func() {
 					`Missing arguments for generate-manifest. Usage: \S+ generate-manifest <service-deployment-JSON> <plan-JSON> <request-params-JSON> <previous-manifest-YAML> <previous-plan-JSON>`))
 			})
 
+			It("is terminated by SIGTERM, as the manifest generator takes no context", func() {
+				cmd := exec.Command(adapterBin,
+					"generate-manifest",
+					toJson(expectedServiceDeployment),
+					toJson(expectedCurrentPlan),
+					toJson(expectedRequestParams),
+					"",
+					"null",
+				)
+				cmd.Env = append(resetCommandEnv(), testvariables.OperationDelayKey+"=4s")
+				runningAdapter, err := gexec.Start(cmd, io.MultiWriter(GinkgoWriter, stdout), io.MultiWriter(GinkgoWriter, stderr))
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(runningAdapter.Err).Should(gbytes.Say(`\[odb-sdk\] handling generate-manifest`))
+
+				runningAdapter.Terminate()
+
+				Eventually(runningAdapter, time.Second*2).Should(gexec.Exit(128 + int(syscall.SIGTERM)))
+				Expect(stdout.String()).To(BeEmpty())
+			})
+
 			It("exits 1 and logs when a generic error occurs", func() {
 				exitCode = startFailingCommandAndGetExitCode([]string{
 					"generate-manifest",
@@ -692,6 +714,32 @@ var _ = Describe("Command line handler", func() {
 			})
 		})
 	})
+
+	Describe("capabilities subcommand", func() {
+		It("describes the implemented actions without any input", func() {
+			exitCode = startCommandWithNoStdinAndGetExitCode([]string{"capabilities"})
+
+			Expect(exitCode).To(Equal(0))
+			var capabilities serviceadapter.Capabilities
+			Expect(json.Unmarshal(stdout.Bytes(), &capabilities)).To(Succeed())
+			Expect(capabilities.SDKVersion).NotTo(BeEmpty())
+			Expect(capabilities.Actions).To(HaveKeyWithValue("generate-manifest", serviceadapter.ActionCapabilities{
+				Implemented:    true,
+				InputProtocols: []string{serviceadapter.PositionalArgsProtocol, serviceadapter.StdinJSONProtocol},
+				OptionalFields: []string{"previous_secrets", "previous_configs", "uaa_client", "timeout"},
+			}))
+			Expect(capabilities.Actions).To(HaveKeyWithValue("capabilities", serviceadapter.ActionCapabilities{Implemented: true}))
+		})
+
+		It("reports actions that are not implemented", func() {
+			exitCode = startEmptyImplementationCommandAndGetExitCode([]string{"capabilities"})
+
+			Expect(exitCode).To(Equal(0))
+			var capabilities serviceadapter.Capabilities
+			Expect(json.Unmarshal(stdout.Bytes(), &capabilities)).To(Succeed())
+			Expect(capabilities.Actions["create-binding"].Implemented).To(BeFalse())
+		})
+	})
 })
 
 func startEmptyImplementationCommandAndGetExitCode(args []string) int {
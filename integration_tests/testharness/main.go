@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
 	"github.com/pivotal-cf/on-demand-services-sdk/integration_tests/testharness/testvariables"
@@ -46,6 +47,10 @@ func main() {
 type manifestGenerator struct{}
 
 func (m *manifestGenerator) GenerateManifest(params serviceadapter.GenerateManifestParams) (serviceadapter.GenerateManifestOutput, error) {
+	if delay, err := time.ParseDuration(os.Getenv(testvariables.OperationDelayKey)); err == nil {
+		time.Sleep(delay)
+	}
+
 	if os.Getenv(testvariables.OperationFailsKey) == OperationShouldFail {
 		fmt.Fprintf(os.Stderr, "not valid")
 		return serviceadapter.GenerateManifestOutput{}, errors.New("some message to the user")
@@ -122,6 +127,10 @@ func (d *dashboard) DashboardUrl(params serviceadapter.DashboardUrlParams) (serv
 
 type schemaGenerator struct{}
 
+type planParameters struct {
+	BillingAccount string `json:"billing-account" description:"Billing account number used to charge use of shared fake server."`
+}
+
 func (s *schemaGenerator) GeneratePlanSchema(params serviceadapter.GeneratePlanSchemaParams) (serviceadapter.PlanSchema, error) {
 	errs := func(err error) (serviceadapter.PlanSchema, error) {
 		return serviceadapter.PlanSchema{}, err
@@ -131,17 +140,9 @@ func (s *schemaGenerator) GeneratePlanSchema(params serviceadapter.GeneratePlanS
 		return errs(errors.New("An error occurred"))
 	}
 
-	schemas := serviceadapter.JSONSchemas{
-		Parameters: map[string]interface{}{
-			"$schema": "http://json-schema.org/draft-04/schema#",
-			"type":    "object",
-			"properties": map[string]interface{}{
-				"billing-account": map[string]interface{}{
-					"description": "Billing account number used to charge use of shared fake server.",
-					"type":        "string",
-				},
-			},
-		},
+	schemas, err := serviceadapter.JSONSchemasFor(planParameters{})
+	if err != nil {
+		return errs(err)
 	}
 	return serviceadapter.PlanSchema{
 		ServiceInstance: serviceadapter.ServiceInstanceSchema{
@@ -22,6 +22,9 @@ const (
 
 	DoNotImplementInterfacesKey = "DO_NOT_IMPLEMENT_INTERFACES"
 
+	// OperationDelayKey is the duration generate-manifest sleeps for.
+	OperationDelayKey = "OPERATION_DELAY"
+
 	ErrAppGuidNotProvided   = "no app guid"
 	ErrBindingAlreadyExists = "binding already exists"
 	ErrBindingNotFound      = "binding not found"
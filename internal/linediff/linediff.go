@@ -0,0 +1,108 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package linediff compares text line by line.
+package linediff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxTableSize bounds the number of cells of the table Diff computes the
+// longest common subsequence with, which takes time and memory in the
+// product of the number of lines that differ.
+const maxTableSize = 1 << 22
+
+// Diff returns the lines of a and b, prefixed with "- " when only in a,
+// "+ " when only in b and "  " when in both. A final newline does not make
+// an empty last line.
+//
+// Lines common to the start and end of a and b are matched first. When the
+// lines in between are too many to compare, they are all reported as
+// removed from a and added in b.
+func Diff(a, b string) string {
+	aLines, bLines := lines(a), lines(b)
+
+	prefix := 0
+	for prefix < len(aLines) && prefix < len(bLines) && aLines[prefix] == bLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(aLines)-prefix && suffix < len(bLines)-prefix &&
+		aLines[len(aLines)-1-suffix] == bLines[len(bLines)-1-suffix] {
+		suffix++
+	}
+
+	var diff strings.Builder
+	for _, line := range aLines[:prefix] {
+		fmt.Fprintf(&diff, "  %s\n", line)
+	}
+	diffMiddle(&diff, aLines[prefix:len(aLines)-suffix], bLines[prefix:len(bLines)-suffix])
+	for _, line := range aLines[len(aLines)-suffix:] {
+		fmt.Fprintf(&diff, "  %s\n", line)
+	}
+	return diff.String()
+}
+
+func diffMiddle(diff *strings.Builder, aLines, bLines []string) {
+	if (len(aLines)+1)*(len(bLines)+1) > maxTableSize {
+		for _, line := range aLines {
+			fmt.Fprintf(diff, "- %s\n", line)
+		}
+		for _, line := range bLines {
+			fmt.Fprintf(diff, "+ %s\n", line)
+		}
+		return
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// aLines[i:] and bLines[j:]
+	lcs := make([][]int, len(aLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bLines)+1)
+	}
+	for i := len(aLines) - 1; i >= 0; i-- {
+		for j := len(bLines) - 1; j >= 0; j-- {
+			if aLines[i] == bLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(aLines) || j < len(bLines) {
+		switch {
+		case i < len(aLines) && j < len(bLines) && aLines[i] == bLines[j]:
+			fmt.Fprintf(diff, "  %s\n", aLines[i])
+			i, j = i+1, j+1
+		case i < len(aLines) && (j == len(bLines) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(diff, "- %s\n", aLines[i])
+			i++
+		default:
+			fmt.Fprintf(diff, "+ %s\n", bLines[j])
+			j++
+		}
+	}
+}
+
+func lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
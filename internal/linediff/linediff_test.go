@@ -0,0 +1,61 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linediff_test
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/internal/linediff"
+)
+
+var _ = Describe("Diff", func() {
+	DescribeTable("compares lines",
+		func(a, b, expected string) {
+			Expect(linediff.Diff(a, b)).To(Equal(expected))
+		},
+		Entry("empty texts", "", "", ""),
+		Entry("equal texts", "a\nb\n", "a\nb\n", "  a\n  b\n"),
+		Entry("a final newline", "a\nb", "a\nb\n", "  a\n  b\n"),
+		Entry("added text", "", "a\n", "+ a\n"),
+		Entry("removed text", "a\n", "", "- a\n"),
+		Entry("a changed line", "a\nb\nc\n", "a\nx\nc\n", "  a\n- b\n+ x\n  c\n"),
+		Entry("an inserted line", "a\nc\n", "a\nb\nc\n", "  a\n+ b\n  c\n"),
+		Entry("a moved line", "a\nb\nc\n", "b\nc\na\n", "- a\n  b\n  c\n+ a\n"),
+		Entry("repeated lines", "x\nx\n", "x\ny\nx\n", "  x\n+ y\n  x\n"),
+		Entry("an empty line", "a\n\nb\n", "a\nb\n", "  a\n- \n  b\n"),
+	)
+
+	It("reports texts too different to compare as replaced, keeping common lines", func() {
+		var a, b []string
+		for i := 0; i < 3000; i++ {
+			a = append(a, fmt.Sprintf("a%d", i))
+			b = append(b, fmt.Sprintf("b%d", i))
+		}
+		common := "first\n"
+		diff := linediff.Diff(common+strings.Join(a, "\n")+"\nlast\n", common+strings.Join(b, "\n")+"\nlast\n")
+
+		lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+		Expect(lines).To(HaveLen(6002))
+		Expect(lines[0]).To(Equal("  first"))
+		Expect(lines[1]).To(Equal("- a0"))
+		Expect(lines[3001]).To(Equal("+ b0"))
+		Expect(lines[6001]).To(Equal("  last"))
+	})
+})
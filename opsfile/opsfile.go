@@ -0,0 +1,152 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package opsfile applies BOSH ops-files to manifests, using the same path
+// syntax as the BOSH CLI: /key, /key?, /0, /-, /name=value and /name=value?
+package opsfile
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+)
+
+type OpType string
+
+const (
+	Replace OpType = "replace"
+	Remove  OpType = "remove"
+)
+
+type Op struct {
+	Type  OpType      `yaml:"type"`
+	Path  string      `yaml:"path"`
+	Value interface{} `yaml:"value,omitempty"`
+}
+
+type Ops []Op
+
+// OpError reports which op of an ops-file could not be applied.
+type OpError struct {
+	Index int
+	Op    Op
+	Err   error
+}
+
+func (e OpError) Error() string {
+	return fmt.Sprintf("applying op %d (%s %s): %s", e.Index, e.Op.Type, e.Op.Path, e.Err)
+}
+
+func (e OpError) Unwrap() error {
+	return e.Err
+}
+
+// Parse parses the YAML of an ops-file, checking that every op has a known
+// type and a valid path.
+func Parse(data []byte) (Ops, error) {
+	var rawOps []map[string]interface{}
+	if err := yaml.Unmarshal(data, &rawOps); err != nil {
+		return nil, fmt.Errorf("parsing ops-file: %s", err)
+	}
+
+	ops := Ops{}
+	for i, rawOp := range rawOps {
+		opType, _ := rawOp["type"].(string)
+		path, _ := rawOp["path"].(string)
+		op := Op{Type: OpType(opType), Path: path, Value: rawOp["value"]}
+
+		if err := op.validate(); err != nil {
+			return nil, OpError{Index: i, Op: op, Err: err}
+		}
+		if _, hasValue := rawOp["value"]; op.Type == Replace && !hasValue {
+			return nil, OpError{Index: i, Op: op, Err: fmt.Errorf("missing value")}
+		}
+
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// Apply applies the ops in order to a copy of a generic YAML document.
+func (ops Ops) Apply(document interface{}) (interface{}, error) {
+	document = deepCopy(document)
+
+	for i, op := range ops {
+		var err error
+		if document, err = op.apply(document); err != nil {
+			return nil, OpError{Index: i, Op: op, Err: err}
+		}
+	}
+	return document, nil
+}
+
+// ApplyToYAML applies the ops to a YAML document.
+func (ops Ops) ApplyToYAML(document []byte) ([]byte, error) {
+	var parsed interface{}
+	if err := yaml.Unmarshal(document, &parsed); err != nil {
+		return nil, fmt.Errorf("parsing document: %s", err)
+	}
+
+	result, err := ops.Apply(parsed)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(result)
+}
+
+// ApplyToManifest applies the ops to a BOSH manifest. Keys added by the ops
+// that the SDK does not model are kept in the manifest's Extra fields.
+func (ops Ops) ApplyToManifest(manifest bosh.BoshManifest) (bosh.BoshManifest, error) {
+	manifestYAML, err := yaml.Marshal(manifest)
+	if err != nil {
+		return bosh.BoshManifest{}, fmt.Errorf("marshalling manifest: %s", err)
+	}
+
+	resultYAML, err := ops.ApplyToYAML(manifestYAML)
+	if err != nil {
+		return bosh.BoshManifest{}, err
+	}
+
+	var result bosh.BoshManifest
+	if err := yaml.Unmarshal(resultYAML, &result); err != nil {
+		return bosh.BoshManifest{}, fmt.Errorf("unmarshalling manifest: %s", err)
+	}
+	return result, nil
+}
+
+func (op Op) validate() error {
+	if op.Type != Replace && op.Type != Remove {
+		return fmt.Errorf("unknown op type '%s'", op.Type)
+	}
+	_, err := ParsePointer(op.Path)
+	return err
+}
+
+func (op Op) apply(document interface{}) (interface{}, error) {
+	if err := op.validate(); err != nil {
+		return nil, err
+	}
+	pointer, _ := ParsePointer(op.Path)
+
+	if op.Type == Remove {
+		if len(pointer.Tokens) == 0 {
+			return nil, fmt.Errorf("cannot remove the whole document")
+		}
+		return remove(document, pointer.Tokens, 0)
+	}
+	return replace(document, pointer.Tokens, 0, deepCopy(op.Value))
+}
@@ -0,0 +1,272 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opsfile_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+	"github.com/pivotal-cf/on-demand-services-sdk/opsfile"
+)
+
+var _ = Describe("Ops-files", func() {
+	const document = `---
+name: redis
+instance_groups:
+- name: redis
+  instances: 1
+  jobs:
+  - name: redis
+    release: redis
+    properties:
+      port: 6379
+- name: errands
+  instances: 1
+`
+
+	apply := func(opsYAML string) (string, error) {
+		ops, err := opsfile.Parse([]byte(opsYAML))
+		Expect(err).NotTo(HaveOccurred())
+
+		result, err := ops.ApplyToYAML([]byte(document))
+		return string(result), err
+	}
+
+	Describe("Parse", func() {
+		It("parses replace and remove ops", func() {
+			ops, err := opsfile.Parse([]byte(`
+- type: replace
+  path: /a
+  value: {b: c}
+- type: remove
+  path: /d
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ops).To(Equal(opsfile.Ops{
+				{Type: opsfile.Replace, Path: "/a", Value: map[interface{}]interface{}{"b": "c"}},
+				{Type: opsfile.Remove, Path: "/d"},
+			}))
+		})
+
+		It("allows replacing with null", func() {
+			_, err := opsfile.Parse([]byte("- {type: replace, path: /a, value: null}"))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		DescribeTable("rejects invalid ops",
+			func(opsYAML, message string) {
+				_, err := opsfile.Parse([]byte(opsYAML))
+				Expect(err).To(MatchError(ContainSubstring(message)))
+			},
+			Entry("invalid YAML", "{", "parsing ops-file"),
+			Entry("unknown type", "- {type: move, path: /a}", "applying op 0 (move /a): unknown op type 'move'"),
+			Entry("invalid path", "- {type: remove, path: /a}\n- {type: remove, path: a}", "applying op 1 (remove a): expected path 'a' to start with '/'"),
+			Entry("replace without a value", "- {type: replace, path: /a}", "missing value"),
+		)
+	})
+
+	Describe("replace", func() {
+		It("replaces a map key", func() {
+			Expect(apply("- {type: replace, path: /name, value: cache}")).To(MatchYAML(`
+name: cache
+instance_groups:
+- name: redis
+  instances: 1
+  jobs: [{name: redis, release: redis, properties: {port: 6379}}]
+- name: errands
+  instances: 1
+`))
+		})
+
+		It("replaces through a matching index", func() {
+			result, err := apply("- {type: replace, path: /instance_groups/name=redis/instances, value: 3}")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(ContainSubstring("instances: 3"))
+		})
+
+		It("replaces an array index, counting negative indexes from the end", func() {
+			result, err := apply("- {type: replace, path: /instance_groups/-1/name, value: smoke-tests}")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(ContainSubstring("name: smoke-tests"))
+			Expect(result).NotTo(ContainSubstring("name: errands"))
+		})
+
+		It("appends to an array with '-'", func() {
+			result, err := apply(`
+- type: replace
+  path: /instance_groups/name=redis/jobs/-
+  value: {name: bpm, release: bpm}
+`)
+			Expect(err).NotTo(HaveOccurred())
+
+			var parsed struct {
+				InstanceGroups []struct {
+					Jobs []map[string]interface{} `yaml:"jobs"`
+				} `yaml:"instance_groups"`
+			}
+			Expect(yaml.Unmarshal([]byte(result), &parsed)).To(Succeed())
+			Expect(parsed.InstanceGroups[0].Jobs).To(HaveLen(2))
+			Expect(parsed.InstanceGroups[0].Jobs[1]).To(HaveKeyWithValue("name", "bpm"))
+		})
+
+		It("creates missing keys along an optional path", func() {
+			result, err := apply(`
+- type: replace
+  path: /instance_groups/name=redis/env?/bosh/keep_root_password
+  value: true
+`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(ContainSubstring("env:\n    bosh:\n      keep_root_password: true"))
+		})
+
+		It("creates a missing array item for an optional matching index", func() {
+			result, err := apply(`
+- type: replace
+  path: /instance_groups/name=kafka?/instances
+  value: 2
+`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(ContainSubstring("- instances: 2\n  name: kafka"))
+		})
+
+		It("appends the value for an optional matching index at the end of the path", func() {
+			result, err := apply(`
+- type: replace
+  path: /instance_groups/name=kafka?
+  value: {name: kafka, instances: 5}
+`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(ContainSubstring("- instances: 5\n  name: kafka"))
+		})
+
+		It("replaces the whole document at the root", func() {
+			Expect(apply("- {type: replace, path: /, value: {name: other}}")).To(MatchYAML("name: other"))
+		})
+
+		It("does not modify the original document", func() {
+			var original interface{}
+			Expect(yaml.Unmarshal([]byte(document), &original)).To(Succeed())
+
+			ops := opsfile.Ops{{Type: opsfile.Replace, Path: "/instance_groups/0/instances", Value: 7}}
+			_, err := ops.Apply(original)
+			Expect(err).NotTo(HaveOccurred())
+
+			remarshalled, err := yaml.Marshal(original)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(remarshalled).To(MatchYAML(document))
+		})
+	})
+
+	Describe("remove", func() {
+		It("removes a map key", func() {
+			result, err := apply("- {type: remove, path: /instance_groups/name=redis/jobs/name=redis/properties}")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).NotTo(ContainSubstring("port"))
+		})
+
+		It("removes an array item", func() {
+			result, err := apply("- {type: remove, path: /instance_groups/name=errands}")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).NotTo(ContainSubstring("errands"))
+			Expect(result).To(ContainSubstring("name: redis"))
+		})
+
+		It("removes an array index", func() {
+			result, err := apply("- {type: remove, path: /instance_groups/0}")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).NotTo(ContainSubstring("jobs"))
+		})
+
+		It("ignores missing optional paths", func() {
+			result, err := apply(`
+- type: remove
+  path: /instance_groups/name=kafka?/jobs
+- type: remove
+  path: /update?/serial
+`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(MatchYAML(document))
+		})
+	})
+
+	DescribeTable("reports the failing op",
+		func(opsYAML, message string) {
+			_, err := apply(opsYAML)
+
+			var opErr opsfile.OpError
+			Expect(errors.As(err, &opErr)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("missing map key",
+			"- {type: replace, path: /name, value: a}\n- {type: replace, path: /instance_groups/name=redis/vm_type, value: small}",
+			"applying op 1 (replace /instance_groups/name=redis/vm_type): expected to find a map key 'vm_type' for path '/instance_groups/name=redis/vm_type' (found map keys: 'instances', 'jobs', 'name')"),
+		Entry("missing array item",
+			"- {type: remove, path: /instance_groups/name=kafka}",
+			"expected to find exactly one matching array item for path '/instance_groups/name=kafka' but found 0"),
+		Entry("array index out of range",
+			"- {type: replace, path: /instance_groups/2, value: {}}",
+			"expected to find an array index '2' for path '/instance_groups/2' (found array length '2')"),
+		Entry("map where an array is expected",
+			"- {type: replace, path: /name/0, value: a}",
+			"expected to find an array at path '/name/0' but found 'string'"),
+		Entry("array where a map is expected",
+			"- {type: replace, path: /instance_groups/name, value: a}",
+			"expected to find a map at path '/instance_groups/name' but found '[]interface {}'"),
+		Entry("removing '-'",
+			"- {type: remove, path: /instance_groups/-}",
+			"cannot remove the position after the last array item"),
+		Entry("removing the root",
+			"- {type: remove, path: /}",
+			"cannot remove the whole document"),
+	)
+
+	Describe("ApplyToManifest", func() {
+		It("applies the ops to a BOSH manifest, keeping unmodelled keys", func() {
+			manifest := bosh.BoshManifest{
+				Name: "redis",
+				InstanceGroups: []bosh.InstanceGroup{{
+					Name:      "redis",
+					Instances: 1,
+					Jobs:      []bosh.Job{{Name: "redis", Release: "redis"}},
+				}},
+			}
+
+			ops, err := opsfile.Parse([]byte(`
+- type: replace
+  path: /instance_groups/name=redis/jobs/-
+  value: {name: bpm, release: bpm}
+- type: replace
+  path: /instance_groups/name=redis/persistent_disk?
+  value: 10240
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			result, err := ops.ApplyToManifest(manifest)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.InstanceGroups[0].Jobs).To(Equal([]bosh.Job{
+				{Name: "redis", Release: "redis"},
+				{Name: "bpm", Release: "bpm"},
+			}))
+			Expect(result.InstanceGroups[0].PersistentDisk).To(Equal(10240))
+			Expect(manifest.InstanceGroups[0].Jobs).To(HaveLen(1))
+		})
+	})
+})
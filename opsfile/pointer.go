@@ -0,0 +1,156 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opsfile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Token is one segment of a Pointer. It is one of KeyToken, IndexToken,
+// AfterLastIndexToken or MatchingIndexToken.
+type Token interface {
+	String() string
+}
+
+// KeyToken selects a map key, e.g. /name or /name?
+type KeyToken struct {
+	Key      string
+	Optional bool
+}
+
+// IndexToken selects an array element by position, e.g. /0 or /-1
+type IndexToken struct {
+	Index int
+}
+
+// AfterLastIndexToken selects the position after the last array element: /-
+type AfterLastIndexToken struct{}
+
+// MatchingIndexToken selects the array element whose Key equals Value, e.g.
+// /name=redis or /name=redis?
+type MatchingIndexToken struct {
+	Key      string
+	Value    string
+	Optional bool
+}
+
+// Pointer is a parsed ops-file path such as /instance_groups/name=redis/jobs/-
+type Pointer struct {
+	Tokens []Token
+}
+
+// ParsePointer parses an ops-file path. Once a token is marked optional with
+// "?", all tokens after it are optional too.
+func ParsePointer(path string) (Pointer, error) {
+	if path == "" || path == "/" {
+		return Pointer{}, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return Pointer{}, fmt.Errorf("expected path '%s' to start with '/'", path)
+	}
+
+	var tokens []Token
+	optional := false
+
+	segments := strings.Split(path[1:], "/")
+	for i, segment := range segments {
+		segment = unescape(segment)
+		isLast := i == len(segments)-1
+
+		if strings.HasSuffix(segment, "?") {
+			optional = true
+			segment = strings.TrimSuffix(segment, "?")
+		}
+
+		switch {
+		case segment == "-":
+			if !isLast {
+				return Pointer{}, fmt.Errorf("expected '-' to be the last token in path '%s'", path)
+			}
+			tokens = append(tokens, AfterLastIndexToken{})
+
+		case strings.Contains(segment, "="):
+			parts := strings.SplitN(segment, "=", 2)
+			if parts[0] == "" {
+				return Pointer{}, fmt.Errorf("expected a key before '=' in path '%s'", path)
+			}
+			tokens = append(tokens, MatchingIndexToken{Key: parts[0], Value: parts[1], Optional: optional})
+
+		default:
+			if index, err := strconv.Atoi(segment); err == nil {
+				tokens = append(tokens, IndexToken{Index: index})
+				continue
+			}
+			tokens = append(tokens, KeyToken{Key: segment, Optional: optional})
+		}
+	}
+
+	return Pointer{Tokens: tokens}, nil
+}
+
+func (p Pointer) String() string {
+	if len(p.Tokens) == 0 {
+		return "/"
+	}
+	var segments []string
+	optional := false
+	for _, token := range p.Tokens {
+		// Only the first optional token is marked; the rest are implied.
+		switch t := token.(type) {
+		case KeyToken:
+			t.Optional, optional = t.Optional && !optional, optional || t.Optional
+			token = t
+		case MatchingIndexToken:
+			t.Optional, optional = t.Optional && !optional, optional || t.Optional
+			token = t
+		}
+		segments = append(segments, token.String())
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+func (t KeyToken) String() string {
+	if t.Optional {
+		return escape(t.Key) + "?"
+	}
+	return escape(t.Key)
+}
+
+func (t IndexToken) String() string {
+	return strconv.Itoa(t.Index)
+}
+
+func (t AfterLastIndexToken) String() string {
+	return "-"
+}
+
+func (t MatchingIndexToken) String() string {
+	token := escape(t.Key) + "=" + escape(t.Value)
+	if t.Optional {
+		token += "?"
+	}
+	return token
+}
+
+func escape(segment string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(segment)
+}
+
+func unescape(segment string) string {
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(segment)
+}
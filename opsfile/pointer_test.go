@@ -0,0 +1,80 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opsfile_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/opsfile"
+)
+
+var _ = Describe("ParsePointer", func() {
+	It("parses every kind of token", func() {
+		pointer, err := opsfile.ParsePointer("/instance_groups/name=redis/jobs/0/properties/a~1b~0c/-")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(pointer.Tokens).To(Equal([]opsfile.Token{
+			opsfile.KeyToken{Key: "instance_groups"},
+			opsfile.MatchingIndexToken{Key: "name", Value: "redis"},
+			opsfile.KeyToken{Key: "jobs"},
+			opsfile.IndexToken{Index: 0},
+			opsfile.KeyToken{Key: "properties"},
+			opsfile.KeyToken{Key: "a/b~c"},
+			opsfile.AfterLastIndexToken{},
+		}))
+	})
+
+	It("makes every token after an optional one optional", func() {
+		pointer, err := opsfile.ParsePointer("/a/name=b?/c/d")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(pointer.Tokens).To(Equal([]opsfile.Token{
+			opsfile.KeyToken{Key: "a"},
+			opsfile.MatchingIndexToken{Key: "name", Value: "b", Optional: true},
+			opsfile.KeyToken{Key: "c", Optional: true},
+			opsfile.KeyToken{Key: "d", Optional: true},
+		}))
+	})
+
+	It("parses the root path", func() {
+		pointer, err := opsfile.ParsePointer("/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pointer.Tokens).To(BeEmpty())
+	})
+
+	DescribeTable("round-trips through String",
+		func(path string) {
+			pointer, err := opsfile.ParsePointer(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pointer.String()).To(Equal(path))
+		},
+		Entry("root", "/"),
+		Entry("keys and indexes", "/a/0/-1"),
+		Entry("matching indexes", "/instance_groups/name=redis?/jobs/-"),
+		Entry("escaped keys", "/a~1b/c~0d"),
+	)
+
+	DescribeTable("rejects invalid paths",
+		func(path, message string) {
+			_, err := opsfile.ParsePointer(path)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("without a leading slash", "a/b", "expected path 'a/b' to start with '/'"),
+		Entry("with '-' before the end", "/a/-/b", "expected '-' to be the last token"),
+		Entry("with an empty matching key", "/a/=b", "expected a key before '='"),
+	)
+})
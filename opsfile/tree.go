@@ -0,0 +1,302 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opsfile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func replace(node interface{}, tokens []Token, i int, value interface{}) (interface{}, error) {
+	if i == len(tokens) {
+		return value, nil
+	}
+	isLast := i == len(tokens)-1
+	path := pathTo(tokens, i)
+
+	switch token := tokens[i].(type) {
+	case KeyToken:
+		if node == nil && token.Optional {
+			node = map[interface{}]interface{}{}
+		}
+		child, found, err := mapGet(node, token.Key, path)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			if !token.Optional {
+				return nil, missingKeyError(node, token.Key, path)
+			}
+			child = emptyContainerFor(tokens, i+1)
+		}
+		newChild, err := replace(child, tokens, i+1, value)
+		if err != nil {
+			return nil, err
+		}
+		mapSet(node, token.Key, newChild)
+		return node, nil
+
+	case IndexToken:
+		array, err := asArray(node, path)
+		if err != nil {
+			return nil, err
+		}
+		index, err := resolveIndex(array, token.Index, path)
+		if err != nil {
+			return nil, err
+		}
+		if array[index], err = replace(array[index], tokens, i+1, value); err != nil {
+			return nil, err
+		}
+		return array, nil
+
+	case AfterLastIndexToken:
+		if node == nil {
+			node = []interface{}{}
+		}
+		array, err := asArray(node, path)
+		if err != nil {
+			return nil, err
+		}
+		return append(array, value), nil
+
+	case MatchingIndexToken:
+		if node == nil && token.Optional {
+			node = []interface{}{}
+		}
+		array, err := asArray(node, path)
+		if err != nil {
+			return nil, err
+		}
+		matches := findMatches(array, token)
+		switch {
+		case len(matches) > 1:
+			return nil, fmt.Errorf("expected to find exactly one matching array item for path '%s' but found %d", path, len(matches))
+		case len(matches) == 0 && !token.Optional:
+			return nil, fmt.Errorf("expected to find exactly one matching array item for path '%s' but found 0", path)
+		case len(matches) == 0 && isLast:
+			return append(array, value), nil
+		case len(matches) == 0:
+			newItem := map[interface{}]interface{}{token.Key: token.Value}
+			newChild, err := replace(newItem, tokens, i+1, value)
+			if err != nil {
+				return nil, err
+			}
+			return append(array, newChild), nil
+		}
+		if array[matches[0]], err = replace(array[matches[0]], tokens, i+1, value); err != nil {
+			return nil, err
+		}
+		return array, nil
+	}
+
+	return nil, fmt.Errorf("unsupported token '%s' in path '%s'", tokens[i], path)
+}
+
+func remove(node interface{}, tokens []Token, i int) (interface{}, error) {
+	isLast := i == len(tokens)-1
+	path := pathTo(tokens, i)
+
+	switch token := tokens[i].(type) {
+	case KeyToken:
+		if node == nil && token.Optional {
+			return node, nil
+		}
+		child, found, err := mapGet(node, token.Key, path)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			if token.Optional {
+				return node, nil
+			}
+			return nil, missingKeyError(node, token.Key, path)
+		}
+		if isLast {
+			mapDelete(node, token.Key)
+			return node, nil
+		}
+		newChild, err := remove(child, tokens, i+1)
+		if err != nil {
+			return nil, err
+		}
+		mapSet(node, token.Key, newChild)
+		return node, nil
+
+	case IndexToken:
+		array, err := asArray(node, path)
+		if err != nil {
+			return nil, err
+		}
+		index, err := resolveIndex(array, token.Index, path)
+		if err != nil {
+			return nil, err
+		}
+		if isLast {
+			return append(array[:index:index], array[index+1:]...), nil
+		}
+		if array[index], err = remove(array[index], tokens, i+1); err != nil {
+			return nil, err
+		}
+		return array, nil
+
+	case AfterLastIndexToken:
+		return nil, fmt.Errorf("cannot remove the position after the last array item for path '%s'", path)
+
+	case MatchingIndexToken:
+		if node == nil && token.Optional {
+			return node, nil
+		}
+		array, err := asArray(node, path)
+		if err != nil {
+			return nil, err
+		}
+		matches := findMatches(array, token)
+		switch {
+		case len(matches) > 1:
+			return nil, fmt.Errorf("expected to find exactly one matching array item for path '%s' but found %d", path, len(matches))
+		case len(matches) == 0 && token.Optional:
+			return array, nil
+		case len(matches) == 0:
+			return nil, fmt.Errorf("expected to find exactly one matching array item for path '%s' but found 0", path)
+		}
+		index := matches[0]
+		if isLast {
+			return append(array[:index:index], array[index+1:]...), nil
+		}
+		if array[index], err = remove(array[index], tokens, i+1); err != nil {
+			return nil, err
+		}
+		return array, nil
+	}
+
+	return nil, fmt.Errorf("unsupported token '%s' in path '%s'", tokens[i], path)
+}
+
+func pathTo(tokens []Token, i int) string {
+	return Pointer{Tokens: tokens[:i+1]}.String()
+}
+
+func emptyContainerFor(tokens []Token, i int) interface{} {
+	if i == len(tokens) {
+		return nil
+	}
+	if _, isKey := tokens[i].(KeyToken); isKey {
+		return map[interface{}]interface{}{}
+	}
+	return []interface{}{}
+}
+
+func mapGet(node interface{}, key, path string) (interface{}, bool, error) {
+	switch m := node.(type) {
+	case map[interface{}]interface{}:
+		value, found := m[key]
+		return value, found, nil
+	case map[string]interface{}:
+		value, found := m[key]
+		return value, found, nil
+	}
+	return nil, false, fmt.Errorf("expected to find a map at path '%s' but found '%T'", path, node)
+}
+
+func mapSet(node interface{}, key string, value interface{}) {
+	switch m := node.(type) {
+	case map[interface{}]interface{}:
+		m[key] = value
+	case map[string]interface{}:
+		m[key] = value
+	}
+}
+
+func mapDelete(node interface{}, key string) {
+	switch m := node.(type) {
+	case map[interface{}]interface{}:
+		delete(m, key)
+	case map[string]interface{}:
+		delete(m, key)
+	}
+}
+
+func missingKeyError(node interface{}, key, path string) error {
+	var keys []string
+	switch m := node.(type) {
+	case map[interface{}]interface{}:
+		for k := range m {
+			keys = append(keys, fmt.Sprintf("'%v'", k))
+		}
+	case map[string]interface{}:
+		for k := range m {
+			keys = append(keys, fmt.Sprintf("'%s'", k))
+		}
+	}
+	sort.Strings(keys)
+	return fmt.Errorf("expected to find a map key '%s' for path '%s' (found map keys: %s)", key, path, strings.Join(keys, ", "))
+}
+
+func asArray(node interface{}, path string) ([]interface{}, error) {
+	array, ok := node.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected to find an array at path '%s' but found '%T'", path, node)
+	}
+	return array, nil
+}
+
+func resolveIndex(array []interface{}, index int, path string) (int, error) {
+	resolved := index
+	if resolved < 0 {
+		resolved += len(array)
+	}
+	if resolved < 0 || resolved >= len(array) {
+		return 0, fmt.Errorf("expected to find an array index '%d' for path '%s' (found array length '%d')", index, path, len(array))
+	}
+	return resolved, nil
+}
+
+func findMatches(array []interface{}, token MatchingIndexToken) []int {
+	var matches []int
+	for i, item := range array {
+		value, found, err := mapGet(item, token.Key, "")
+		if err == nil && found && fmt.Sprint(value) == token.Value {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+func deepCopy(node interface{}) interface{} {
+	switch n := node.(type) {
+	case map[interface{}]interface{}:
+		copied := make(map[interface{}]interface{}, len(n))
+		for k, v := range n {
+			copied[k] = deepCopy(v)
+		}
+		return copied
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(n))
+		for k, v := range n {
+			copied[k] = deepCopy(v)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(n))
+		for i, v := range n {
+			copied[i] = deepCopy(v)
+		}
+		return copied
+	}
+	return node
+}
@@ -0,0 +1,103 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewClient returns a client for a server listening on the given network
+// ("tcp" or "unix") and address.
+func NewClient(network, address string) *Client {
+	dialer := &net.Dialer{}
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, address)
+		},
+	}
+
+	baseURL := "http://service-adapter"
+	if network != "unix" {
+		baseURL = "http://" + address
+	}
+
+	return &Client{
+		httpClient: &http.Client{Transport: transport},
+		baseURL:    baseURL,
+	}
+}
+
+// Invoke runs action on the server with the given InputParams JSON.
+func (c *Client) Invoke(ctx context.Context, action string, inputParams io.Reader) (Response, error) {
+	var response Response
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+actionsPath+url.PathEscape(action), inputParams)
+	if err != nil {
+		return response, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	httpResponse, err := c.httpClient.Do(request)
+	if err != nil {
+		return response, fmt.Errorf("calling service adapter server: %s", err)
+	}
+	defer httpResponse.Body.Close()
+
+	if err := json.NewDecoder(httpResponse.Body).Decode(&response); err != nil {
+		return response, fmt.Errorf("decoding service adapter server response with status %d: %s", httpResponse.StatusCode, err)
+	}
+	return response, nil
+}
+
+// Forward handles a command line invocation by forwarding it to the server,
+// writing the action's output to stdout and stderr. It returns the exit code
+// the process should exit with.
+//
+// Only input params passed via stdin are supported; legacy positional
+// arguments are rejected.
+func Forward(ctx context.Context, client *Client, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) < 2 {
+		fmt.Fprintf(stderr, "[odb-sdk] missing subcommand\n")
+		return serviceadapter.ErrorExitCode
+	}
+	if len(args) > 2 {
+		fmt.Fprintf(stderr, "[odb-sdk] %s: only input params passed via stdin are supported in server mode\n", args[1])
+		return serviceadapter.ErrorExitCode
+	}
+
+	response, err := client.Invoke(ctx, args[1], stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "[odb-sdk] %s\n", err)
+		return serviceadapter.ErrorExitCode
+	}
+
+	io.WriteString(stdout, response.Stdout)
+	io.WriteString(stderr, response.Stderr)
+	return response.ExitCode
+}
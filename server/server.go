@@ -0,0 +1,124 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package server runs a service adapter as a long-lived HTTP server, so that
+// expensive initialisation happens once rather than on every ODB invocation.
+//
+// Each action is exposed as POST /actions/<action>, taking the same
+// InputParams JSON that ODB writes to the adapter's stdin. The response is a
+// JSON Response carrying what the CLI would have written to stdout and stderr
+// along with its exit code, which is also reflected in the HTTP status.
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+const (
+	NetworkEnvVar = "ODB_SERVICE_ADAPTER_SERVER_NETWORK"
+	AddressEnvVar = "ODB_SERVICE_ADAPTER_SERVER_ADDRESS"
+
+	DefaultNetwork = "unix"
+	DefaultAddress = "/var/vcap/sys/run/service-adapter/adapter.sock"
+
+	actionsPath = "/actions/"
+)
+
+type Response struct {
+	ExitCode int    `json:"exit_code"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
+// NewHandler returns an http.Handler serving the actions of adapter.
+func NewHandler(adapter serviceadapter.CommandLineHandler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+actionsPath+"{action}", func(w http.ResponseWriter, r *http.Request) {
+		var stdout, stderr bytes.Buffer
+
+		args := []string{"service-adapter", r.PathValue("action")}
+		err := adapter.HandleWithContext(r.Context(), args, &stdout, &stderr, r.Body)
+
+		exitCode := 0
+		if err != nil {
+			exitCode = serviceadapter.ErrorExitCode
+			var cliErr serviceadapter.CLIHandlerError
+			if errors.As(err, &cliErr) {
+				exitCode = cliErr.ExitCode
+			}
+			fmt.Fprintf(&stderr, "[odb-sdk] %s\n", err.Error())
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(StatusForExitCode(exitCode))
+		json.NewEncoder(w).Encode(Response{
+			ExitCode: exitCode,
+			Stdout:   stdout.String(),
+			Stderr:   stderr.String(),
+		})
+	})
+	return mux
+}
+
+// StatusForExitCode maps a service adapter exit code to an HTTP status.
+func StatusForExitCode(exitCode int) int {
+	switch exitCode {
+	case 0:
+		return http.StatusOK
+	case serviceadapter.NotImplementedExitCode:
+		return http.StatusNotImplemented
+	case serviceadapter.BindingNotFoundErrorExitCode:
+		return http.StatusNotFound
+	case serviceadapter.AppGuidNotProvidedErrorExitCode:
+		return http.StatusUnprocessableEntity
+	case serviceadapter.BindingAlreadyExistsErrorExitCode:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Serve serves adapter on listener until ctx is done.
+func Serve(ctx context.Context, listener net.Listener, adapter serviceadapter.CommandLineHandler) error {
+	server := &http.Server{Handler: NewHandler(adapter)}
+
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// ListenAndServe listens on the given network ("tcp" or "unix") and address
+// and serves adapter until ctx is done.
+func ListenAndServe(ctx context.Context, network, address string, adapter serviceadapter.CommandLineHandler) error {
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return fmt.Errorf("listening on %s %s: %s", network, address, err)
+	}
+	return Serve(ctx, listener, adapter)
+}
@@ -0,0 +1,222 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+	"github.com/pivotal-cf/on-demand-services-sdk/server"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter/fakes"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		fakeBinder                *fakes.FakeBinder
+		fakeDashboardUrlGenerator *fakes.FakeDashboardUrlGenerator
+		adapter                   serviceadapter.CommandLineHandler
+		testServer                *httptest.Server
+		bindingInput              string
+	)
+
+	BeforeEach(func() {
+		fakeBinder = new(fakes.FakeBinder)
+		fakeDashboardUrlGenerator = new(fakes.FakeDashboardUrlGenerator)
+		adapter = serviceadapter.CommandLineHandler{
+			Binder:                fakeBinder,
+			DashboardURLGenerator: fakeDashboardUrlGenerator,
+		}
+		testServer = httptest.NewServer(server.NewHandler(adapter))
+
+		bindingInput = toJson(serviceadapter.InputParams{
+			CreateBinding: serviceadapter.CreateBindingJSONParams{
+				BindingId:         "binding-id",
+				BoshVms:           toJson(bosh.BoshVMs{"redis": {"10.0.0.1"}}),
+				Manifest:          "name: a-deployment",
+				RequestParameters: toJson(map[string]interface{}{"parameters": map[string]interface{}{}}),
+			},
+		})
+	})
+
+	AfterEach(func() {
+		testServer.Close()
+	})
+
+	post := func(action, body string) (*http.Response, server.Response) {
+		httpResponse, err := http.Post(testServer.URL+"/actions/"+action, "application/json", bytes.NewBufferString(body))
+		Expect(err).NotTo(HaveOccurred())
+		defer httpResponse.Body.Close()
+
+		var response server.Response
+		Expect(json.NewDecoder(httpResponse.Body).Decode(&response)).To(Succeed())
+		return httpResponse, response
+	}
+
+	It("runs the action with the request body as input params", func() {
+		fakeBinder.CreateBindingReturns(serviceadapter.Binding{Credentials: map[string]interface{}{"user": "alice"}}, nil)
+
+		httpResponse, response := post("create-binding", bindingInput)
+
+		Expect(httpResponse.StatusCode).To(Equal(http.StatusOK))
+		Expect(response.ExitCode).To(Equal(0))
+		Expect(response.Stdout).To(MatchJSON(`{"credentials":{"user":"alice"}}`))
+		Expect(response.Stderr).To(ContainSubstring("[odb-sdk] handling create-binding"))
+
+		Expect(fakeBinder.CreateBindingCallCount()).To(Equal(1))
+		Expect(fakeBinder.CreateBindingArgsForCall(0).BindingID).To(Equal("binding-id"))
+	})
+
+	It("maps adapter exit codes to HTTP statuses", func() {
+		fakeBinder.CreateBindingReturns(serviceadapter.Binding{}, serviceadapter.NewBindingAlreadyExistsError(errors.New("oops")))
+
+		httpResponse, response := post("create-binding", bindingInput)
+
+		Expect(httpResponse.StatusCode).To(Equal(http.StatusConflict))
+		Expect(response.ExitCode).To(Equal(serviceadapter.BindingAlreadyExistsErrorExitCode))
+		Expect(response.Stdout).To(Equal("binding already exists: oops"))
+		Expect(response.Stderr).To(ContainSubstring("[odb-sdk] binding already exists: oops"))
+	})
+
+	It("reports actions that are not implemented", func() {
+		httpResponse, response := post("generate-manifest", "{}")
+
+		Expect(httpResponse.StatusCode).To(Equal(http.StatusNotImplemented))
+		Expect(response.ExitCode).To(Equal(serviceadapter.NotImplementedExitCode))
+		Expect(response.Stderr).To(ContainSubstring("generate-manifest not implemented"))
+	})
+
+	It("reports unknown actions without stopping the server", func() {
+		httpResponse, response := post("unknown-action", "{}")
+		Expect(httpResponse.StatusCode).To(Equal(http.StatusInternalServerError))
+		Expect(response.ExitCode).To(Equal(serviceadapter.ErrorExitCode))
+		Expect(response.Stderr).To(ContainSubstring("unknown subcommand: unknown-action"))
+
+		httpResponse, _ = post("create-binding", bindingInput)
+		Expect(httpResponse.StatusCode).To(Equal(http.StatusOK))
+	})
+
+	It("only accepts POST requests", func() {
+		httpResponse, err := http.Get(testServer.URL + "/actions/create-binding")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(httpResponse.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	Describe("StatusForExitCode", func() {
+		DescribeTable("maps exit codes",
+			func(exitCode, status int) {
+				Expect(server.StatusForExitCode(exitCode)).To(Equal(status))
+			},
+			Entry("success", 0, http.StatusOK),
+			Entry("generic error", serviceadapter.ErrorExitCode, http.StatusInternalServerError),
+			Entry("not implemented", serviceadapter.NotImplementedExitCode, http.StatusNotImplemented),
+			Entry("binding not found", serviceadapter.BindingNotFoundErrorExitCode, http.StatusNotFound),
+			Entry("app guid not provided", serviceadapter.AppGuidNotProvidedErrorExitCode, http.StatusUnprocessableEntity),
+			Entry("binding already exists", serviceadapter.BindingAlreadyExistsErrorExitCode, http.StatusConflict),
+		)
+	})
+
+	Describe("Forward", func() {
+		var stdout, stderr *bytes.Buffer
+
+		BeforeEach(func() {
+			stdout = new(bytes.Buffer)
+			stderr = new(bytes.Buffer)
+		})
+
+		It("forwards an invocation over TCP", func() {
+			fakeDashboardUrlGenerator.DashboardUrlReturns(serviceadapter.DashboardUrl{DashboardUrl: "http://dashboard"}, nil)
+			client := server.NewClient("tcp", testServer.Listener.Addr().String())
+
+			input := toJson(serviceadapter.InputParams{
+				DashboardUrl: serviceadapter.DashboardUrlJSONParams{
+					InstanceId: "instance-id",
+					Plan:       toJson(serviceadapter.Plan{InstanceGroups: []serviceadapter.InstanceGroup{{Name: "redis", VMType: "small", Instances: 1, Networks: []string{"net"}, AZs: []string{"z1"}}}}),
+					Manifest:   "name: a-deployment",
+				},
+			})
+			exitCode := server.Forward(context.Background(), client, []string{"adapter", "dashboard-url"}, bytes.NewBufferString(input), stdout, stderr)
+
+			Expect(exitCode).To(Equal(0))
+			Expect(stdout.String()).To(MatchJSON(`{"dashboard_url":"http://dashboard"}`))
+			Expect(fakeDashboardUrlGenerator.DashboardUrlArgsForCall(0).InstanceID).To(Equal("instance-id"))
+		})
+
+		It("forwards an invocation over a unix socket", func() {
+			fakeBinder.DeleteBindingReturns(serviceadapter.NewBindingNotFoundError(errors.New("gone")))
+			socketPath := filepath.Join(GinkgoT().TempDir(), "adapter.sock")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			serveErr := make(chan error, 1)
+			go func() {
+				serveErr <- server.ListenAndServe(ctx, "unix", socketPath, adapter)
+			}()
+			Eventually(socketPath).Should(BeAnExistingFile())
+
+			deleteInput := toJson(serviceadapter.InputParams{
+				DeleteBinding: serviceadapter.DeleteBindingJSONParams{
+					BindingId:         "binding-id",
+					BoshVms:           toJson(bosh.BoshVMs{"redis": {"10.0.0.1"}}),
+					Manifest:          "name: a-deployment",
+					RequestParameters: "{}",
+				},
+			})
+			client := server.NewClient("unix", socketPath)
+			exitCode := server.Forward(context.Background(), client, []string{"adapter", "delete-binding"}, bytes.NewBufferString(deleteInput), stdout, stderr)
+
+			Expect(exitCode).To(Equal(serviceadapter.BindingNotFoundErrorExitCode))
+			Expect(stdout.String()).To(Equal("binding not found: gone"))
+			Expect(stderr.String()).To(ContainSubstring("[odb-sdk] binding not found: gone"))
+
+			cancel()
+			Eventually(serveErr).Should(Receive(BeNil()))
+		})
+
+		It("rejects legacy positional arguments", func() {
+			client := server.NewClient("tcp", testServer.Listener.Addr().String())
+
+			exitCode := server.Forward(context.Background(), client, []string{"adapter", "create-binding", "id", "{}", "", "{}"}, new(bytes.Buffer), stdout, stderr)
+
+			Expect(exitCode).To(Equal(serviceadapter.ErrorExitCode))
+			Expect(stderr.String()).To(ContainSubstring("only input params passed via stdin are supported"))
+			Expect(fakeBinder.CreateBindingCallCount()).To(Equal(0))
+		})
+
+		It("fails when the server cannot be reached", func() {
+			client := server.NewClient("unix", filepath.Join(GinkgoT().TempDir(), "missing.sock"))
+
+			exitCode := server.Forward(context.Background(), client, []string{"adapter", "create-binding"}, bytes.NewBufferString(bindingInput), stdout, stderr)
+
+			Expect(exitCode).To(Equal(serviceadapter.ErrorExitCode))
+			Expect(stderr.String()).To(ContainSubstring("calling service adapter server"))
+		})
+	})
+})
+
+func toJson(obj interface{}) string {
+	str, err := json.Marshal(obj)
+	Expect(err).NotTo(HaveOccurred())
+	return string(str)
+}
@@ -0,0 +1,175 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// AdapterError is an error an adapter can return to tell apart what the
+// user is shown from what only the operator should see. Its Error method
+// returns UserMessage, which is what ODB shows to the user.
+//
+// The handler reports every field when CommandLineHandler.StructuredErrors
+// is set; otherwise only UserMessage is written, as for any other error.
+type AdapterError struct {
+	// Code identifies the kind of failure, e.g. quota_exceeded
+	Code string
+	// UserMessage is shown to the user who made the request.
+	UserMessage string
+	// OperatorDetail is only logged, it may refer to internals.
+	OperatorDetail string
+	// Retryable tells whether the same request may succeed later.
+	Retryable bool
+	// ExitCode overrides the exit code of the action when not zero.
+	ExitCode int
+	Cause    error
+}
+
+func NewAdapterError(code, userMessage string, cause error) AdapterError {
+	return AdapterError{Code: code, UserMessage: userMessage, Cause: cause}
+}
+
+func (e AdapterError) Error() string {
+	if e.UserMessage == "" && e.Cause != nil {
+		return e.Cause.Error()
+	}
+	return e.UserMessage
+}
+
+func (e AdapterError) Unwrap() error {
+	return e.Cause
+}
+
+// ErrorResponse is the JSON written to stdout and stderr for a failed
+// invocation when structured errors are enabled. On stdout only Code,
+// Message and Retryable are set.
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Code           string   `json:"code"`
+	Message        string   `json:"message,omitempty"`
+	OperatorDetail string   `json:"operator_detail,omitempty"`
+	Retryable      bool     `json:"retryable"`
+	ExitCode       int      `json:"exit_code,omitempty"`
+	Causes         []string `json:"causes,omitempty"`
+}
+
+const (
+	InternalErrorCode          = "internal_error"
+	AdapterErrorCode           = "adapter_error"
+	InvalidParametersErrorCode = "invalid_parameters"
+)
+
+var exitCodeErrorCodes = map[int]string{
+	NotImplementedExitCode:            "not_implemented",
+	BindingNotFoundErrorExitCode:      "binding_not_found",
+	AppGuidNotProvidedErrorExitCode:   "app_guid_not_provided",
+	BindingAlreadyExistsErrorExitCode: "binding_already_exists",
+}
+
+func asAdapterError(err error) (AdapterError, bool) {
+	var adapterErr AdapterError
+	if errors.As(err, &adapterErr) {
+		return adapterErr, true
+	}
+	var adapterErrPtr *AdapterError
+	if errors.As(err, &adapterErrPtr) && adapterErrPtr != nil {
+		return *adapterErrPtr, true
+	}
+	return AdapterError{}, false
+}
+
+// adapterFailure writes the message of err, returned by the adapter, to
+// outputWriter for ODB to show to the user, and returns the CLIHandlerError
+// to exit with. An AdapterError's ExitCode takes precedence over exitCode.
+func adapterFailure(err error, outputWriter io.Writer, exitCode int) error {
+	if adapterErr, ok := asAdapterError(err); ok && adapterErr.ExitCode != 0 {
+		exitCode = adapterErr.ExitCode
+	}
+	message := err.Error()
+	if invocation, ok := outputWriter.(*invocationWriter); ok {
+		invocation.adapterErr = err
+		message = invocation.redactor.Redact(message)
+	}
+	fmt.Fprint(outputWriter, message)
+	return CLIHandlerError{exitCode, message}
+}
+
+// invocationWriter is the output writer the handler passes to actions. It
+// records the error of the adapter, if it fails, and redacts its message.
+type invocationWriter struct {
+	io.Writer
+	adapterErr error
+	redactor   *Redactor
+}
+
+// writeErrorResponses writes detail to stderr, and the part of it meant for
+// the user to stdout.
+func writeErrorResponses(detail ErrorDetail, outputWriter, errorWriter io.Writer) {
+	json.NewEncoder(outputWriter).Encode(ErrorResponse{Error: ErrorDetail{
+		Code:      detail.Code,
+		Message:   detail.Message,
+		Retryable: detail.Retryable,
+	}})
+	json.NewEncoder(errorWriter).Encode(ErrorResponse{Error: detail})
+}
+
+// errorDetail describes err, returned by an action, and adapterErr, the
+// error of the adapter it reported if any.
+func errorDetail(err, adapterErr error) ErrorDetail {
+	exitCode := ErrorExitCode
+	var cliErr CLIHandlerError
+	if errors.As(err, &cliErr) {
+		exitCode = cliErr.ExitCode
+	}
+
+	detail := ErrorDetail{Code: InternalErrorCode, ExitCode: exitCode}
+	if code, ok := exitCodeErrorCodes[exitCode]; ok {
+		detail.Code = code
+	}
+
+	cause := err
+	if adapterErr != nil {
+		cause = adapterErr
+		detail.Message = adapterErr.Error()
+		if detail.Code == InternalErrorCode {
+			detail.Code = AdapterErrorCode
+			var paramsErr InvalidParametersError
+			if errors.As(adapterErr, &paramsErr) {
+				detail.Code = InvalidParametersErrorCode
+			}
+		}
+		if typed, ok := asAdapterError(adapterErr); ok {
+			if typed.Code != "" {
+				detail.Code = typed.Code
+			}
+			detail.OperatorDetail = typed.OperatorDetail
+			detail.Retryable = typed.Retryable
+			cause = typed.Cause
+		}
+	}
+
+	for ; cause != nil; cause = errors.Unwrap(cause) {
+		detail.Causes = append(detail.Causes, cause.Error())
+	}
+	return detail
+}
@@ -0,0 +1,129 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter
+
+import (
+	"encoding/json"
+	"io"
+	"runtime/debug"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// PositionalArgsProtocol is the legacy protocol passing input params as
+	// command line arguments.
+	PositionalArgsProtocol = "positional-args"
+	// StdinJSONProtocol passes input params as InputParams JSON via stdin.
+	StdinJSONProtocol = "stdin-json"
+
+	sdkModulePath = "github.com/pivotal-cf/on-demand-services-sdk"
+)
+
+// Capabilities is what the capabilities subcommand writes as JSON, for
+// tooling to check an adapter is compatible before rolling it out.
+type Capabilities struct {
+	SDKVersion string                        `json:"sdk_version"`
+	Actions    map[string]ActionCapabilities `json:"actions"`
+	Features   map[string]bool               `json:"features"`
+	Metadata   map[string]interface{}        `json:"metadata,omitempty"`
+}
+
+type ActionCapabilities struct {
+	Implemented    bool     `json:"implemented"`
+	Help           string   `json:"help,omitempty"`
+	InputProtocols []string `json:"input_protocols,omitempty"`
+	// OptionalFields lists the optional fields of the action's stdin JSON
+	// input params it reads.
+	OptionalFields []string `json:"optional_fields,omitempty"`
+}
+
+// DescribedAction is an Action that describes the input it accepts in the
+// output of the capabilities subcommand. Custom actions may implement it.
+type DescribedAction interface {
+	Action
+	Capabilities() ActionCapabilities
+}
+
+type CapabilitiesAction struct {
+	capabilities func() Capabilities
+}
+
+func (a *CapabilitiesAction) IsImplemented() bool {
+	return true
+}
+
+// ParseArgs does not read stdin, so that the subcommand can be run without
+// input.
+func (a *CapabilitiesAction) ParseArgs(reader io.Reader, args []string) (InputParams, error) {
+	return InputParams{TextOutput: true}, nil
+}
+
+func (a *CapabilitiesAction) Execute(inputParams InputParams, outputWriter io.Writer) error {
+	if err := json.NewEncoder(outputWriter).Encode(a.capabilities()); err != nil {
+		return errors.Wrap(err, "marshalling capabilities")
+	}
+	return nil
+}
+
+func (h CommandLineHandler) newCapabilitiesAction(actions map[string]Action) *CapabilitiesAction {
+	return &CapabilitiesAction{capabilities: func() Capabilities {
+		capabilities := Capabilities{
+			SDKVersion: sdkVersion(),
+			Actions:    map[string]ActionCapabilities{},
+			Features: map[string]bool{
+				"validate_generated_manifests": h.ValidateGeneratedManifests,
+				"validate_request_parameters":  h.ValidateRequestParameters && h.SchemaGenerator != nil,
+				"structured_errors":            h.StructuredErrors,
+			},
+			Metadata: h.Metadata,
+		}
+
+		for name, action := range actions {
+			actionCapabilities := ActionCapabilities{Implemented: action.IsImplemented()}
+			if described, ok := action.(DescribedAction); ok {
+				actionCapabilities = described.Capabilities()
+				actionCapabilities.Implemented = action.IsImplemented()
+			}
+			if custom, ok := h.customActions[name]; ok && actionCapabilities.Help == "" {
+				actionCapabilities.Help = custom.help
+			}
+			capabilities.Actions[name] = actionCapabilities
+		}
+		return capabilities
+	}}
+}
+
+// sdkVersion returns the version of the SDK module the binary was built
+// with, or "(devel)" when unknown.
+func sdkVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	if info.Main.Path == sdkModulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == sdkModulePath {
+			if dep.Replace != nil && dep.Replace.Version != "" {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return "(devel)"
+}
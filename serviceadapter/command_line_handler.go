@@ -16,12 +16,19 @@
 package serviceadapter
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"syscall"
+	"time"
 )
 
 // CommandLineHandler contains all of the implementers required for the service adapter interface
@@ -30,6 +37,90 @@ type CommandLineHandler struct {
 	Binder                Binder
 	DashboardURLGenerator DashboardUrlGenerator
 	SchemaGenerator       SchemaGenerator
+
+	// ValidateGeneratedManifests rejects generated manifests that fail
+	// bosh.BoshManifest.Validate or reference ODB managed secrets they have
+	// no value for, instead of passing them on to ODB.
+	ValidateGeneratedManifests bool
+
+	// ValidateRequestParameters validates the arbitrary parameters passed to
+	// generate-manifest and create-binding against the schemas returned by
+	// SchemaGenerator, applying defaults. Invalid parameters fail with
+	// ErrorExitCode, telling the user what is wrong. ODB does not pass the
+	// plan to create-binding, so binding schemas are generated for the zero
+	// Plan and must not depend on it. It has no effect without a
+	// SchemaGenerator.
+	ValidateRequestParameters bool
+
+	// UpgradePolicy, when not empty, rejects generated manifests whose
+	// transition from the previous plan and manifest violates it, telling
+	// the user which rules were violated.
+	UpgradePolicy UpgradePolicy
+
+	// StructuredErrors makes failed invocations that read their input params
+	// from stdin write an ErrorResponse as JSON to stdout, for the user, and
+	// to stderr, for the operator, instead of the plain error message.
+	// Invocations with positional arguments are not affected.
+	StructuredErrors bool
+
+	// Logger receives structured events for every invocation: when it
+	// starts, a summary of its input params without secrets or parameter
+	// values, and how it ended. Implementers can log with the same
+	// correlation fields through LoggerFromContext. When nil, only the
+	// "[odb-sdk] handling" line is written to stderr.
+	Logger *slog.Logger
+
+	// Metadata is reported as is by the capabilities subcommand, e.g. the
+	// adapter's version or the service it deploys.
+	Metadata map[string]interface{}
+
+	// RecordDir, when set, is the directory every invocation is recorded
+	// to as a Recording, to be replayed with the replay subcommand or
+	// Replay. Secrets are redacted from recordings unless RecordingKey is
+	// set. Failing to record an invocation does not fail it.
+	RecordDir string
+
+	// RecordingKey is an AES key of 16, 24 or 32 bytes. When set, recorded
+	// invocations are encrypted with it rather than redacted, so that they
+	// can be replayed as they happened.
+	RecordingKey []byte
+
+	customActions map[string]customAction
+
+	// recordSink receives the recordings instead of RecordDir when set
+	recordSink func(Recording)
+}
+
+type customAction struct {
+	help   string
+	action Action
+}
+
+var builtInActions = []string{"generate-manifest", "create-binding", "delete-binding", "dashboard-url", "generate-plan-schemas", "capabilities", "replay"}
+
+// RegisterAction adds a subcommand, handled by action like the built-in
+// ones: with the same timeout, logging, redaction and error handling. It is
+// listed with its help text in the supported commands. Actions reading
+// their input params from stdin can use ReadInputParams, and find them in
+// InputParams.Custom under name.
+func (h *CommandLineHandler) RegisterAction(name, help string, action Action) error {
+	switch {
+	case name == "" || strings.ContainsAny(name, " \t\n"):
+		return fmt.Errorf("invalid action name %q", name)
+	case action == nil:
+		return fmt.Errorf("action %s is nil", name)
+	case slices.Contains(builtInActions, name):
+		return fmt.Errorf("action %s is built in", name)
+	}
+	if _, registered := h.customActions[name]; registered {
+		return fmt.Errorf("action %s is already registered", name)
+	}
+
+	if h.customActions == nil {
+		h.customActions = map[string]customAction{}
+	}
+	h.customActions[name] = customAction{help: help, action: action}
+	return nil
 }
 
 type CLIHandlerError struct {
@@ -58,8 +149,17 @@ func HandleCommandLineInvocation(args []string, manifestGenerator ManifestGenera
 // HandleCLI calls the correct Service Adapter handler method based on command
 // line arguments. The first argument at the command line should be one of:
 // generate-manifest, create-binding, delete-binding, dashboard-url.
+//
+// When the implementer of the action is context-aware, the context passed
+// to it is cancelled when the process receives SIGINT or SIGTERM. Otherwise
+// these signals terminate the process, as they would without the SDK.
 func HandleCLI(args []string, handler CommandLineHandler) {
-	err := handler.Handle(args, os.Stdout, os.Stderr, os.Stdin)
+	ctx, stop := context.Background(), func() {}
+	if handler.contextAware(args) {
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	}
+	err := handler.HandleWithContext(ctx, args, os.Stdout, os.Stderr, os.Stdin)
+	stop()
 	switch e := err.(type) {
 	case nil:
 	case CLIHandlerError:
@@ -69,15 +169,72 @@ func HandleCLI(args []string, handler CommandLineHandler) {
 	}
 }
 
+// contextAware tells whether the implementer of the action args invoke
+// takes a context, and so can be cancelled.
+func (h CommandLineHandler) contextAware(args []string) bool {
+	if len(args) < 2 {
+		return false
+	}
+	var ok bool
+	switch args[1] {
+	case "generate-manifest":
+		_, ok = h.ManifestGenerator.(ContextManifestGenerator)
+	case "create-binding", "delete-binding":
+		_, ok = h.Binder.(ContextBinder)
+	case "dashboard-url":
+		_, ok = h.DashboardURLGenerator.(ContextDashboardUrlGenerator)
+	case "generate-plan-schemas":
+		_, ok = h.SchemaGenerator.(ContextSchemaGenerator)
+	default:
+		_, ok = h.customActions[args[1]].action.(ContextAction)
+	}
+	return ok
+}
+
 // Handle executes required action and returns an error. Writes responses to the writer provided
 func (h CommandLineHandler) Handle(args []string, outputWriter, errorWriter io.Writer, inputParamsReader io.Reader) error {
+	return h.HandleWithContext(context.Background(), args, outputWriter, errorWriter, inputParamsReader)
+}
+
+// HandleWithContext is like Handle, but passes ctx on to context-aware
+// implementers. The context is further bounded by the timeout in the input
+// params or, failing that, in the TimeoutEnvVar environment variable.
+func (h CommandLineHandler) HandleWithContext(ctx context.Context, args []string, outputWriter, errorWriter io.Writer, inputParamsReader io.Reader) (err error) {
+	redactor := NewRedactor()
+	errorWriter = redactingWriter{Writer: errorWriter, redactor: redactor}
+
+	var recorder *invocationRecorder
+	if h.RecordDir != "" || h.recordSink != nil {
+		recorder = &invocationRecorder{start: time.Now()}
+		outputWriter = io.MultiWriter(outputWriter, &recorder.stdout)
+		errorWriter = io.MultiWriter(errorWriter, redactingWriter{Writer: &recorder.stderr, redactor: redactor})
+	}
+
+	generateManifestAction := NewGenerateManifestAction(h.ManifestGenerator)
+	if h.ValidateGeneratedManifests {
+		generateManifestAction.WithManifestValidation()
+	}
+	if len(h.UpgradePolicy) > 0 {
+		generateManifestAction.WithUpgradePolicy(h.UpgradePolicy)
+	}
+	createBindingAction := NewCreateBindingAction(h.Binder)
+	if h.ValidateRequestParameters && h.SchemaGenerator != nil {
+		generateManifestAction.WithRequestParametersValidation(h.SchemaGenerator)
+		createBindingAction.WithRequestParametersValidation(h.SchemaGenerator)
+	}
+
 	actions := map[string]Action{
-		"generate-manifest":     NewGenerateManifestAction(h.ManifestGenerator),
-		"create-binding":        NewCreateBindingAction(h.Binder),
+		"generate-manifest":     generateManifestAction,
+		"create-binding":        createBindingAction,
 		"delete-binding":        NewDeleteBindingAction(h.Binder),
 		"dashboard-url":         NewDashboardUrlAction(h.DashboardURLGenerator),
 		"generate-plan-schemas": NewGeneratePlanSchemasAction(h.SchemaGenerator, errorWriter),
 	}
+	for name, custom := range h.customActions {
+		actions[name] = custom.action
+	}
+	actions["capabilities"] = h.newCapabilitiesAction(actions)
+	actions["replay"] = &ReplayAction{handler: h}
 	supportedCommands := h.generateSupportedCommandsMessage(actions)
 
 	if len(args) < 2 {
@@ -88,29 +245,115 @@ func (h CommandLineHandler) Handle(args []string, outputWriter, errorWriter io.W
 	}
 
 	action, arguments := args[1], args[2:]
-	fmt.Fprintf(errorWriter, "[odb-sdk] handling %s\n", action)
+	invocationID := newInvocationID()
 
-	var inputParams InputParams
+	logger := discardLogger
+	if h.Logger != nil {
+		logger = slog.New(redactingHandler{Handler: h.Logger.Handler(), redactor: redactor}).With(slog.String("action", action), slog.String("invocation_id", invocationID))
+		logger.Info("handling action")
+	} else {
+		fmt.Fprintf(errorWriter, "[odb-sdk] handling %s\n", action)
+	}
 
-	var err error
 	ac, ok := actions[action]
 	if !ok {
-		failWithCode(ErrorExitCode, "unknown subcommand: %s. The following commands are supported: %s", args[1], supportedCommands)
-		return nil
+		err := CLIHandlerError{
+			ErrorExitCode,
+			fmt.Sprintf("unknown subcommand: %s. The following commands are supported: %s", args[1], supportedCommands),
+		}
+		logger.Error("unknown action", slog.Int("exit_code", err.ExitCode))
+		return err
 	}
 
 	if !ac.IsImplemented() {
-		return CLIHandlerError{NotImplementedExitCode, fmt.Sprintf("%s not implemented", action)}
+		err := CLIHandlerError{NotImplementedExitCode, fmt.Sprintf("%s not implemented", action)}
+		logger.Info("action not implemented", slog.Int("exit_code", err.ExitCode))
+		return err
+	}
+
+	if recorder != nil && action != "replay" {
+		recorder.action, recorder.invocationID, recorder.args = action, invocationID, arguments
+		defer func() {
+			if recordErr := h.record(recorder, err, redactor); recordErr != nil {
+				if h.Logger != nil {
+					logger.Warn("recording invocation failed", slog.String("error", recordErr.Error()))
+				} else {
+					fmt.Fprintf(errorWriter, "[odb-sdk] recording invocation failed: %s\n", recordErr)
+				}
+			}
+		}()
+	}
+
+	invocation := &invocationWriter{Writer: outputWriter, redactor: redactor}
+	structured := h.StructuredErrors && len(arguments) == 0
+	var heldOutput bytes.Buffer
+	if structured {
+		// held back so that it can be replaced by an ErrorResponse
+		invocation.Writer = &heldOutput
+	}
+
+	start := time.Now()
+	inputParams, err := parseArgs(ac, args, inputParamsReader)
+	if recorder != nil {
+		recorder.inputParams = inputParams
+	}
+	if err == nil {
+		logger = logger.With(inputCorrelationAttrs(action, inputParams)...)
+		logger.Info("parsed input params", inputSummary(action, inputParams))
+		ctx = contextWithRedactor(contextWithLogger(ctx, logger), redactor)
+		err = execute(ctx, ac, inputParams, invocation)
+	}
+	duration := slog.Duration("duration", time.Since(start))
+
+	if err == nil {
+		logger.Info("handled action", duration, slog.Int("exit_code", 0))
+		if structured {
+			_, err = heldOutput.WriteTo(outputWriter)
+		}
+		return err
+	}
+
+	detail := redactor.redactErrorDetail(errorDetail(err, invocation.adapterErr))
+	attrs := []any{
+		duration,
+		slog.Int("exit_code", detail.ExitCode),
+		slog.String("error_kind", detail.Code),
+		slog.Bool("retryable", detail.Retryable),
+		slog.String("error", err.Error()),
+	}
+	if detail.OperatorDetail != "" {
+		attrs = append(attrs, slog.String("operator_detail", detail.OperatorDetail))
 	}
+	logger.Error("action failed", attrs...)
+	if structured {
+		writeErrorResponses(detail, outputWriter, errorWriter)
+	}
+	return redactor.redactError(err)
+}
 
-	if inputParams, err = ac.ParseArgs(inputParamsReader, arguments); err != nil {
+func parseArgs(ac Action, args []string, inputParamsReader io.Reader) (InputParams, error) {
+	inputParams, err := ac.ParseArgs(inputParamsReader, args[2:])
+	if err != nil {
 		switch e := err.(type) {
 		case MissingArgsError:
-			return missingArgsError(args, e.Error())
+			return inputParams, missingArgsError(args, e.Error())
 		default:
-			return e
+			return inputParams, e
 		}
 	}
+	return inputParams, nil
+}
+
+func execute(ctx context.Context, ac Action, inputParams InputParams, outputWriter io.Writer) error {
+	ctx, cancel, err := withInvocationTimeout(ctx, inputParams)
+	if err != nil {
+		return CLIHandlerError{ErrorExitCode, err.Error()}
+	}
+	defer cancel()
+
+	if contextAction, ok := ac.(ContextAction); ok {
+		return contextAction.ExecuteWithContext(ctx, inputParams, outputWriter)
+	}
 	return ac.Execute(inputParams, outputWriter)
 }
 
@@ -154,7 +397,14 @@ func (h CommandLineHandler) generateSupportedCommandsMessage(actions map[string]
 	}
 
 	sort.Strings(commands)
-	return strings.Join(commands, ", ")
+	message := strings.Join(commands, ", ")
+
+	for _, command := range commands {
+		if custom, ok := h.customActions[command]; ok && custom.help != "" {
+			message += fmt.Sprintf("\n  %s: %s", command, custom.help)
+		}
+	}
+	return message
 }
 
 func (h CommandLineHandler) must(err error, msg string) {
@@ -17,8 +17,15 @@ package serviceadapter_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -112,7 +119,13 @@ var _ = Describe("CommandLineHandler", func() {
 	It("when no arguments passed returns error", func() {
 		err := handler.Handle([]string{commandName}, outputBuffer, errorBuffer, bytes.NewBufferString(""))
 
-		Expect(err).To(BeACLIError(1, "the following commands are supported: create-binding, dashboard-url, delete-binding, generate-manifest, generate-plan-schemas"))
+		Expect(err).To(BeACLIError(1, "the following commands are supported: capabilities, create-binding, dashboard-url, delete-binding, generate-manifest, generate-plan-schemas, replay"))
+	})
+
+	It("returns an error for an unknown subcommand", func() {
+		err := handler.Handle([]string{commandName, "non-existing-subcommand"}, outputBuffer, errorBuffer, bytes.NewBufferString(""))
+
+		Expect(err).To(BeACLIError(1, "unknown subcommand: non-existing-subcommand. The following commands are supported: capabilities, create-binding, dashboard-url, delete-binding, generate-manifest, generate-plan-schemas, replay"))
 	})
 
 	It("does not output optional commands if not implemented", func() {
@@ -120,7 +133,7 @@ var _ = Describe("CommandLineHandler", func() {
 		handler.SchemaGenerator = nil
 		err := handler.Handle([]string{commandName}, outputBuffer, errorBuffer, bytes.NewBufferString(""))
 
-		Expect(err).To(BeACLIError(1, "the following commands are supported: create-binding, delete-binding, generate-manifest"))
+		Expect(err).To(BeACLIError(1, "the following commands are supported: capabilities, create-binding, delete-binding, generate-manifest, replay"))
 		Expect(err.Error()).NotTo(ContainSubstring("dashboard-url"))
 		Expect(err.Error()).NotTo(ContainSubstring("generate-plan-schemas"))
 	})
@@ -203,6 +216,26 @@ var _ = Describe("CommandLineHandler", func() {
 
 			Expect(err).To(MatchError(ContainSubstring("unmarshalling service plan")))
 		})
+
+		It("rejects an inconsistent manifest when ValidateGeneratedManifests is set", func() {
+			manifest := defaultManifest()
+			manifest.Releases = nil
+			manifest.InstanceGroups = []bosh.InstanceGroup{{
+				Name:     "redis",
+				Stemcell: "greatest",
+				AZs:      []string{"z1"},
+				Networks: []bosh.Network{{Name: "default"}},
+				Jobs:     []bosh.Job{{Name: "redis", Release: "redis"}},
+			}}
+			fakeManifestGenerator.GenerateManifestReturns(serviceadapter.GenerateManifestOutput{Manifest: manifest}, nil)
+			handler.ValidateGeneratedManifests = true
+
+			err := handler.Handle([]string{
+				commandName, "generate-manifest", serviceDeploymentJSON, planJSON, argsJSON, previousManifestYAML, previousPlanJSON,
+			}, outputBuffer, errorBuffer, bytes.NewBufferString(""))
+
+			Expect(err).To(BeACLIError(serviceadapter.ErrorExitCode, "release 'redis' is not declared in releases"))
+		})
 	})
 
 	Describe("create-binding action", func() {
@@ -554,4 +587,788 @@ var _ = Describe("CommandLineHandler", func() {
 			Expect(err).To(MatchError(ContainSubstring("unmarshalling plan JSON")))
 		})
 	})
+
+	Describe("context-aware implementers", func() {
+		var (
+			fakeContextManifestGenerator     *fakes.FakeContextManifestGenerator
+			fakeContextBinder                *fakes.FakeContextBinder
+			fakeContextDashboardUrlGenerator *fakes.FakeContextDashboardUrlGenerator
+			fakeContextSchemaGenerator       *fakes.FakeContextSchemaGenerator
+			generateManifestInput            string
+		)
+
+		BeforeEach(func() {
+			fakeContextManifestGenerator = new(fakes.FakeContextManifestGenerator)
+			fakeContextBinder = new(fakes.FakeContextBinder)
+			fakeContextDashboardUrlGenerator = new(fakes.FakeContextDashboardUrlGenerator)
+			fakeContextSchemaGenerator = new(fakes.FakeContextSchemaGenerator)
+
+			handler = serviceadapter.CommandLineHandler{
+				ManifestGenerator:     contextManifestGenerator{fakeManifestGenerator, fakeContextManifestGenerator},
+				Binder:                contextBinder{fakeBinder, fakeContextBinder},
+				DashboardURLGenerator: contextDashboardUrlGenerator{fakeDashboardUrlGenerator, fakeContextDashboardUrlGenerator},
+				SchemaGenerator:       contextSchemaGenerator{fakeSchemaGenerator, fakeContextSchemaGenerator},
+			}
+
+			generateManifestInput = toJson(serviceadapter.InputParams{
+				GenerateManifest: serviceadapter.GenerateManifestJSONParams{
+					ServiceDeployment: serviceDeploymentJSON,
+					Plan:              planJSON,
+					PreviousPlan:      previousPlanJSON,
+					RequestParameters: requestParamsJSON,
+					PreviousManifest:  previousManifestYAML,
+				},
+			})
+		})
+
+		It("prefers GenerateManifestWithContext", func() {
+			err := handler.Handle([]string{commandName, "generate-manifest"}, outputBuffer, errorBuffer, bytes.NewBufferString(generateManifestInput))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeManifestGenerator.GenerateManifestCallCount()).To(Equal(0))
+			Expect(fakeContextManifestGenerator.GenerateManifestWithContextCallCount()).To(Equal(1))
+			_, params := fakeContextManifestGenerator.GenerateManifestWithContextArgsForCall(0)
+			Expect(params.ServiceDeployment).To(Equal(serviceDeployment))
+		})
+
+		It("prefers CreateBindingWithContext and DeleteBindingWithContext", func() {
+			fakeContextBinder.CreateBindingWithContextReturns(expectedBinding, nil)
+
+			err := handler.Handle([]string{
+				commandName, "create-binding", bindingID, boshVMsJSON, previousManifestYAML, requestParamsJSON,
+			}, outputBuffer, errorBuffer, bytes.NewBufferString(""))
+			Expect(err).NotTo(HaveOccurred())
+
+			err = handler.Handle([]string{
+				commandName, "delete-binding", bindingID, boshVMsJSON, previousManifestYAML, requestParamsJSON,
+			}, outputBuffer, errorBuffer, bytes.NewBufferString(""))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeBinder.CreateBindingCallCount()).To(Equal(0))
+			Expect(fakeBinder.DeleteBindingCallCount()).To(Equal(0))
+			Expect(fakeContextBinder.CreateBindingWithContextCallCount()).To(Equal(1))
+			Expect(fakeContextBinder.DeleteBindingWithContextCallCount()).To(Equal(1))
+		})
+
+		It("prefers DashboardUrlWithContext", func() {
+			err := handler.Handle([]string{
+				commandName, "dashboard-url", instanceID, planJSON, previousManifestYAML,
+			}, outputBuffer, errorBuffer, bytes.NewBufferString(""))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeDashboardUrlGenerator.DashboardUrlCallCount()).To(Equal(0))
+			Expect(fakeContextDashboardUrlGenerator.DashboardUrlWithContextCallCount()).To(Equal(1))
+		})
+
+		It("prefers GeneratePlanSchemaWithContext", func() {
+			err := handler.Handle([]string{
+				commandName, "generate-plan-schemas", "-plan-json", planJSON,
+			}, outputBuffer, errorBuffer, bytes.NewBufferString(""))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeSchemaGenerator.GeneratePlanSchemaCallCount()).To(Equal(0))
+			Expect(fakeContextSchemaGenerator.GeneratePlanSchemaWithContextCallCount()).To(Equal(1))
+		})
+
+		It("passes on the context given to HandleWithContext", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := handler.HandleWithContext(ctx, []string{commandName, "generate-manifest"}, outputBuffer, errorBuffer, bytes.NewBufferString(generateManifestInput))
+			Expect(err).NotTo(HaveOccurred())
+
+			actualContext, _ := fakeContextManifestGenerator.GenerateManifestWithContextArgsForCall(0)
+			Expect(actualContext.Err()).To(MatchError(context.Canceled))
+		})
+
+		It("has no deadline by default", func() {
+			err := handler.Handle([]string{commandName, "generate-manifest"}, outputBuffer, errorBuffer, bytes.NewBufferString(generateManifestInput))
+			Expect(err).NotTo(HaveOccurred())
+
+			actualContext, _ := fakeContextManifestGenerator.GenerateManifestWithContextArgsForCall(0)
+			_, hasDeadline := actualContext.Deadline()
+			Expect(hasDeadline).To(BeFalse())
+		})
+
+		It("bounds the context by the timeout in the environment", func() {
+			os.Setenv(serviceadapter.TimeoutEnvVar, "1m")
+			DeferCleanup(os.Unsetenv, serviceadapter.TimeoutEnvVar)
+
+			err := handler.Handle([]string{commandName, "generate-manifest"}, outputBuffer, errorBuffer, bytes.NewBufferString(generateManifestInput))
+			Expect(err).NotTo(HaveOccurred())
+
+			actualContext, _ := fakeContextManifestGenerator.GenerateManifestWithContextArgsForCall(0)
+			deadline, hasDeadline := actualContext.Deadline()
+			Expect(hasDeadline).To(BeTrue())
+			Expect(deadline).To(BeTemporally("~", time.Now().Add(time.Minute), 5*time.Second))
+		})
+
+		It("prefers the timeout in the input params over the environment", func() {
+			os.Setenv(serviceadapter.TimeoutEnvVar, "1m")
+			DeferCleanup(os.Unsetenv, serviceadapter.TimeoutEnvVar)
+
+			var inputParams serviceadapter.InputParams
+			Expect(json.Unmarshal([]byte(generateManifestInput), &inputParams)).To(Succeed())
+			inputParams.Timeout = "1h"
+
+			err := handler.Handle([]string{commandName, "generate-manifest"}, outputBuffer, errorBuffer, bytes.NewBufferString(toJson(inputParams)))
+			Expect(err).NotTo(HaveOccurred())
+
+			actualContext, _ := fakeContextManifestGenerator.GenerateManifestWithContextArgsForCall(0)
+			deadline, _ := actualContext.Deadline()
+			Expect(deadline).To(BeTemporally("~", time.Now().Add(time.Hour), 5*time.Second))
+		})
+
+		It("returns an error when the timeout is invalid", func() {
+			os.Setenv(serviceadapter.TimeoutEnvVar, "soon")
+			DeferCleanup(os.Unsetenv, serviceadapter.TimeoutEnvVar)
+
+			err := handler.Handle([]string{commandName, "generate-manifest"}, outputBuffer, errorBuffer, bytes.NewBufferString(generateManifestInput))
+
+			Expect(err).To(BeACLIError(serviceadapter.ErrorExitCode, `invalid timeout "soon"`))
+			Expect(fakeContextManifestGenerator.GenerateManifestWithContextCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("structured errors", func() {
+		var (
+			stdout, stderr      *bytes.Buffer
+			createBindingInput  string
+			createBindingHandle func() error
+		)
+
+		BeforeEach(func() {
+			handler.StructuredErrors = true
+			stdout, stderr = new(bytes.Buffer), new(bytes.Buffer)
+			createBindingInput = toJson(serviceadapter.InputParams{
+				CreateBinding: serviceadapter.CreateBindingJSONParams{
+					RequestParameters: requestParamsJSON,
+					BindingId:         bindingID,
+					BoshVms:           boshVMsJSON,
+					Manifest:          previousManifestYAML,
+				},
+			})
+			createBindingHandle = func() error {
+				return handler.Handle([]string{commandName, "create-binding"}, stdout, stderr, bytes.NewBufferString(createBindingInput))
+			}
+		})
+
+		lastLine := func(b *bytes.Buffer) string {
+			lines := bytes.Split(bytes.TrimSpace(b.Bytes()), []byte("\n"))
+			return string(lines[len(lines)-1])
+		}
+
+		It("writes the output as usual when the action succeeds", func() {
+			fakeBinder.CreateBindingReturns(expectedBinding, nil)
+
+			Expect(createBindingHandle()).To(Succeed())
+			Expect(stdout.String()).To(MatchJSON(toJson(expectedBinding)))
+		})
+
+		It("separates the user message from the operator detail of an AdapterError", func() {
+			fakeBinder.CreateBindingReturns(serviceadapter.Binding{}, serviceadapter.AdapterError{
+				Code:           "quota_exceeded",
+				UserMessage:    "the service is at capacity, try again later",
+				OperatorDetail: "instance quota of 20 reached",
+				Retryable:      true,
+				Cause:          fmt.Errorf("creating user: %w", errors.New("connection refused")),
+			})
+
+			err := createBindingHandle()
+
+			Expect(err).To(BeACLIError(serviceadapter.ErrorExitCode, "the service is at capacity, try again later"))
+			Expect(stdout.String()).To(MatchJSON(`{"error": {
+				"code": "quota_exceeded",
+				"message": "the service is at capacity, try again later",
+				"retryable": true
+			}}`))
+			Expect(lastLine(stderr)).To(MatchJSON(`{"error": {
+				"code": "quota_exceeded",
+				"message": "the service is at capacity, try again later",
+				"operator_detail": "instance quota of 20 reached",
+				"retryable": true,
+				"exit_code": 1,
+				"causes": ["creating user: connection refused", "connection refused"]
+			}}`))
+		})
+
+		It("exits with the exit code of an AdapterError", func() {
+			fakeBinder.CreateBindingReturns(serviceadapter.Binding{}, &serviceadapter.AdapterError{
+				UserMessage: "binding exists",
+				ExitCode:    serviceadapter.BindingAlreadyExistsErrorExitCode,
+			})
+
+			err := createBindingHandle()
+
+			Expect(err).To(BeACLIError(serviceadapter.BindingAlreadyExistsErrorExitCode, "binding exists"))
+			Expect(stdout.String()).To(MatchJSON(`{"error": {"code": "binding_already_exists", "message": "binding exists", "retryable": false}}`))
+		})
+
+		It("reports invalid parameters with their own code", func() {
+			fakeBinder.CreateBindingReturns(serviceadapter.Binding{}, serviceadapter.NewInvalidParametersError(errors.New("unknown role")))
+
+			err := createBindingHandle()
+
+			Expect(err).To(BeACLIError(serviceadapter.ErrorExitCode, "invalid parameters: unknown role"))
+			Expect(stdout.String()).To(MatchJSON(`{"error": {"code": "invalid_parameters", "message": "invalid parameters: unknown role", "retryable": false}}`))
+		})
+
+		It("derives the code of other errors from the exit code", func() {
+			fakeBinder.CreateBindingReturns(serviceadapter.Binding{}, serviceadapter.NewAppGuidNotProvidedError(errors.New("no app")))
+
+			err := createBindingHandle()
+
+			Expect(err).To(BeACLIError(serviceadapter.AppGuidNotProvidedErrorExitCode, "app GUID not provided: no app"))
+			Expect(stdout.String()).To(MatchJSON(`{"error": {"code": "app_guid_not_provided", "message": "app GUID not provided: no app", "retryable": false}}`))
+		})
+
+		It("reports plain adapter errors as adapter errors", func() {
+			fakeBinder.CreateBindingReturns(serviceadapter.Binding{}, errors.New("oops"))
+
+			createBindingHandle()
+
+			Expect(stdout.String()).To(MatchJSON(`{"error": {"code": "adapter_error", "message": "oops", "retryable": false}}`))
+			Expect(lastLine(stderr)).To(MatchJSON(`{"error": {"code": "adapter_error", "message": "oops", "retryable": false, "exit_code": 1, "causes": ["oops"]}}`))
+		})
+
+		It("does not show internal errors to the user", func() {
+			createBindingInput = `{"create_binding": {"bosh_vms": "not json"}}`
+
+			err := createBindingHandle()
+
+			Expect(err).To(MatchError(ContainSubstring("unmarshalling BOSH VMs")))
+			Expect(stdout.String()).To(MatchJSON(`{"error": {"code": "internal_error", "retryable": false}}`))
+			Expect(lastLine(stderr)).To(ContainSubstring("unmarshalling BOSH VMs"))
+		})
+
+		It("keeps plain text errors for positional arguments", func() {
+			fakeBinder.CreateBindingReturns(serviceadapter.Binding{}, serviceadapter.AdapterError{UserMessage: "oops", OperatorDetail: "secret"})
+
+			err := handler.Handle([]string{
+				commandName, "create-binding", bindingID, boshVMsJSON, previousManifestYAML, requestParamsJSON,
+			}, stdout, stderr, bytes.NewBufferString(""))
+
+			Expect(err).To(BeACLIError(serviceadapter.ErrorExitCode, "oops"))
+			Expect(stdout.String()).To(Equal("oops"))
+			Expect(stderr.String()).NotTo(ContainSubstring("secret"))
+		})
+	})
+	Describe("logging", func() {
+		var logBuffer *bytes.Buffer
+
+		BeforeEach(func() {
+			logBuffer = new(bytes.Buffer)
+			handler.Logger = slog.New(slog.NewJSONHandler(logBuffer, nil))
+		})
+
+		logEvents := func() []map[string]interface{} {
+			var events []map[string]interface{}
+			decoder := json.NewDecoder(logBuffer)
+			for decoder.More() {
+				var event map[string]interface{}
+				Expect(decoder.Decode(&event)).To(Succeed())
+				delete(event, "time")
+				events = append(events, event)
+			}
+			return events
+		}
+
+		It("logs the start, input and end of an action with correlation fields", func() {
+			fakeBinder.CreateBindingReturns(expectedBinding, nil)
+			input := toJson(serviceadapter.InputParams{
+				CreateBinding: serviceadapter.CreateBindingJSONParams{
+					BindingId:         bindingID,
+					BoshVms:           boshVMsJSON,
+					Manifest:          previousManifestYAML,
+					RequestParameters: `{"plan_id": "small", "parameters": {"password": "hunter2", "size": 3}}`,
+					Secrets:           `{"/admin_password": "hunter3"}`,
+				},
+			})
+
+			err := handler.Handle([]string{commandName, "create-binding"}, outputBuffer, errorBuffer, bytes.NewBufferString(input))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(errorBuffer).NotTo(gbytes.Say("handling"))
+			Expect(logBuffer.String()).NotTo(ContainSubstring("hunter"))
+
+			events := logEvents()
+			Expect(events).To(HaveLen(3))
+			invocationID := events[0]["invocation_id"]
+			Expect(invocationID).To(MatchRegexp("^[0-9a-f]{16}$"))
+
+			Expect(events[0]).To(Equal(map[string]interface{}{
+				"level": "INFO", "msg": "handling action", "action": "create-binding", "invocation_id": invocationID,
+			}))
+			Expect(events[1]).To(Equal(map[string]interface{}{
+				"level": "INFO", "msg": "parsed input params", "action": "create-binding", "invocation_id": invocationID,
+				"binding_id": bindingID,
+				"input": map[string]interface{}{
+					"secrets":       []interface{}{"/admin_password"},
+					"dns_addresses": false,
+					"parameters":    []interface{}{"password", "size"},
+					"plan_id":       "small",
+				},
+			}))
+			Expect(events[2]).To(HaveKeyWithValue("msg", "handled action"))
+			Expect(events[2]).To(HaveKeyWithValue("binding_id", bindingID))
+			Expect(events[2]).To(HaveKeyWithValue("exit_code", BeNumerically("==", 0)))
+			Expect(events[2]).To(HaveKey("duration"))
+		})
+
+		It("logs the exit code and kind of error when an action fails", func() {
+			fakeBinder.DeleteBindingReturns(serviceadapter.NewBindingNotFoundError(errors.New("gone")))
+
+			err := handler.Handle([]string{
+				commandName, "delete-binding", bindingID, boshVMsJSON, previousManifestYAML, requestParamsJSON,
+			}, outputBuffer, errorBuffer, bytes.NewBufferString(""))
+			Expect(err).To(HaveOccurred())
+
+			events := logEvents()
+			last := events[len(events)-1]
+			Expect(last).To(HaveKeyWithValue("level", "ERROR"))
+			Expect(last).To(HaveKeyWithValue("msg", "action failed"))
+			Expect(last).To(HaveKeyWithValue("exit_code", BeNumerically("==", serviceadapter.BindingNotFoundErrorExitCode)))
+			Expect(last).To(HaveKeyWithValue("error_kind", "binding_not_found"))
+			Expect(last).To(HaveKeyWithValue("error", "binding not found: gone"))
+		})
+
+		It("gives context-aware implementers the invocation logger", func() {
+			fakeContextManifestGenerator := new(fakes.FakeContextManifestGenerator)
+			fakeContextManifestGenerator.GenerateManifestWithContextStub = func(ctx context.Context, _ serviceadapter.GenerateManifestParams) (serviceadapter.GenerateManifestOutput, error) {
+				serviceadapter.LoggerFromContext(ctx).Info("generating", slog.Int("instances", 3))
+				return serviceadapter.GenerateManifestOutput{}, nil
+			}
+			handler.ManifestGenerator = contextManifestGenerator{fakeManifestGenerator, fakeContextManifestGenerator}
+
+			err := handler.Handle([]string{
+				commandName, "generate-manifest", serviceDeploymentJSON, planJSON, argsJSON, previousManifestYAML, previousPlanJSON,
+			}, outputBuffer, errorBuffer, bytes.NewBufferString(""))
+			Expect(err).NotTo(HaveOccurred())
+
+			events := logEvents()
+			Expect(events).To(ContainElement(SatisfyAll(
+				HaveKeyWithValue("msg", "generating"),
+				HaveKeyWithValue("instances", BeNumerically("==", 3)),
+				HaveKeyWithValue("action", "generate-manifest"),
+				HaveKeyWithValue("deployment_name", serviceDeployment.DeploymentName),
+				HaveKeyWithValue("invocation_id", events[0]["invocation_id"]),
+			)))
+		})
+
+		It("writes the handling line when no logger is set", func() {
+			handler.Logger = nil
+			handler.Handle([]string{commandName, "generate-manifest"}, outputBuffer, errorBuffer, bytes.NewBufferString(""))
+
+			Expect(errorBuffer).To(gbytes.Say(`\[odb-sdk\] handling generate-manifest`))
+			Expect(serviceadapter.LoggerFromContext(context.Background()).Enabled(context.Background(), slog.LevelError)).To(BeFalse())
+		})
+	})
+	Describe("secret redaction", func() {
+		const secret = "hunter2-very-secret"
+
+		var (
+			stdout, stderr *bytes.Buffer
+			bindingInput   string
+		)
+
+		BeforeEach(func() {
+			stdout, stderr = new(bytes.Buffer), new(bytes.Buffer)
+			bindingInput = toJson(serviceadapter.InputParams{
+				CreateBinding: serviceadapter.CreateBindingJSONParams{
+					BindingId:         bindingID,
+					BoshVms:           boshVMsJSON,
+					Manifest:          previousManifestYAML,
+					RequestParameters: requestParamsJSON,
+					Secrets:           toJson(serviceadapter.ManifestSecrets{"/admin_password": secret}),
+				},
+			})
+		})
+
+		It("redacts secrets passed in from adapter errors", func() {
+			fakeBinder.CreateBindingReturns(serviceadapter.Binding{}, fmt.Errorf("cannot log in with %s", secret))
+
+			err := handler.Handle([]string{commandName, "create-binding"}, stdout, stderr, bytes.NewBufferString(bindingInput))
+
+			Expect(err).To(BeACLIError(serviceadapter.ErrorExitCode, "cannot log in with [REDACTED]"))
+			Expect(stdout.String()).To(Equal("cannot log in with [REDACTED]"))
+		})
+
+		It("redacts the credentials of the binding and the UAA client secret", func() {
+			fakeBinder.CreateBindingReturns(serviceadapter.Binding{Credentials: map[string]interface{}{"password": "binding-password"}}, nil)
+
+			err := handler.Handle([]string{commandName, "create-binding"}, stdout, stderr, bytes.NewBufferString(bindingInput))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout.String()).To(ContainSubstring("binding-password"))
+
+			fakeManifestGenerator.GenerateManifestReturns(serviceadapter.GenerateManifestOutput{
+				ODBManagedSecrets: serviceadapter.ODBManagedSecrets{"generated": "generated-secret"},
+			}, errors.New("cannot use uaa-client-secret or generated-secret"))
+			generateManifestInput := toJson(serviceadapter.InputParams{
+				GenerateManifest: serviceadapter.GenerateManifestJSONParams{
+					ServiceDeployment:        serviceDeploymentJSON,
+					Plan:                     planJSON,
+					RequestParameters:        requestParamsJSON,
+					PreviousManifest:         previousManifestYAML,
+					PreviousPlan:             previousPlanJSON,
+					ServiceInstanceUAAClient: `{"client_id": "id", "client_secret": "uaa-client-secret"}`,
+				},
+			})
+
+			stdout.Reset()
+			err = handler.Handle([]string{commandName, "generate-manifest"}, stdout, stderr, bytes.NewBufferString(generateManifestInput))
+			Expect(err).To(MatchError("cannot use [REDACTED] or [REDACTED]"))
+			Expect(stdout.String()).To(Equal("cannot use [REDACTED] or [REDACTED]"))
+		})
+
+		It("redacts structured errors, logs and stderr", func() {
+			logBuffer := new(bytes.Buffer)
+			handler.Logger = slog.New(slog.NewJSONHandler(logBuffer, nil))
+			handler.StructuredErrors = true
+			fakeContextBinder := new(fakes.FakeContextBinder)
+			fakeContextBinder.CreateBindingWithContextStub = func(ctx context.Context, _ serviceadapter.CreateBindingParams) (serviceadapter.Binding, error) {
+				serviceadapter.RedactorFromContext(ctx).Add("adapter-own-secret")
+				serviceadapter.LoggerFromContext(ctx).Info("connecting", slog.String("password", secret), slog.Any("error", errors.New("adapter-own-secret")))
+				return serviceadapter.Binding{}, serviceadapter.AdapterError{
+					UserMessage:    "cannot connect",
+					OperatorDetail: "password " + secret + " rejected",
+					Cause:          errors.New("auth failed for " + secret),
+				}
+			}
+			handler.Binder = contextBinder{fakeBinder, fakeContextBinder}
+
+			err := handler.Handle([]string{commandName, "create-binding"}, stdout, stderr, bytes.NewBufferString(bindingInput))
+			Expect(err).To(HaveOccurred())
+
+			Expect(stdout.String()).NotTo(ContainSubstring(secret))
+			Expect(stderr.String()).To(ContainSubstring("password [REDACTED] rejected"))
+			Expect(stderr.String()).To(ContainSubstring("auth failed for [REDACTED]"))
+			Expect(stderr.String()).NotTo(ContainSubstring(secret))
+			Expect(logBuffer.String()).To(ContainSubstring(`"password":"[REDACTED]"`))
+			Expect(logBuffer.String()).NotTo(ContainSubstring(secret))
+			Expect(logBuffer.String()).NotTo(ContainSubstring("adapter-own-secret"))
+		})
+	})
+	Describe("custom actions", func() {
+		var fakeAction *fakes.FakeAction
+
+		BeforeEach(func() {
+			fakeAction = new(fakes.FakeAction)
+			fakeAction.IsImplementedReturns(true)
+			Expect(handler.RegisterAction("describe", "describes the service instance", fakeAction)).To(Succeed())
+		})
+
+		It("lists custom actions with their help text", func() {
+			Expect(handler.RegisterAction("migrate-instance", "", new(fakes.FakeAction))).To(Succeed())
+
+			err := handler.Handle([]string{commandName}, outputBuffer, errorBuffer, bytes.NewBufferString(""))
+
+			Expect(err).To(BeACLIError(1, "the following commands are supported: capabilities, create-binding, dashboard-url, delete-binding, describe, generate-manifest, generate-plan-schemas, replay\n  describe: describes the service instance"))
+		})
+
+		It("parses the arguments and executes the action", func() {
+			fakeAction.ParseArgsStub = func(reader io.Reader, args []string) (serviceadapter.InputParams, error) {
+				return serviceadapter.ReadInputParams(reader)
+			}
+			fakeAction.ExecuteStub = func(inputParams serviceadapter.InputParams, w io.Writer) error {
+				var describeParams struct {
+					InstanceID string `json:"instance_id"`
+				}
+				Expect(json.Unmarshal(inputParams.Custom["describe"], &describeParams)).To(Succeed())
+				_, err := fmt.Fprintf(w, "described %s", describeParams.InstanceID)
+				return err
+			}
+
+			err := handler.Handle([]string{commandName, "describe"}, outputBuffer, errorBuffer, bytes.NewBufferString(`{"describe": {"instance_id": "some-instance"}}`))
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(outputBuffer).To(gbytes.Say("described some-instance"))
+		})
+
+		It("passes on positional arguments", func() {
+			err := handler.Handle([]string{commandName, "describe", "some-instance"}, outputBuffer, errorBuffer, bytes.NewBufferString(""))
+
+			Expect(err).NotTo(HaveOccurred())
+			_, args := fakeAction.ParseArgsArgsForCall(0)
+			Expect(args).To(Equal([]string{"some-instance"}))
+			Expect(fakeAction.ExecuteCallCount()).To(Equal(1))
+		})
+
+		It("returns the errors of the action", func() {
+			fakeAction.ExecuteReturns(serviceadapter.CLIHandlerError{ExitCode: 3, Message: "cannot describe"})
+
+			err := handler.Handle([]string{commandName, "describe", "some-instance"}, outputBuffer, errorBuffer, bytes.NewBufferString(""))
+
+			Expect(err).To(BeACLIError(3, "cannot describe"))
+		})
+
+		It("returns a not-implemented error when the action is not implemented", func() {
+			fakeAction.IsImplementedReturns(false)
+
+			err := handler.Handle([]string{commandName, "describe"}, outputBuffer, errorBuffer, bytes.NewBufferString(""))
+
+			Expect(err).To(BeACLIError(serviceadapter.NotImplementedExitCode, "describe not implemented"))
+		})
+
+		It("fails to register invalid actions", func() {
+			Expect(handler.RegisterAction("describe", "", new(fakes.FakeAction))).To(MatchError("action describe is already registered"))
+			Expect(handler.RegisterAction("create-binding", "", new(fakes.FakeAction))).To(MatchError("action create-binding is built in"))
+			Expect(handler.RegisterAction("", "", new(fakes.FakeAction))).To(MatchError(`invalid action name ""`))
+			Expect(handler.RegisterAction("other", "", nil)).To(MatchError("action other is nil"))
+		})
+	})
+	Describe("capabilities action", func() {
+		capabilitiesOf := func(handler serviceadapter.CommandLineHandler) serviceadapter.Capabilities {
+			stdout := new(bytes.Buffer)
+			Expect(handler.Handle([]string{commandName, "capabilities"}, stdout, errorBuffer, nil)).To(Succeed())
+
+			var capabilities serviceadapter.Capabilities
+			Expect(json.Unmarshal(stdout.Bytes(), &capabilities)).To(Succeed())
+			return capabilities
+		}
+
+		It("describes every action", func() {
+			handler.DashboardURLGenerator = nil
+
+			capabilities := capabilitiesOf(handler)
+
+			Expect(capabilities.SDKVersion).NotTo(BeEmpty())
+			Expect(capabilities.Actions).To(HaveLen(7))
+			Expect(capabilities.Actions["create-binding"]).To(Equal(serviceadapter.ActionCapabilities{
+				Implemented:    true,
+				InputProtocols: []string{serviceadapter.PositionalArgsProtocol, serviceadapter.StdinJSONProtocol},
+				OptionalFields: []string{"secrets", "dns_addresses", "timeout"},
+			}))
+			Expect(capabilities.Actions["dashboard-url"].Implemented).To(BeFalse())
+			Expect(capabilities.Actions["dashboard-url"].InputProtocols).NotTo(BeEmpty())
+		})
+
+		It("reports the optional features enabled and the adapter's metadata", func() {
+			handler.ValidateGeneratedManifests = true
+			handler.StructuredErrors = true
+			handler.Metadata = map[string]interface{}{"adapter_version": "1.2.3"}
+
+			capabilities := capabilitiesOf(handler)
+
+			Expect(capabilities.Features).To(Equal(map[string]bool{
+				"validate_generated_manifests": true,
+				"validate_request_parameters":  false,
+				"structured_errors":            true,
+			}))
+			Expect(capabilities.Metadata).To(Equal(map[string]interface{}{"adapter_version": "1.2.3"}))
+		})
+
+		It("describes custom actions", func() {
+			plainAction := new(fakes.FakeAction)
+			plainAction.IsImplementedReturns(true)
+			Expect(handler.RegisterAction("describe", "describes the service instance", plainAction)).To(Succeed())
+			Expect(handler.RegisterAction("migrate", "migrates the service instance", describedAction{plainAction})).To(Succeed())
+
+			capabilities := capabilitiesOf(handler)
+
+			Expect(capabilities.Actions["describe"]).To(Equal(serviceadapter.ActionCapabilities{
+				Implemented: true,
+				Help:        "describes the service instance",
+			}))
+			Expect(capabilities.Actions["migrate"]).To(Equal(serviceadapter.ActionCapabilities{
+				Implemented:    true,
+				Help:           "migrates the service instance",
+				InputProtocols: []string{serviceadapter.StdinJSONProtocol},
+			}))
+		})
+	})
+
+	Describe("recording and replay", func() {
+		const secret = "hunter2-very-secret"
+
+		var (
+			recordDir      string
+			stdout, stderr *bytes.Buffer
+			bindingInput   string
+		)
+
+		BeforeEach(func() {
+			recordDir = GinkgoT().TempDir()
+			handler.RecordDir = recordDir
+			stdout, stderr = new(bytes.Buffer), new(bytes.Buffer)
+			bindingInput = toJson(serviceadapter.InputParams{
+				CreateBinding: serviceadapter.CreateBindingJSONParams{
+					BindingId:         bindingID,
+					BoshVms:           boshVMsJSON,
+					Manifest:          previousManifestYAML,
+					RequestParameters: requestParamsJSON,
+					Secrets:           toJson(serviceadapter.ManifestSecrets{"/admin_password": secret}),
+				},
+			})
+			fakeBinder.CreateBindingReturns(serviceadapter.Binding{Credentials: map[string]interface{}{"password": "binding-password"}}, nil)
+		})
+
+		onlyRecording := func() (string, serviceadapter.Recording) {
+			paths, err := filepath.Glob(filepath.Join(recordDir, "*-create-binding-*.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(paths).To(HaveLen(1))
+
+			recording, err := serviceadapter.LoadRecording(paths[0])
+			Expect(err).NotTo(HaveOccurred())
+			return paths[0], recording
+		}
+
+		It("records invocations with their secrets redacted", func() {
+			Expect(handler.Handle([]string{commandName, "create-binding"}, stdout, stderr, bytes.NewBufferString(bindingInput))).To(Succeed())
+			Expect(stdout.String()).To(ContainSubstring("binding-password"))
+
+			path, recording := onlyRecording()
+			info, err := os.Stat(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+			Expect(recording.Action).To(Equal("create-binding"))
+			Expect(recording.ExitCode).To(Equal(0))
+			Expect(recording.Encrypted).To(BeNil())
+			Expect(recording.Invocation.Args).To(BeEmpty())
+			Expect(recording.Invocation.InputParams.CreateBinding.BindingId).To(Equal(bindingID))
+			Expect(recording.Invocation.InputParams.CreateBinding.Secrets).To(MatchJSON(`{"/admin_password": "[REDACTED]"}`))
+			Expect(recording.Invocation.Stdout).To(ContainSubstring(`"password":"[REDACTED]"`))
+			Expect(recording.Invocation.Stderr).To(ContainSubstring("[odb-sdk] handling create-binding"))
+
+			content, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).NotTo(ContainSubstring(secret))
+			Expect(string(content)).NotTo(ContainSubstring("binding-password"))
+		})
+
+		It("records the exit code of failed invocations", func() {
+			fakeBinder.CreateBindingReturns(serviceadapter.Binding{}, serviceadapter.NewBindingAlreadyExistsError(nil))
+
+			err := handler.Handle([]string{commandName, "create-binding"}, stdout, stderr, bytes.NewBufferString(bindingInput))
+			Expect(err).To(HaveOccurred())
+
+			_, recording := onlyRecording()
+			Expect(recording.ExitCode).To(Equal(serviceadapter.BindingAlreadyExistsErrorExitCode))
+		})
+
+		It("encrypts invocations when given a key", func() {
+			handler.RecordingKey = []byte("0123456789abcdef0123456789abcdef")
+
+			Expect(handler.Handle([]string{commandName, "create-binding"}, stdout, stderr, bytes.NewBufferString(bindingInput))).To(Succeed())
+
+			path, recording := onlyRecording()
+			Expect(recording.Invocation).To(BeNil())
+			content, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).NotTo(ContainSubstring(secret))
+
+			_, err = recording.Open(nil)
+			Expect(err).To(MatchError(ContainSubstring("key")))
+			_, err = recording.Open([]byte("fedcba9876543210fedcba9876543210"))
+			Expect(err).To(MatchError(ContainSubstring("decrypting recording")))
+
+			invocation, err := recording.Open(handler.RecordingKey)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(invocation.InputParams.CreateBinding.Secrets).To(MatchJSON(`{"/admin_password": "` + secret + `"}`))
+			Expect(invocation.Stdout).To(ContainSubstring("binding-password"))
+		})
+
+		It("does not fail invocations that cannot be recorded", func() {
+			handler.RecordDir = filepath.Join(recordDir, "file")
+			Expect(os.WriteFile(handler.RecordDir, nil, 0600)).To(Succeed())
+
+			Expect(handler.Handle([]string{commandName, "create-binding"}, stdout, stderr, bytes.NewBufferString(bindingInput))).To(Succeed())
+			Expect(stderr.String()).To(ContainSubstring("[odb-sdk] recording invocation failed"))
+		})
+
+		It("replays invocations against the current adapter", func() {
+			handler.RecordingKey = []byte("0123456789abcdef")
+			Expect(handler.Handle([]string{commandName, "create-binding"}, stdout, stderr, bytes.NewBufferString(bindingInput))).To(Succeed())
+			_, recording := onlyRecording()
+
+			result, err := handler.Replay(context.Background(), recording)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Identical()).To(BeTrue())
+			Expect(result.Diff()).To(BeEmpty())
+			Expect(fakeBinder.CreateBindingCallCount()).To(Equal(2))
+			Expect(fakeBinder.CreateBindingArgsForCall(1).Secrets).To(Equal(serviceadapter.ManifestSecrets{"/admin_password": secret}))
+
+			fakeBinder.CreateBindingReturns(serviceadapter.Binding{}, errors.New("binding failed"))
+			result, err = handler.Replay(context.Background(), recording)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Identical()).To(BeFalse())
+			Expect(result.ReplayedExitCode).To(Equal(serviceadapter.ErrorExitCode))
+			Expect(result.Diff()).To(ContainSubstring("exit code:\n- 0\n+ 1\n"))
+			Expect(result.Diff()).To(ContainSubstring("+ binding failed\n"))
+
+			paths, err := filepath.Glob(filepath.Join(recordDir, "*.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(paths).To(HaveLen(1), "replays are not recorded")
+		})
+
+		It("replays invocations of actions that are no longer implemented", func() {
+			Expect(handler.Handle([]string{commandName, "create-binding"}, stdout, stderr, bytes.NewBufferString(bindingInput))).To(Succeed())
+			_, recording := onlyRecording()
+
+			handler.Binder = nil
+			result, err := handler.Replay(context.Background(), recording)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.ReplayedExitCode).To(Equal(serviceadapter.NotImplementedExitCode))
+			Expect(result.Diff()).To(HavePrefix("exit code:\n- 0\n+ 10\n"))
+		})
+
+		It("replays invocations with positional arguments", func() {
+			Expect(handler.Handle([]string{commandName, "create-binding", bindingID, boshVMsJSON, previousManifestYAML, requestParamsJSON}, stdout, stderr, nil)).To(Succeed())
+			_, recording := onlyRecording()
+			Expect(recording.Invocation.Args).To(HaveLen(4))
+
+			fakeBinder.CreateBindingReturns(serviceadapter.Binding{Credentials: map[string]interface{}{"password": "binding-password"}, RouteServiceURL: "https://route.example.com"}, nil)
+			result, err := handler.Replay(context.Background(), recording)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Identical()).To(BeFalse())
+			Expect(result.Diff()).To(ContainSubstring("route.example.com"))
+		})
+
+		It("replays recordings with the replay subcommand", func() {
+			Expect(handler.Handle([]string{commandName, "create-binding"}, stdout, stderr, bytes.NewBufferString(bindingInput))).To(Succeed())
+			path, _ := onlyRecording()
+
+			stdout.Reset()
+			Expect(handler.Handle([]string{commandName, "replay", path}, stdout, stderr, nil)).To(Succeed())
+			Expect(stdout.String()).To(Equal("replayed create-binding: identical\n"))
+
+			fakeBinder.CreateBindingReturns(serviceadapter.Binding{}, errors.New("binding failed"))
+			stdout.Reset()
+			err := handler.Handle([]string{commandName, "replay", path}, stdout, stderr, nil)
+			Expect(err).To(BeACLIError(serviceadapter.ErrorExitCode, "replayed create-binding differs from the recording"))
+			Expect(stdout.String()).To(HavePrefix("replayed create-binding: differs\n"))
+			Expect(stdout.String()).To(ContainSubstring("+ binding failed"))
+
+			err = handler.Handle([]string{commandName, "replay"}, stdout, stderr, nil)
+			Expect(err).To(BeACLIError(serviceadapter.ErrorExitCode, "Missing arguments for replay. Usage: arbitrary-command-name replay <recording-path>"))
+		})
+	})
 })
+
+type contextManifestGenerator struct {
+	*fakes.FakeManifestGenerator
+	*fakes.FakeContextManifestGenerator
+}
+
+type contextBinder struct {
+	*fakes.FakeBinder
+	*fakes.FakeContextBinder
+}
+
+type contextDashboardUrlGenerator struct {
+	*fakes.FakeDashboardUrlGenerator
+	*fakes.FakeContextDashboardUrlGenerator
+}
+
+type contextSchemaGenerator struct {
+	*fakes.FakeSchemaGenerator
+	*fakes.FakeContextSchemaGenerator
+}
+
+type describedAction struct {
+	*fakes.FakeAction
+}
+
+func (describedAction) Capabilities() serviceadapter.ActionCapabilities {
+	return serviceadapter.ActionCapabilities{InputProtocols: []string{serviceadapter.StdinJSONProtocol}}
+}
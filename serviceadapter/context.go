@@ -0,0 +1,49 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// TimeoutEnvVar names the environment variable holding the default
+// invocation timeout, as a Go duration such as "5m".
+const TimeoutEnvVar = "ODB_SERVICE_ADAPTER_TIMEOUT"
+
+func withInvocationTimeout(ctx context.Context, inputParams InputParams) (context.Context, context.CancelFunc, error) {
+	timeout := inputParams.Timeout
+	if timeout == "" {
+		timeout = os.Getenv(TimeoutEnvVar)
+	}
+	if timeout == "" {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, nil
+	}
+
+	duration, err := time.ParseDuration(timeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid timeout %q: %s", timeout, err)
+	}
+	if duration <= 0 {
+		return nil, nil, fmt.Errorf("invalid timeout %q: must be positive", timeout)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	return ctx, cancel, nil
+}
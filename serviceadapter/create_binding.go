@@ -1,8 +1,8 @@
 package serviceadapter
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 
 	"github.com/pkg/errors"
@@ -12,7 +12,8 @@ import (
 )
 
 type CreateBindingAction struct {
-	bindingCreator Binder
+	bindingCreator  Binder
+	paramsValidator *requestParametersValidator
 }
 
 func NewCreateBindingAction(binder Binder) *CreateBindingAction {
@@ -22,10 +23,27 @@ func NewCreateBindingAction(binder Binder) *CreateBindingAction {
 	return &action
 }
 
+// WithRequestParametersValidation makes the action validate and default the
+// arbitrary request parameters against the binding schema schemaGenerator
+// returns. ODB does not pass the plan to create-binding, so the schema is
+// generated for the zero Plan: a schemaGenerator that reads the plan's
+// properties or instance groups sees none.
+func (a *CreateBindingAction) WithRequestParametersValidation(schemaGenerator SchemaGenerator) *CreateBindingAction {
+	a.paramsValidator = &requestParametersValidator{schemaGenerator: schemaGenerator}
+	return a
+}
+
 func (a *CreateBindingAction) IsImplemented() bool {
 	return a.bindingCreator != nil
 }
 
+func (a *CreateBindingAction) Capabilities() ActionCapabilities {
+	return ActionCapabilities{
+		InputProtocols: []string{PositionalArgsProtocol, StdinJSONProtocol},
+		OptionalFields: []string{"secrets", "dns_addresses", "timeout"},
+	}
+}
+
 func (a *CreateBindingAction) ParseArgs(reader io.Reader, args []string) (InputParams, error) {
 	var inputParams InputParams
 
@@ -45,24 +63,14 @@ func (a *CreateBindingAction) ParseArgs(reader io.Reader, args []string) (InputP
 		return inputParams, nil
 	}
 
-	data, err := io.ReadAll(reader)
-	if err != nil {
-		return inputParams, CLIHandlerError{ErrorExitCode, fmt.Sprintf("error reading input params JSON, error: %s", err)}
-	}
-
-	if len(data) > 0 {
-		err = json.Unmarshal(data, &inputParams)
-		if err != nil {
-			return inputParams, CLIHandlerError{ErrorExitCode, fmt.Sprintf("error unmarshalling input params JSON, error: %s", err)}
-		}
-
-		return inputParams, err
-	}
-
-	return inputParams, CLIHandlerError{ErrorExitCode, "expecting parameters to be passed via stdin"}
+	return ReadInputParams(reader)
 }
 
 func (a *CreateBindingAction) Execute(inputParams InputParams, outputWriter io.Writer) error {
+	return a.ExecuteWithContext(context.Background(), inputParams, outputWriter)
+}
+
+func (a *CreateBindingAction) ExecuteWithContext(ctx context.Context, inputParams InputParams, outputWriter io.Writer) error {
 	var boshVMs map[string][]string
 	if err := json.Unmarshal([]byte(inputParams.CreateBinding.BoshVms), &boshVMs); err != nil {
 		return errors.Wrap(err, "unmarshalling BOSH VMs")
@@ -84,6 +92,8 @@ func (a *CreateBindingAction) Execute(inputParams InputParams, outputWriter io.W
 			return errors.Wrap(err, "unmarshalling secrets")
 		}
 	}
+	redactor := RedactorFromContext(ctx)
+	redactor.AddValues(secrets)
 
 	var dnsAddresses DNSAddresses
 	if inputParams.CreateBinding.DNSAddresses != "" {
@@ -92,6 +102,13 @@ func (a *CreateBindingAction) Execute(inputParams InputParams, outputWriter io.W
 		}
 	}
 
+	if a.paramsValidator != nil {
+		var err error
+		if reqParams, err = a.paramsValidator.validate(ctx, Plan{}, bindingCreateSchema, reqParams, true); err != nil {
+			return adapterFailure(err, outputWriter, ErrorExitCode)
+		}
+	}
+
 	params := CreateBindingParams{
 		BindingID:          inputParams.CreateBinding.BindingId,
 		DeploymentTopology: boshVMs,
@@ -100,16 +117,16 @@ func (a *CreateBindingAction) Execute(inputParams InputParams, outputWriter io.W
 		Secrets:            secrets,
 		DNSAddresses:       dnsAddresses,
 	}
-	binding, err := a.bindingCreator.CreateBinding(params)
+	binding, err := a.createBinding(ctx, params)
+	redactor.AddValues(binding.Credentials)
 	if err != nil {
-		fmt.Fprint(outputWriter, err.Error())
 		switch err := err.(type) {
 		case BindingAlreadyExistsError:
-			return CLIHandlerError{BindingAlreadyExistsErrorExitCode, err.Error()}
+			return adapterFailure(err, outputWriter, BindingAlreadyExistsErrorExitCode)
 		case AppGuidNotProvidedError:
-			return CLIHandlerError{AppGuidNotProvidedErrorExitCode, err.Error()}
+			return adapterFailure(err, outputWriter, AppGuidNotProvidedErrorExitCode)
 		default:
-			return CLIHandlerError{ErrorExitCode, err.Error()}
+			return adapterFailure(err, outputWriter, ErrorExitCode)
 		}
 	}
 
@@ -119,3 +136,10 @@ func (a *CreateBindingAction) Execute(inputParams InputParams, outputWriter io.W
 
 	return nil
 }
+
+func (a *CreateBindingAction) createBinding(ctx context.Context, params CreateBindingParams) (Binding, error) {
+	if binder, ok := a.bindingCreator.(ContextBinder); ok {
+		return binder.CreateBindingWithContext(ctx, params)
+	}
+	return a.bindingCreator.CreateBinding(params)
+}
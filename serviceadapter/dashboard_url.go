@@ -1,8 +1,8 @@
 package serviceadapter
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 
 	"github.com/pkg/errors"
@@ -25,6 +25,13 @@ func (d *DashboardUrlAction) IsImplemented() bool {
 	return d.dashboardUrlGenerator != nil
 }
 
+func (d *DashboardUrlAction) Capabilities() ActionCapabilities {
+	return ActionCapabilities{
+		InputProtocols: []string{PositionalArgsProtocol, StdinJSONProtocol},
+		OptionalFields: []string{"timeout"},
+	}
+}
+
 func (d *DashboardUrlAction) ParseArgs(reader io.Reader, args []string) (InputParams, error) {
 	var inputParams InputParams
 
@@ -43,24 +50,14 @@ func (d *DashboardUrlAction) ParseArgs(reader io.Reader, args []string) (InputPa
 		return inputParams, nil
 	}
 
-	data, err := io.ReadAll(reader)
-	if err != nil {
-		return inputParams, CLIHandlerError{ErrorExitCode, fmt.Sprintf("error reading input params JSON, error: %s", err)}
-	}
-
-	if len(data) > 0 {
-		err = json.Unmarshal(data, &inputParams)
-		if err != nil {
-			return inputParams, CLIHandlerError{ErrorExitCode, fmt.Sprintf("error unmarshalling input params JSON, error: %s", err)}
-		}
-
-		return inputParams, err
-	}
-
-	return inputParams, CLIHandlerError{ErrorExitCode, "expecting parameters to be passed via stdin"}
+	return ReadInputParams(reader)
 }
 
 func (d *DashboardUrlAction) Execute(inputParams InputParams, outputWriter io.Writer) error {
+	return d.ExecuteWithContext(context.Background(), inputParams, outputWriter)
+}
+
+func (d *DashboardUrlAction) ExecuteWithContext(ctx context.Context, inputParams InputParams, outputWriter io.Writer) error {
 	var plan Plan
 	if err := json.Unmarshal([]byte(inputParams.DashboardUrl.Plan), &plan); err != nil {
 		return errors.Wrap(err, "unmarshalling service plan")
@@ -79,10 +76,9 @@ func (d *DashboardUrlAction) Execute(inputParams InputParams, outputWriter io.Wr
 		Plan:       plan,
 		Manifest:   manifest,
 	}
-	dashboardUrl, err := d.dashboardUrlGenerator.DashboardUrl(params)
+	dashboardUrl, err := d.dashboardUrl(ctx, params)
 	if err != nil {
-		fmt.Fprint(outputWriter, err.Error())
-		return CLIHandlerError{ErrorExitCode, err.Error()}
+		return adapterFailure(err, outputWriter, ErrorExitCode)
 	}
 
 	if err := json.NewEncoder(outputWriter).Encode(dashboardUrl); err != nil {
@@ -91,3 +87,10 @@ func (d *DashboardUrlAction) Execute(inputParams InputParams, outputWriter io.Wr
 
 	return nil
 }
+
+func (d *DashboardUrlAction) dashboardUrl(ctx context.Context, params DashboardUrlParams) (DashboardUrl, error) {
+	if generator, ok := d.dashboardUrlGenerator.(ContextDashboardUrlGenerator); ok {
+		return generator.DashboardUrlWithContext(ctx, params)
+	}
+	return d.dashboardUrlGenerator.DashboardUrl(params)
+}
@@ -0,0 +1,458 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParameterError describes a request parameter that could not be decoded.
+// Parameter is the dotted path to it, e.g. backup.retention or plugins.0
+type ParameterError struct {
+	Parameter string
+	Message   string
+}
+
+func (e ParameterError) Error() string {
+	if e.Parameter == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Parameter, e.Message)
+}
+
+type ParameterErrors []ParameterError
+
+func (e ParameterErrors) Error() string {
+	var messages []string
+	for _, parameterError := range e {
+		messages = append(messages, parameterError.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+type DecodeOption func(*parameterDecoder)
+
+// RejectUnknownFields makes decoding fail for parameters that do not match
+// a field of the target struct. By default they are ignored.
+func RejectUnknownFields() DecodeOption {
+	return func(d *parameterDecoder) {
+		d.rejectUnknownFields = true
+	}
+}
+
+// CoerceStrings makes decoding accept strings such as "10" and "true" for
+// numeric and boolean fields.
+func CoerceStrings() DecodeOption {
+	return func(d *parameterDecoder) {
+		d.coerceStrings = true
+	}
+}
+
+// DecodeParameters decodes the arbitrary parameters into a T. Parameters
+// that do not fit T are reported as an InvalidParametersError wrapping
+// ParameterErrors, which an adapter can return as is to show them to the
+// user.
+//
+// Fields are matched by their json tags. Numbers must fit the field they
+// are decoded into, so 1.5 is rejected for an int field. Types implementing
+// json.Unmarshaler are decoded with it, and time.Duration fields also accept
+// strings such as "30s".
+func DecodeParameters[T any](params RequestParameters, opts ...DecodeOption) (T, error) {
+	return decodeRequestParameter[T](params, "parameters", opts)
+}
+
+// DecodeContext decodes the context of the request into a T, see
+// DecodeParameters.
+func DecodeContext[T any](params RequestParameters, opts ...DecodeOption) (T, error) {
+	return decodeRequestParameter[T](params, "context", opts)
+}
+
+// DecodeBindResource decodes the bind_resource of the request into a T, see
+// DecodeParameters.
+func DecodeBindResource[T any](params RequestParameters, opts ...DecodeOption) (T, error) {
+	return decodeRequestParameter[T](params, "bind_resource", opts)
+}
+
+func decodeRequestParameter[T any](params RequestParameters, key string, opts []DecodeOption) (T, error) {
+	var result T
+	err := decodeInto(params[key], &result, opts)
+	return result, err
+}
+
+// decodeInto decodes value, as found in RequestParameters, into target,
+// which must be a pointer.
+func decodeInto(value interface{}, target interface{}, opts []DecodeOption) error {
+	d := &parameterDecoder{}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	normalized, err := normalizeJSON(value)
+	if err != nil {
+		return NewInvalidParametersError(ParameterErrors{{Message: err.Error()}})
+	}
+	if normalized == nil {
+		normalized = map[string]interface{}{}
+	}
+
+	d.decode("", normalized, reflect.ValueOf(target).Elem())
+	if len(d.errors) > 0 {
+		return NewInvalidParametersError(d.errors)
+	}
+	return nil
+}
+
+type parameterDecoder struct {
+	rejectUnknownFields bool
+	coerceStrings       bool
+	errors              ParameterErrors
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+func (d *parameterDecoder) fail(path, format string, args ...interface{}) {
+	d.errors = append(d.errors, ParameterError{Parameter: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (d *parameterDecoder) decode(path string, value interface{}, target reflect.Value) {
+	t := target.Type()
+
+	if value == nil {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			target.Set(reflect.Zero(t))
+		}
+		return
+	}
+
+	if t.Kind() == reflect.Ptr {
+		if target.IsNil() {
+			target.Set(reflect.New(t.Elem()))
+		}
+		d.decode(path, value, target.Elem())
+		return
+	}
+
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		valueJSON, _ := json.Marshal(value)
+		if err := json.Unmarshal(valueJSON, target.Addr().Interface()); err != nil {
+			d.fail(path, "%s", err)
+		}
+		return
+	}
+
+	if t == durationType {
+		if s, ok := value.(string); ok {
+			duration, err := time.ParseDuration(s)
+			if err != nil {
+				d.fail(path, "expected a duration such as 30s, got %q", s)
+				return
+			}
+			target.SetInt(int64(duration))
+			return
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		if t.NumMethod() > 0 {
+			d.fail(path, "cannot decode into %s", t)
+			return
+		}
+		target.Set(reflect.ValueOf(denormalizeJSON(value)))
+
+	case reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			d.fail(path, "expected string, got %s", describeJSONType(value))
+			return
+		}
+		target.SetString(s)
+
+	case reflect.Bool:
+		switch v := value.(type) {
+		case bool:
+			target.SetBool(v)
+		case string:
+			b, err := strconv.ParseBool(v)
+			if !d.coerceStrings || err != nil {
+				d.fail(path, "expected boolean, got %s", describeJSONType(value))
+				return
+			}
+			target.SetBool(b)
+		default:
+			d.fail(path, "expected boolean, got %s", describeJSONType(value))
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		d.decodeNumber(path, value, target)
+
+	case reflect.Slice, reflect.Array:
+		array, ok := value.([]interface{})
+		if !ok {
+			d.fail(path, "expected array, got %s", describeJSONType(value))
+			return
+		}
+		if t.Kind() == reflect.Array {
+			if len(array) != t.Len() {
+				d.fail(path, "expected an array of %d items, got %d", t.Len(), len(array))
+				return
+			}
+		} else {
+			target.Set(reflect.MakeSlice(t, len(array), len(array)))
+		}
+		for i, item := range array {
+			d.decode(joinPath(path, strconv.Itoa(i)), item, target.Index(i))
+		}
+
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			d.fail(path, "expected object, got %s", describeJSONType(value))
+			return
+		}
+		if t.Key().Kind() != reflect.String {
+			d.fail(path, "cannot decode into map with %s keys", t.Key())
+			return
+		}
+		if target.IsNil() {
+			target.Set(reflect.MakeMapWithSize(t, len(object)))
+		}
+		for _, key := range sortedMapKeys(object) {
+			element := reflect.New(t.Elem()).Elem()
+			d.decode(joinPath(path, key), object[key], element)
+			target.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), element)
+		}
+
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			d.fail(path, "expected object, got %s", describeJSONType(value))
+			return
+		}
+		d.decodeStruct(path, object, target)
+
+	default:
+		d.fail(path, "cannot decode into %s", t)
+	}
+}
+
+func (d *parameterDecoder) decodeStruct(path string, object map[string]interface{}, target reflect.Value) {
+	fields := map[string]structField{}
+	for _, field := range structFields(target.Type()) {
+		fields[field.name] = field
+	}
+
+	for _, key := range sortedMapKeys(object) {
+		field, ok := fields[key]
+		if !ok {
+			// encoding/json falls back to a case-insensitive match
+			for name, candidate := range fields {
+				if strings.EqualFold(name, key) {
+					field, ok = candidate, true
+					break
+				}
+			}
+		}
+		if !ok {
+			if d.rejectUnknownFields {
+				d.fail(joinPath(path, key), "unknown parameter")
+			}
+			continue
+		}
+		d.decode(joinPath(path, key), object[key], fieldByIndex(target, field.index))
+	}
+}
+
+func (d *parameterDecoder) decodeNumber(path string, value interface{}, target reflect.Value) {
+	t := target.Type()
+	kind := "number"
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+	default:
+		kind = "integer"
+	}
+
+	var number json.Number
+	switch v := value.(type) {
+	case json.Number:
+		number = v
+	case string:
+		if !d.coerceStrings {
+			d.fail(path, "expected %s, got %s", kind, describeJSONType(value))
+			return
+		}
+		number = json.Number(strings.TrimSpace(v))
+	default:
+		d.fail(path, "expected %s, got %s", kind, describeJSONType(value))
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(string(number), t.Bits())
+		if err != nil {
+			d.fail(path, "expected number, got %q", string(number))
+			return
+		}
+		target.SetFloat(f)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		whole, ok := parseWholeNumber(number)
+		if !ok {
+			d.fail(path, "expected integer, got %s", string(number))
+			return
+		}
+		if !whole.IsInt64() || target.OverflowInt(whole.Int64()) {
+			d.fail(path, "%s is out of range", string(number))
+			return
+		}
+		target.SetInt(whole.Int64())
+
+	default:
+		whole, ok := parseWholeNumber(number)
+		if !ok || whole.Sign() < 0 {
+			d.fail(path, "expected non-negative integer, got %s", string(number))
+			return
+		}
+		if !whole.IsUint64() || target.OverflowUint(whole.Uint64()) {
+			d.fail(path, "%s is out of range", string(number))
+			return
+		}
+		target.SetUint(whole.Uint64())
+	}
+}
+
+// parseWholeNumber parses number as an integer. Numbers such as 3.0 and 1e3
+// are accepted as they are whole.
+func parseWholeNumber(number json.Number) (*big.Int, bool) {
+	f, _, err := big.ParseFloat(string(number), 10, 256, big.ToNearestEven)
+	if err != nil || !f.IsInt() {
+		return nil, false
+	}
+	whole, _ := f.Int(nil)
+	return whole, true
+}
+
+type structField struct {
+	name  string
+	index []int
+	field reflect.StructField
+}
+
+// structFields returns the fields encoding/json would use for t, including
+// those of embedded structs, named as encoding/json would name them.
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for _, embeddedField := range structFields(embedded) {
+					embeddedField.index = append([]int{i}, embeddedField.index...)
+					fields = append(fields, embeddedField)
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, structField{name: name, index: []int{i}, field: field})
+	}
+	return fields
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex, but allocates nil
+// embedded struct pointers on the way.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, fieldIndex := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(fieldIndex)
+	}
+	return v
+}
+
+// normalizeJSON converts value to the types encoding/json decodes into, with
+// numbers as json.Number so that large integers keep their precision.
+func normalizeJSON(value interface{}) (interface{}, error) {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(valueJSON))
+	decoder.UseNumber()
+	var normalized interface{}
+	err = decoder.Decode(&normalized)
+	return normalized, err
+}
+
+// denormalizeJSON converts the json.Numbers in value back to float64s.
+func denormalizeJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, element := range v {
+			v[key] = denormalizeJSON(element)
+		}
+	case []interface{}:
+		for i, element := range v {
+			v[i] = denormalizeJSON(element)
+		}
+	}
+	return value
+}
+
+func describeJSONType(value interface{}) string {
+	switch v := value.(type) {
+	case json.Number:
+		return "number " + string(v)
+	case string:
+		return fmt.Sprintf("string %q", v)
+	}
+	return jsonType(value)
+}
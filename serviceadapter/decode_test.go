@@ -0,0 +1,187 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter_test
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+type decodedParams struct {
+	Name     string            `json:"name"`
+	Replicas uint8             `json:"replicas"`
+	Memory   int64             `json:"memory"`
+	Ratio    float64           `json:"ratio"`
+	Enabled  *bool             `json:"enabled"`
+	Timeout  time.Duration     `json:"timeout"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels"`
+	Backup   struct {
+		Schedule string `json:"schedule"`
+	} `json:"backup"`
+	Raw     interface{}     `json:"raw"`
+	RawJSON json.RawMessage `json:"raw_json"`
+}
+
+type decodedContext struct {
+	Platform  string `json:"platform"`
+	SpaceGUID string `json:"space_guid"`
+}
+
+type decodedBindResource struct {
+	AppGUID string `json:"app_guid"`
+}
+
+var _ = Describe("Decoding RequestParameters", func() {
+	paramsFrom := func(requestJSON string) serviceadapter.RequestParameters {
+		var params serviceadapter.RequestParameters
+		Expect(json.Unmarshal([]byte(requestJSON), &params)).To(Succeed())
+		return params
+	}
+
+	It("decodes parameters into a struct", func() {
+		params := paramsFrom(`{"parameters": {
+			"name": "redis",
+			"replicas": 3,
+			"memory": 4294967296,
+			"ratio": 0.5,
+			"enabled": false,
+			"timeout": "1m30s",
+			"tags": ["a", "b"],
+			"labels": {"team": "data"},
+			"backup": {"schedule": "@daily"},
+			"raw": {"n": 1},
+			"raw_json": [1, 2]
+		}}`)
+
+		decoded, err := serviceadapter.DecodeParameters[decodedParams](params)
+		Expect(err).NotTo(HaveOccurred())
+
+		enabled := false
+		expected := decodedParams{
+			Name:     "redis",
+			Replicas: 3,
+			Memory:   4294967296,
+			Ratio:    0.5,
+			Enabled:  &enabled,
+			Timeout:  90 * time.Second,
+			Tags:     []string{"a", "b"},
+			Labels:   map[string]string{"team": "data"},
+			Raw:      map[string]interface{}{"n": float64(1)},
+			RawJSON:  json.RawMessage(`[1,2]`),
+		}
+		expected.Backup.Schedule = "@daily"
+		Expect(decoded).To(Equal(expected))
+	})
+
+	It("decodes the context and bind resource", func() {
+		params := paramsFrom(`{
+			"context": {"platform": "cloudfoundry", "space_guid": "space"},
+			"bind_resource": {"app_guid": "app"}
+		}`)
+
+		context, err := serviceadapter.DecodeContext[decodedContext](params)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(context).To(Equal(decodedContext{Platform: "cloudfoundry", SpaceGUID: "space"}))
+
+		bindResource, err := serviceadapter.DecodeBindResource[decodedBindResource](params)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bindResource).To(Equal(decodedBindResource{AppGUID: "app"}))
+	})
+
+	It("returns the zero value when the parameters are absent", func() {
+		decoded, err := serviceadapter.DecodeParameters[decodedParams](serviceadapter.RequestParameters{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decoded).To(Equal(decodedParams{}))
+	})
+
+	It("accepts whole numbers written as floats", func() {
+		decoded, err := serviceadapter.DecodeParameters[decodedParams](paramsFrom(`{"parameters": {"replicas": 3.0, "memory": 1e3}}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decoded.Replicas).To(BeEquivalentTo(3))
+		Expect(decoded.Memory).To(BeEquivalentTo(1000))
+	})
+
+	It("reports every parameter that does not fit, by name", func() {
+		_, err := serviceadapter.DecodeParameters[decodedParams](paramsFrom(`{"parameters": {
+			"name": 1,
+			"replicas": 256,
+			"memory": 1.5,
+			"enabled": "yes",
+			"timeout": "soon",
+			"tags": ["a", 2],
+			"backup": {"schedule": false}
+		}}`))
+
+		Expect(err).To(MatchError(
+			"invalid parameters: " +
+				"backup.schedule: expected string, got boolean; " +
+				"enabled: expected boolean, got string \"yes\"; " +
+				"memory: expected integer, got 1.5; " +
+				"name: expected string, got number 1; " +
+				"replicas: 256 is out of range; " +
+				"tags.1: expected string, got number 2; " +
+				"timeout: expected a duration such as 30s, got \"soon\"",
+		))
+
+		var invalidParametersError serviceadapter.InvalidParametersError
+		Expect(errors.As(err, &invalidParametersError)).To(BeTrue())
+
+		var parameterErrors serviceadapter.ParameterErrors
+		Expect(errors.As(err, &parameterErrors)).To(BeTrue())
+		Expect(parameterErrors[0]).To(Equal(serviceadapter.ParameterError{Parameter: "backup.schedule", Message: "expected string, got boolean"}))
+	})
+
+	It("rejects parameters that are not an object", func() {
+		_, err := serviceadapter.DecodeParameters[decodedParams](serviceadapter.RequestParameters{"parameters": "redis"})
+		Expect(err).To(MatchError(`invalid parameters: expected object, got string "redis"`))
+	})
+
+	It("ignores unknown parameters by default and rejects them in strict mode", func() {
+		params := paramsFrom(`{"parameters": {"name": "redis", "nmae": "typo", "backup": {"when": "now"}}}`)
+
+		_, err := serviceadapter.DecodeParameters[decodedParams](params)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = serviceadapter.DecodeParameters[decodedParams](params, serviceadapter.RejectUnknownFields())
+		Expect(err).To(MatchError("invalid parameters: backup.when: unknown parameter; nmae: unknown parameter"))
+	})
+
+	It("coerces strings into numbers and booleans when asked to", func() {
+		params := paramsFrom(`{"parameters": {"replicas": "3", "ratio": "0.25", "enabled": "true"}}`)
+
+		_, err := serviceadapter.DecodeParameters[decodedParams](params)
+		Expect(err).To(MatchError(ContainSubstring(`replicas: expected integer, got string "3"`)))
+
+		decoded, err := serviceadapter.DecodeParameters[decodedParams](params, serviceadapter.CoerceStrings())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decoded.Replicas).To(BeEquivalentTo(3))
+		Expect(decoded.Ratio).To(Equal(0.25))
+		Expect(*decoded.Enabled).To(BeTrue())
+	})
+
+	It("can decode into maps", func() {
+		decoded, err := serviceadapter.DecodeParameters[map[string]int](paramsFrom(`{"parameters": {"a": 1, "b": 2}}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decoded).To(Equal(map[string]int{"a": 1, "b": 2}))
+	})
+})
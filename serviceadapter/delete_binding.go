@@ -1,8 +1,8 @@
 package serviceadapter
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 
 	"github.com/pkg/errors"
@@ -25,6 +25,13 @@ func (d *DeleteBindingAction) IsImplemented() bool {
 	return d.unbinder != nil
 }
 
+func (d *DeleteBindingAction) Capabilities() ActionCapabilities {
+	return ActionCapabilities{
+		InputProtocols: []string{PositionalArgsProtocol, StdinJSONProtocol},
+		OptionalFields: []string{"secrets", "dns_addresses", "timeout"},
+	}
+}
+
 func (d *DeleteBindingAction) ParseArgs(reader io.Reader, args []string) (InputParams, error) {
 	var inputParams InputParams
 
@@ -44,24 +51,14 @@ func (d *DeleteBindingAction) ParseArgs(reader io.Reader, args []string) (InputP
 		return inputParams, nil
 	}
 
-	data, err := io.ReadAll(reader)
-	if err != nil {
-		return inputParams, CLIHandlerError{ErrorExitCode, fmt.Sprintf("error reading input params JSON, error: %s", err)}
-	}
-
-	if len(data) > 0 {
-		err = json.Unmarshal(data, &inputParams)
-		if err != nil {
-			return inputParams, CLIHandlerError{ErrorExitCode, fmt.Sprintf("error unmarshalling input params JSON, error: %s", err)}
-		}
-
-		return inputParams, err
-	}
-
-	return inputParams, CLIHandlerError{ErrorExitCode, "expecting parameters to be passed via stdin"}
+	return ReadInputParams(reader)
 }
 
 func (d *DeleteBindingAction) Execute(inputParams InputParams, outputWriter io.Writer) error {
+	return d.ExecuteWithContext(context.Background(), inputParams, outputWriter)
+}
+
+func (d *DeleteBindingAction) ExecuteWithContext(ctx context.Context, inputParams InputParams, outputWriter io.Writer) error {
 	var boshVMs map[string][]string
 	if err := json.Unmarshal([]byte(inputParams.DeleteBinding.BoshVms), &boshVMs); err != nil {
 		return errors.Wrap(err, "unmarshalling BOSH VMs")
@@ -83,6 +80,7 @@ func (d *DeleteBindingAction) Execute(inputParams InputParams, outputWriter io.W
 			return errors.Wrap(err, "unmarshalling secrets")
 		}
 	}
+	RedactorFromContext(ctx).AddValues(secrets)
 
 	var dnsAddresses DNSAddresses
 	if inputParams.DeleteBinding.DNSAddresses != "" {
@@ -99,16 +97,22 @@ func (d *DeleteBindingAction) Execute(inputParams InputParams, outputWriter io.W
 		Secrets:            secrets,
 		DNSAddresses:       dnsAddresses,
 	}
-	err := d.unbinder.DeleteBinding(params)
+	err := d.deleteBinding(ctx, params)
 	if err != nil {
-		fmt.Fprint(outputWriter, err.Error())
 		switch err.(type) {
 		case BindingNotFoundError:
-			return CLIHandlerError{BindingNotFoundErrorExitCode, err.Error()}
+			return adapterFailure(err, outputWriter, BindingNotFoundErrorExitCode)
 		default:
-			return CLIHandlerError{ErrorExitCode, err.Error()}
+			return adapterFailure(err, outputWriter, ErrorExitCode)
 		}
 	}
 
 	return nil
 }
+
+func (d *DeleteBindingAction) deleteBinding(ctx context.Context, params DeleteBindingParams) error {
+	if unbinder, ok := d.unbinder.(ContextBinder); ok {
+		return unbinder.DeleteBindingWithContext(ctx, params)
+	}
+	return d.unbinder.DeleteBinding(params)
+}
@@ -17,10 +17,12 @@ package serviceadapter
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"slices"
 
 	"code.cloudfoundry.org/brokerapi/v13/domain"
 	"github.com/go-playground/validator/v10"
@@ -45,6 +47,16 @@ type ManifestGenerator interface {
 	GenerateManifest(params GenerateManifestParams) (GenerateManifestOutput, error)
 }
 
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -o fakes/context_manifest_generator.go . ContextManifestGenerator
+
+// ContextManifestGenerator can be implemented alongside ManifestGenerator.
+// When it is, the handler calls GenerateManifestWithContext instead of
+// GenerateManifest, passing a context that is cancelled on SIGINT/SIGTERM
+// or when the invocation timeout expires.
+type ContextManifestGenerator interface {
+	GenerateManifestWithContext(ctx context.Context, params GenerateManifestParams) (GenerateManifestOutput, error)
+}
+
 type CreateBindingParams struct {
 	BindingID          string
 	DeploymentTopology bosh.BoshVMs
@@ -70,6 +82,15 @@ type Binder interface {
 	DeleteBinding(params DeleteBindingParams) error
 }
 
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -o fakes/context_binder.go . ContextBinder
+
+// ContextBinder is the context-aware counterpart of Binder. See
+// ContextManifestGenerator.
+type ContextBinder interface {
+	CreateBindingWithContext(ctx context.Context, params CreateBindingParams) (Binding, error)
+	DeleteBindingWithContext(ctx context.Context, params DeleteBindingParams) error
+}
+
 type DashboardUrlParams struct {
 	InstanceID string
 	Plan       Plan
@@ -82,6 +103,14 @@ type DashboardUrlGenerator interface {
 	DashboardUrl(params DashboardUrlParams) (DashboardUrl, error)
 }
 
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -o fakes/context_dashboard_url_generator.go . ContextDashboardUrlGenerator
+
+// ContextDashboardUrlGenerator is the context-aware counterpart of
+// DashboardUrlGenerator. See ContextManifestGenerator.
+type ContextDashboardUrlGenerator interface {
+	DashboardUrlWithContext(ctx context.Context, params DashboardUrlParams) (DashboardUrl, error)
+}
+
 type GeneratePlanSchemaParams struct {
 	Plan Plan
 }
@@ -92,6 +121,14 @@ type SchemaGenerator interface {
 	GeneratePlanSchema(params GeneratePlanSchemaParams) (PlanSchema, error)
 }
 
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -o fakes/context_schema_generator.go . ContextSchemaGenerator
+
+// ContextSchemaGenerator is the context-aware counterpart of SchemaGenerator.
+// See ContextManifestGenerator.
+type ContextSchemaGenerator interface {
+	GeneratePlanSchemaWithContext(ctx context.Context, params GeneratePlanSchemaParams) (PlanSchema, error)
+}
+
 type ServiceInstanceSchema struct {
 	Create JSONSchemas `json:"create"`
 	Update JSONSchemas `json:"update"`
@@ -164,7 +201,79 @@ type InputParams struct {
 	CreateBinding       CreateBindingJSONParams       `json:"create_binding,omitempty"`
 	DeleteBinding       DeleteBindingJSONParams       `json:"delete_binding,omitempty"`
 	GeneratePlanSchemas GeneratePlanSchemasJSONParams `json:"generate_plan_schemas,omitempty"`
-	TextOutput          bool                          `json:"-"`
+	// Timeout bounds the invocation, as a Go duration such as "90s". It
+	// takes precedence over the TimeoutEnvVar environment variable.
+	Timeout    string `json:"timeout,omitempty"`
+	TextOutput bool   `json:"-"`
+	// Custom holds the input params of actions registered with
+	// CommandLineHandler.RegisterAction, keyed by action name.
+	Custom map[string]json.RawMessage `json:"-"`
+}
+
+type inputParamsJSON InputParams
+
+var knownInputParams = []string{"generate_manifest", "dashboard_url", "create_binding", "delete_binding", "generate_plan_schemas", "timeout"}
+
+// UnmarshalJSON collects the input params of custom actions, which are not
+// known to InputParams, in Custom.
+func (p *InputParams) UnmarshalJSON(data []byte) error {
+	var params inputParamsJSON
+	if err := json.Unmarshal(data, &params); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for _, known := range knownInputParams {
+		delete(fields, known)
+	}
+	if len(fields) > 0 {
+		params.Custom = fields
+	}
+
+	*p = InputParams(params)
+	return nil
+}
+
+func (p InputParams) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(inputParamsJSON(p))
+	if err != nil || len(p.Custom) == 0 {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, custom := range p.Custom {
+		if slices.Contains(knownInputParams, name) {
+			return nil, fmt.Errorf("custom input params %q clash with a known field", name)
+		}
+		fields[name] = custom
+	}
+	return json.Marshal(fields)
+}
+
+// ReadInputParams reads input params passed as JSON via stdin, failing as
+// the built-in actions do when there are none. Custom actions can use it to
+// implement ParseArgs, and find their own input params in Custom.
+func ReadInputParams(reader io.Reader) (InputParams, error) {
+	var inputParams InputParams
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return inputParams, CLIHandlerError{ErrorExitCode, fmt.Sprintf("error reading input params JSON, error: %s", err)}
+	}
+	if len(data) <= 0 {
+		return inputParams, CLIHandlerError{ErrorExitCode, "expecting parameters to be passed via stdin"}
+	}
+
+	if err = json.Unmarshal(data, &inputParams); err != nil {
+		return inputParams, CLIHandlerError{ErrorExitCode, fmt.Sprintf("error unmarshalling input params JSON, error: %s", err)}
+	}
+	return inputParams, nil
 }
 
 type (
@@ -208,12 +317,27 @@ type BindingNotFoundError struct {
 	error
 }
 
+// InvalidParametersError reports arbitrary request parameters that the user
+// needs to correct. Its message is shown to the user. As ODB defines no exit
+// code for it, the action fails with ErrorExitCode.
+type InvalidParametersError struct {
+	error
+}
+
+//go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -o fakes/action.go . Action
 type Action interface {
 	IsImplemented() bool
 	ParseArgs(io.Reader, []string) (InputParams, error)
 	Execute(InputParams, io.Writer) error
 }
 
+// ContextAction is an Action that can be cancelled. The handler prefers
+// ExecuteWithContext over Execute when an action implements it.
+type ContextAction interface {
+	Action
+	ExecuteWithContext(context.Context, InputParams, io.Writer) error
+}
+
 func NewBindingAlreadyExistsError(err error) BindingAlreadyExistsError {
 	return BindingAlreadyExistsError{error: fmt.Errorf("binding already exists: %s", err)}
 }
@@ -226,20 +350,32 @@ func NewBindingNotFoundError(err error) BindingNotFoundError {
 	return BindingNotFoundError{error: fmt.Errorf("binding not found: %s", err)}
 }
 
+func NewInvalidParametersError(err error) InvalidParametersError {
+	return InvalidParametersError{error: fmt.Errorf("invalid parameters: %w", err)}
+}
+
+func (e InvalidParametersError) Unwrap() error {
+	return errors.Unwrap(e.error)
+}
+
 type RequestParameters map[string]interface{}
 
+// ArbitraryParams returns the arbitrary parameters, or an empty map if they
+// are missing or not an object. Use DecodeParameters to tell these apart.
 func (s RequestParameters) ArbitraryParams() map[string]interface{} {
-	if s["parameters"] == nil {
-		return map[string]interface{}{}
+	if params, ok := s["parameters"].(map[string]interface{}); ok {
+		return params
 	}
-	return s["parameters"].(map[string]interface{})
+	return map[string]interface{}{}
 }
 
+// ArbitraryContext returns the context, or an empty map if it is missing or
+// not an object. Use DecodeContext to tell these apart.
 func (s RequestParameters) ArbitraryContext() map[string]interface{} {
-	if s["context"] == nil {
-		return map[string]interface{}{}
+	if context, ok := s["context"].(map[string]interface{}); ok {
+		return context
 	}
-	return s["context"].(map[string]interface{})
+	return map[string]interface{}{}
 }
 
 func (s RequestParameters) Platform() string {
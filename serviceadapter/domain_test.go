@@ -45,6 +45,13 @@ var _ = Describe("Domain", func() {
 			})
 		})
 
+		Context("when arbitraryParams are not an object", func() {
+			It("arbitrary params are empty", func() {
+				params := serviceadapter.RequestParameters{"parameters": "foo"}
+				Expect(params.ArbitraryParams()).To(Equal(map[string]interface{}{}))
+			})
+		})
+
 		Context("when bindResource is present", func() {
 			It("can extract bindResource", func() {
 				params := serviceadapter.RequestParameters{"bind_resource": map[string]interface{}{"app_guid": "foo", "backup_agent": true}}
@@ -72,6 +79,11 @@ var _ = Describe("Domain", func() {
 				Expect(params.ArbitraryContext()).To(Equal(map[string]interface{}{}))
 			})
 
+			It("is empty when not an object", func() {
+				params := serviceadapter.RequestParameters{"context": []interface{}{"cloudfoundry"}}
+				Expect(params.ArbitraryContext()).To(Equal(map[string]interface{}{}))
+			})
+
 			It("extracts the context", func() {
 				expectedContext := map[string]interface{}{
 					"platform":   "cloudfoundry",
@@ -124,6 +136,38 @@ var _ = Describe("Domain", func() {
 		})
 	})
 
+	Describe("InputParams", func() {
+		It("keeps the input params of custom actions", func() {
+			inputJSON := `{"create_binding": {"binding_id": "binding"}, "describe": {"instance_id": "instance"}, "timeout": "1m"}`
+
+			var inputParams serviceadapter.InputParams
+			Expect(json.Unmarshal([]byte(inputJSON), &inputParams)).To(Succeed())
+
+			Expect(inputParams.CreateBinding.BindingId).To(Equal("binding"))
+			Expect(inputParams.Timeout).To(Equal("1m"))
+			Expect(inputParams.Custom).To(HaveLen(1))
+			Expect(inputParams.Custom["describe"]).To(MatchJSON(`{"instance_id": "instance"}`))
+
+			roundTripped := toJson(inputParams)
+			Expect(roundTripped).To(ContainSubstring(`"describe":{"instance_id":"instance"}`))
+			Expect(roundTripped).To(ContainSubstring(`"binding_id":"binding"`))
+		})
+
+		It("has no custom input params when there are none", func() {
+			var inputParams serviceadapter.InputParams
+			Expect(json.Unmarshal([]byte(`{"dashboard_url": {"instance_id": "instance"}}`), &inputParams)).To(Succeed())
+
+			Expect(inputParams.Custom).To(BeNil())
+		})
+
+		It("rejects custom input params named like a known field", func() {
+			inputParams := serviceadapter.InputParams{Custom: map[string]json.RawMessage{"timeout": json.RawMessage(`"1m"`)}}
+
+			_, err := json.Marshal(inputParams)
+			Expect(err).To(MatchError(ContainSubstring(`custom input params "timeout" clash with a known field`)))
+		})
+	})
+
 	Describe("DashboardUrl", func() {
 		It("serializes dashboard_url", func() {
 			dashboardUrl := serviceadapter.DashboardUrl{DashboardUrl: "https://someurl.com"}
@@ -267,14 +311,14 @@ var _ = Describe("Domain", func() {
 				j := []byte(
 					`{
 						"lifecycle_errands": {
-							"post_deploy": {
+							"post_deploy": [{
 								"name": "health-check",
 								"instances": ["redis-server/0"]
-							},
-							"pre_delete": {
+							}],
+							"pre_delete": [{
 								"name": "cleanup-data",
 								"instances": ["redis-server/0"]
-							}
+							}]
 						},
 						"instance_groups": [
 							{
@@ -0,0 +1,263 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter/fakes"
+	"gopkg.in/yaml.v2"
+)
+
+// The fuzz targets check that malformed input from ODB fails with an error
+// rather than a panic. Their seeds are valid inputs.
+
+func mustJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+func mustYAML(v interface{}) string {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+func FuzzGenerateManifestAction(f *testing.F) {
+	f.Add(mustJSON(defaultServiceDeployment()), mustJSON(defaultPlan()), mustJSON(defaultRequestParams()), mustYAML(defaultPreviousManifest()), mustJSON(defaultPreviousPlan()), mustJSON(defaultSecretParams()), mustJSON(defaultPreviousBoshConfigs()), `{"client_id": "id", "client_secret": "secret"}`)
+	f.Add(mustJSON(defaultServiceDeployment()), mustJSON(defaultPlan()), "{}", "", "null", "", "", "")
+	f.Add("null", "null", "null", "null", "null", "null", "null", "null")
+
+	f.Fuzz(func(t *testing.T, serviceDeployment, plan, requestParams, previousManifest, previousPlan, previousSecrets, previousConfigs, uaaClient string) {
+		generator := new(fakes.FakeManifestGenerator)
+		generator.GenerateManifestReturns(serviceadapter.GenerateManifestOutput{Manifest: defaultManifest()}, nil)
+		action := serviceadapter.NewGenerateManifestAction(generator)
+
+		inputParams := serviceadapter.InputParams{GenerateManifest: serviceadapter.GenerateManifestJSONParams{
+			ServiceDeployment:        serviceDeployment,
+			Plan:                     plan,
+			RequestParameters:        requestParams,
+			PreviousManifest:         previousManifest,
+			PreviousPlan:             previousPlan,
+			PreviousSecrets:          previousSecrets,
+			PreviousConfigs:          previousConfigs,
+			ServiceInstanceUAAClient: uaaClient,
+		}}
+		_ = action.Execute(inputParams, io.Discard)
+	})
+}
+
+func FuzzCreateBindingAction(f *testing.F) {
+	f.Add("binding-id", mustJSON(bosh.BoshVMs{"kafka": []string{"a", "b"}}), mustYAML(defaultManifest()), mustJSON(defaultRequestParams()), mustJSON(defaultSecretParams()), mustJSON(defaultDNSParams()))
+	f.Add("", "null", "null", "null", "null", "null")
+
+	f.Fuzz(func(t *testing.T, bindingID, boshVMs, manifest, requestParams, secrets, dnsAddresses string) {
+		binder := new(fakes.FakeBinder)
+		binder.CreateBindingReturns(serviceadapter.Binding{Credentials: map[string]interface{}{"password": "secret"}}, nil)
+		action := serviceadapter.NewCreateBindingAction(binder)
+
+		_ = action.Execute(serviceadapter.InputParams{CreateBinding: serviceadapter.CreateBindingJSONParams{
+			BindingId:         bindingID,
+			BoshVms:           boshVMs,
+			Manifest:          manifest,
+			RequestParameters: requestParams,
+			Secrets:           secrets,
+			DNSAddresses:      dnsAddresses,
+		}}, io.Discard)
+	})
+}
+
+func FuzzDeleteBindingAction(f *testing.F) {
+	f.Add("binding-id", mustJSON(bosh.BoshVMs{"kafka": []string{"a", "b"}}), mustYAML(defaultManifest()), mustJSON(defaultRequestParams()), mustJSON(defaultSecretParams()), mustJSON(defaultDNSParams()))
+	f.Add("", "null", "null", "null", "null", "null")
+
+	f.Fuzz(func(t *testing.T, bindingID, boshVMs, manifest, requestParams, secrets, dnsAddresses string) {
+		action := serviceadapter.NewDeleteBindingAction(new(fakes.FakeBinder))
+
+		_ = action.Execute(serviceadapter.InputParams{DeleteBinding: serviceadapter.DeleteBindingJSONParams{
+			BindingId:         bindingID,
+			BoshVms:           boshVMs,
+			Manifest:          manifest,
+			RequestParameters: requestParams,
+			Secrets:           secrets,
+			DNSAddresses:      dnsAddresses,
+		}}, io.Discard)
+	})
+}
+
+func FuzzDashboardUrlAction(f *testing.F) {
+	f.Add("instance-id", mustJSON(defaultPlan()), mustYAML(defaultManifest()))
+	f.Add("", "null", "null")
+
+	f.Fuzz(func(t *testing.T, instanceID, plan, manifest string) {
+		generator := new(fakes.FakeDashboardUrlGenerator)
+		generator.DashboardUrlReturns(serviceadapter.DashboardUrl{DashboardUrl: "https://dashboard.example.com"}, nil)
+		action := serviceadapter.NewDashboardUrlAction(generator)
+
+		_ = action.Execute(serviceadapter.InputParams{DashboardUrl: serviceadapter.DashboardUrlJSONParams{
+			InstanceId: instanceID,
+			Plan:       plan,
+			Manifest:   manifest,
+		}}, io.Discard)
+	})
+}
+
+func FuzzGeneratePlanSchemasAction(f *testing.F) {
+	f.Add(mustJSON(defaultPlan()))
+	f.Add("null")
+
+	f.Fuzz(func(t *testing.T, plan string) {
+		action := serviceadapter.NewGeneratePlanSchemasAction(new(fakes.FakeSchemaGenerator), io.Discard)
+
+		_ = action.Execute(serviceadapter.InputParams{GeneratePlanSchemas: serviceadapter.GeneratePlanSchemasJSONParams{Plan: plan}}, io.Discard)
+	})
+}
+
+var fuzzedActions = []string{"generate-manifest", "create-binding", "delete-binding", "dashboard-url", "generate-plan-schemas", "capabilities"}
+
+// FuzzHandleStdin covers ReadInputParams and the handler's own processing
+// of input params, such as timeouts, logging and request parameters
+// validation.
+func FuzzHandleStdin(f *testing.F) {
+	f.Add(uint8(0), []byte(mustJSON(serviceadapter.InputParams{GenerateManifest: serviceadapter.GenerateManifestJSONParams{
+		ServiceDeployment: mustJSON(defaultServiceDeployment()),
+		Plan:              mustJSON(defaultPlan()),
+		RequestParameters: mustJSON(defaultRequestParams()),
+		PreviousPlan:      "null",
+	}})))
+	f.Add(uint8(1), []byte(`{"create_binding": {"binding_id": "id", "request_parameters": "{\"parameters\": {}}"}, "timeout": "1m"}`))
+	f.Add(uint8(3), []byte(`{"dashboard_url": {}, "custom": [1]}`))
+	f.Add(uint8(4), []byte(`null`))
+
+	f.Fuzz(func(t *testing.T, action uint8, stdin []byte) {
+		schemaGenerator := new(fakes.FakeSchemaGenerator)
+		schemas, err := serviceadapter.JSONSchemasFor(struct {
+			Size int `json:"size" minimum:"1"`
+		}{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		schemaGenerator.GeneratePlanSchemaReturns(serviceadapter.PlanSchema{
+			ServiceInstance: serviceadapter.ServiceInstanceSchema{Create: schemas, Update: schemas},
+			ServiceBinding:  serviceadapter.ServiceBindingSchema{Create: schemas},
+		}, nil)
+		handler := serviceadapter.CommandLineHandler{
+			ManifestGenerator:         new(fakes.FakeManifestGenerator),
+			Binder:                    new(fakes.FakeBinder),
+			DashboardURLGenerator:     new(fakes.FakeDashboardUrlGenerator),
+			SchemaGenerator:           schemaGenerator,
+			ValidateRequestParameters: true,
+			StructuredErrors:          true,
+		}
+
+		args := []string{"service-adapter", fuzzedActions[int(action)%len(fuzzedActions)]}
+		_ = handler.HandleWithContext(context.Background(), args, io.Discard, io.Discard, bytes.NewReader(stdin))
+	})
+}
+
+// FuzzHandlePositionalArgs covers the legacy protocol, where ODB passes
+// input params as arguments.
+func FuzzHandlePositionalArgs(f *testing.F) {
+	f.Add(uint8(0), strings.Join([]string{mustJSON(defaultServiceDeployment()), mustJSON(defaultPlan()), "{}", "", "null"}, "\x00"))
+	f.Add(uint8(1), strings.Join([]string{"binding-id", "{}", "", "{}"}, "\x00"))
+	f.Add(uint8(4), "-plan-json\x00{}")
+	f.Add(uint8(4), "-unknown-flag")
+
+	f.Fuzz(func(t *testing.T, action uint8, joinedArgs string) {
+		handler := serviceadapter.CommandLineHandler{
+			ManifestGenerator:     new(fakes.FakeManifestGenerator),
+			Binder:                new(fakes.FakeBinder),
+			DashboardURLGenerator: new(fakes.FakeDashboardUrlGenerator),
+			SchemaGenerator:       new(fakes.FakeSchemaGenerator),
+		}
+
+		args := append([]string{"service-adapter", fuzzedActions[int(action)%len(fuzzedActions)]}, strings.Split(joinedArgs, "\x00")...)
+		_ = handler.HandleWithContext(context.Background(), args, io.Discard, io.Discard, strings.NewReader(""))
+	})
+}
+
+// FuzzPlanJSONRoundTrip checks plans ODB passes survive being marshalled
+// back, as when generate-manifest passes the previous plan on.
+func FuzzPlanJSONRoundTrip(f *testing.F) {
+	f.Add([]byte(mustJSON(defaultPlan())))
+	f.Add([]byte(`{"instance_groups": [], "update": {"canaries": 1, "max_in_flight": "10%"}}`))
+	f.Add([]byte(`{"update": {"max_in_flight": 3, "serial": true}}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var plan serviceadapter.Plan
+		if err := json.Unmarshal(data, &plan); err != nil {
+			return
+		}
+		marshalled, err := json.Marshal(plan)
+		if err != nil {
+			t.Fatalf("marshalling unmarshalled plan: %s", err)
+		}
+
+		var roundTripped serviceadapter.Plan
+		if err := json.Unmarshal(marshalled, &roundTripped); err != nil {
+			t.Fatalf("unmarshalling marshalled plan %s: %s", marshalled, err)
+		}
+		remarshalled, err := json.Marshal(roundTripped)
+		if err != nil {
+			t.Fatalf("marshalling round tripped plan: %s", err)
+		}
+		if !bytes.Equal(marshalled, remarshalled) {
+			t.Fatalf("plan changed on a round trip:\n%s\n%s", marshalled, remarshalled)
+		}
+	})
+}
+
+// FuzzPlanYAMLRoundTrip checks plans written as YAML marshal the same once
+// unmarshalled again.
+func FuzzPlanYAMLRoundTrip(f *testing.F) {
+	f.Add(mustYAML(defaultPlan()))
+	f.Add("update:\n  canaries: 1\n  max_in_flight: 10%\ninstance_groups:\n- name: a\n  vm_extensions: ['', b]\n")
+
+	f.Fuzz(func(t *testing.T, document string) {
+		var plan serviceadapter.Plan
+		if err := yaml.Unmarshal([]byte(document), &plan); err != nil {
+			return
+		}
+		marshalled, err := yaml.Marshal(plan)
+		if err != nil {
+			t.Fatalf("marshalling unmarshalled plan: %s", err)
+		}
+
+		var roundTripped serviceadapter.Plan
+		if err := yaml.Unmarshal(marshalled, &roundTripped); err != nil {
+			t.Fatalf("unmarshalling marshalled plan:\n%s\n%s", marshalled, err)
+		}
+		remarshalled, err := yaml.Marshal(roundTripped)
+		if err != nil {
+			t.Fatalf("marshalling round tripped plan: %s", err)
+		}
+		if !bytes.Equal(marshalled, remarshalled) {
+			t.Fatalf("plan changed on a round trip:\n%s\n%s", marshalled, remarshalled)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package serviceadapter
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,6 +14,9 @@ import (
 
 type GenerateManifestAction struct {
 	manifestGenerator ManifestGenerator
+	validateManifest  bool
+	paramsValidator   *requestParametersValidator
+	upgradePolicy     UpgradePolicy
 }
 
 func NewGenerateManifestAction(manifestGenerator ManifestGenerator) *GenerateManifestAction {
@@ -21,10 +25,43 @@ func NewGenerateManifestAction(manifestGenerator ManifestGenerator) *GenerateMan
 	}
 }
 
+// WithManifestValidation makes the action reject generated manifests that
+// fail bosh.BoshManifest.Validate or VerifyODBSecrets, before they are
+// returned to ODB.
+func (g *GenerateManifestAction) WithManifestValidation() *GenerateManifestAction {
+	g.validateManifest = true
+	return g
+}
+
+// WithRequestParametersValidation makes the action validate the arbitrary
+// request parameters against the create or update schema schemaGenerator
+// returns for the plan. On create, missing parameters are defaulted. Updates
+// that pass no parameters, such as upgrades, are not validated.
+func (g *GenerateManifestAction) WithRequestParametersValidation(schemaGenerator SchemaGenerator) *GenerateManifestAction {
+	g.paramsValidator = &requestParametersValidator{schemaGenerator: schemaGenerator}
+	return g
+}
+
+// WithUpgradePolicy makes the action reject generated manifests whose
+// transition from the previous plan and manifest violates policy. The
+// violations are reported to the user as an AdapterError with code
+// PlanChangeNotAllowedCode.
+func (g *GenerateManifestAction) WithUpgradePolicy(policy UpgradePolicy) *GenerateManifestAction {
+	g.upgradePolicy = policy
+	return g
+}
+
 func (g *GenerateManifestAction) IsImplemented() bool {
 	return g.manifestGenerator != nil
 }
 
+func (g *GenerateManifestAction) Capabilities() ActionCapabilities {
+	return ActionCapabilities{
+		InputProtocols: []string{PositionalArgsProtocol, StdinJSONProtocol},
+		OptionalFields: []string{"previous_secrets", "previous_configs", "uaa_client", "timeout"},
+	}
+}
+
 func (g *GenerateManifestAction) ParseArgs(reader io.Reader, args []string) (InputParams, error) {
 	var inputParams InputParams
 
@@ -46,20 +83,7 @@ func (g *GenerateManifestAction) ParseArgs(reader io.Reader, args []string) (Inp
 		return inputParams, nil
 	}
 
-	data, err := io.ReadAll(reader)
-	if err != nil {
-		return inputParams, CLIHandlerError{ErrorExitCode, fmt.Sprintf("error reading input params JSON, error: %s", err)}
-	}
-	if len(data) <= 0 {
-		return inputParams, CLIHandlerError{ErrorExitCode, "expecting parameters to be passed via stdin"}
-	}
-
-	err = json.Unmarshal(data, &inputParams)
-	if err != nil {
-		return inputParams, CLIHandlerError{ErrorExitCode, fmt.Sprintf("error unmarshalling input params JSON, error: %s", err)}
-	}
-
-	return inputParams, nil
+	return ReadInputParams(reader)
 }
 
 type ServiceInstanceUAAClient struct {
@@ -72,7 +96,11 @@ type ServiceInstanceUAAClient struct {
 	Scopes               string `json:"scopes"`
 }
 
-func (g *GenerateManifestAction) Execute(inputParams InputParams, outputWriter io.Writer) (err error) {
+func (g *GenerateManifestAction) Execute(inputParams InputParams, outputWriter io.Writer) error {
+	return g.ExecuteWithContext(context.Background(), inputParams, outputWriter)
+}
+
+func (g *GenerateManifestAction) ExecuteWithContext(ctx context.Context, inputParams InputParams, outputWriter io.Writer) (err error) {
 	var serviceDeployment ServiceDeployment
 	generateManifestParams := inputParams.GenerateManifest
 
@@ -117,6 +145,8 @@ func (g *GenerateManifestAction) Execute(inputParams InputParams, outputWriter i
 			return errors.Wrap(err, "unmarshalling previous secrets")
 		}
 	}
+	redactor := RedactorFromContext(ctx)
+	redactor.AddValues(previousSecrets)
 
 	var previousConfigs BOSHConfigs
 	if generateManifestParams.PreviousConfigs != "" {
@@ -130,9 +160,25 @@ func (g *GenerateManifestAction) Execute(inputParams InputParams, outputWriter i
 		if err = json.Unmarshal([]byte(generateManifestParams.ServiceInstanceUAAClient), &serviceInstanceClient); err != nil {
 			return errors.Wrap(err, "unmarshalling service instance client")
 		}
+		if serviceInstanceClient != nil {
+			redactor.Add(serviceInstanceClient.ClientSecret)
+		}
+	}
+
+	if g.paramsValidator != nil {
+		isUpdate := previousManifest != nil
+		if _, hasParams := requestParams["parameters"]; !isUpdate || hasParams {
+			schemaFor := instanceCreateSchema
+			if isUpdate {
+				schemaFor = instanceUpdateSchema
+			}
+			if requestParams, err = g.paramsValidator.validate(ctx, plan, schemaFor, requestParams, !isUpdate); err != nil {
+				return adapterFailure(err, outputWriter, ErrorExitCode)
+			}
+		}
 	}
 
-	generateManifestOutput, err := g.manifestGenerator.GenerateManifest(GenerateManifestParams{
+	generateManifestOutput, err := g.generateManifest(ctx, GenerateManifestParams{
 		ServiceDeployment:        serviceDeployment,
 		Plan:                     plan,
 		RequestParams:            requestParams,
@@ -142,9 +188,30 @@ func (g *GenerateManifestAction) Execute(inputParams InputParams, outputWriter i
 		PreviousConfigs:          previousConfigs,
 		ServiceInstanceUAAClient: serviceInstanceClient,
 	})
+	redactor.AddValues(generateManifestOutput.ODBManagedSecrets)
 	if err != nil {
-		fmt.Fprint(outputWriter, err.Error())
-		return CLIHandlerError{ErrorExitCode, err.Error()}
+		return adapterFailure(err, outputWriter, ErrorExitCode)
+	}
+
+	if g.validateManifest {
+		if err = generateManifestOutput.Manifest.Validate(); err != nil {
+			return adapterFailure(err, outputWriter, ErrorExitCode)
+		}
+		if err = VerifyODBSecrets(generateManifestOutput); err != nil {
+			return adapterFailure(err, outputWriter, ErrorExitCode)
+		}
+	}
+
+	if len(g.upgradePolicy) > 0 {
+		transition := PlanTransition{
+			PreviousPlan:     previousPlan,
+			Plan:             plan,
+			PreviousManifest: previousManifest,
+			Manifest:         generateManifestOutput.Manifest,
+		}
+		if err = g.upgradePolicy.Evaluate(transition); err != nil {
+			return adapterFailure(NewAdapterError(PlanChangeNotAllowedCode, err.Error(), err), outputWriter, ErrorExitCode)
+		}
 	}
 
 	var output []byte
@@ -177,6 +244,13 @@ func (g *GenerateManifestAction) Execute(inputParams InputParams, outputWriter i
 	return nil
 }
 
+func (g *GenerateManifestAction) generateManifest(ctx context.Context, params GenerateManifestParams) (GenerateManifestOutput, error) {
+	if generator, ok := g.manifestGenerator.(ContextManifestGenerator); ok {
+		return generator.GenerateManifestWithContext(ctx, params)
+	}
+	return g.manifestGenerator.GenerateManifest(params)
+}
+
 func handleErr(err *error) {
 	if v := recover(); v != nil {
 		*err = errors.New("error marshalling bosh manifest")
@@ -19,6 +19,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"regexp"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -346,6 +347,80 @@ var _ = Describe("GenerateManifest", func() {
 				Expect(err).To(MatchError(ContainSubstring("error marshalling bosh manifest")))
 			})
 
+			Context("with manifest validation", func() {
+				BeforeEach(func() {
+					action = serviceadapter.NewGenerateManifestAction(fakeManifestGenerator).WithManifestValidation()
+				})
+
+				It("returns an error when the generated manifest is inconsistent", func() {
+					manifest := defaultManifest()
+					manifest.InstanceGroups = []bosh.InstanceGroup{{
+						Name:     "redis",
+						Stemcell: "jammy",
+						AZs:      []string{"z1"},
+						Networks: []bosh.Network{{Name: "default"}},
+					}}
+
+					fakeManifestGenerator.GenerateManifestReturns(serviceadapter.GenerateManifestOutput{Manifest: manifest}, nil)
+					err := action.Execute(expectedInputParams, outputBuffer)
+
+					message := "invalid manifest: /instance_groups/name=redis/stemcell: stemcell alias 'jammy' is not declared in stemcells"
+					Expect(err).To(BeACLIError(1, message))
+					Expect(outputBuffer).To(gbytes.Say(regexp.QuoteMeta(message)))
+				})
+
+				It("returns an error when the manifest references an ODB managed secret with no value", func() {
+					manifest := defaultManifest()
+					manifest.Properties = map[string]interface{}{
+						"password": "((odb_secret:password))",
+						"token":    "((odb_secret:token))",
+					}
+
+					fakeManifestGenerator.GenerateManifestReturns(serviceadapter.GenerateManifestOutput{
+						Manifest:          manifest,
+						ODBManagedSecrets: serviceadapter.ODBManagedSecrets{"token": "a-token"},
+					}, nil)
+					err := action.Execute(expectedInputParams, outputBuffer)
+
+					Expect(err).To(BeACLIError(1, "manifest references ODB managed secrets with no value: password"))
+				})
+
+				It("outputs a consistent manifest", func() {
+					fakeManifestGenerator.GenerateManifestReturns(serviceadapter.GenerateManifestOutput{Manifest: defaultManifest()}, nil)
+					Expect(action.Execute(expectedInputParams, outputBuffer)).To(Succeed())
+				})
+			})
+
+			Context("with an upgrade policy", func() {
+				BeforeEach(func() {
+					action = serviceadapter.NewGenerateManifestAction(fakeManifestGenerator).WithUpgradePolicy(serviceadapter.UpgradePolicy{
+						serviceadapter.UpgradeRuleFunc(func(transition serviceadapter.PlanTransition) serviceadapter.Violations {
+							if transition.PreviousManifest != nil && len(transition.Manifest.InstanceGroups) > 0 {
+								return serviceadapter.Violations{{Rule: "frozen", Message: "instance groups cannot be added"}}
+							}
+							return nil
+						}),
+					})
+				})
+
+				It("reports violations to the user", func() {
+					manifest := defaultManifest()
+					manifest.InstanceGroups = []bosh.InstanceGroup{{Name: "redis"}}
+					fakeManifestGenerator.GenerateManifestReturns(serviceadapter.GenerateManifestOutput{Manifest: manifest}, nil)
+
+					err := action.Execute(expectedInputParams, outputBuffer)
+
+					message := "plan change not allowed: instance groups cannot be added"
+					Expect(err).To(BeACLIError(1, message))
+					Expect(outputBuffer).To(gbytes.Say(message))
+				})
+
+				It("outputs manifests that satisfy the policy", func() {
+					fakeManifestGenerator.GenerateManifestReturns(serviceadapter.GenerateManifestOutput{Manifest: defaultManifest()}, nil)
+					Expect(action.Execute(expectedInputParams, outputBuffer)).To(Succeed())
+				})
+			})
+
 			It("returns an error when the generated output cannot be marshalled", func() {
 				manifest := bosh.BoshManifest{
 					Tags: map[string]interface{}{"foo": make(chan int)},
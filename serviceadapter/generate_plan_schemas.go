@@ -1,9 +1,9 @@
 package serviceadapter
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
-	"fmt"
 	"io"
 
 	"github.com/pkg/errors"
@@ -26,6 +26,13 @@ func (g *GeneratePlanSchemasAction) IsImplemented() bool {
 	return g.schemaGenerator != nil
 }
 
+func (g *GeneratePlanSchemasAction) Capabilities() ActionCapabilities {
+	return ActionCapabilities{
+		InputProtocols: []string{PositionalArgsProtocol, StdinJSONProtocol},
+		OptionalFields: []string{"timeout"},
+	}
+}
+
 func (g *GeneratePlanSchemasAction) ParseArgs(reader io.Reader, args []string) (InputParams, error) {
 	var inputParams InputParams
 
@@ -52,24 +59,14 @@ func (g *GeneratePlanSchemasAction) ParseArgs(reader io.Reader, args []string) (
 		return inputParams, nil
 	}
 
-	data, err := io.ReadAll(reader)
-	if err != nil {
-		return inputParams, CLIHandlerError{ErrorExitCode, fmt.Sprintf("error reading input params JSON, error: %s", err)}
-	}
-
-	if len(data) > 0 {
-		err = json.Unmarshal(data, &inputParams)
-		if err != nil {
-			return inputParams, CLIHandlerError{ErrorExitCode, fmt.Sprintf("error unmarshalling input params JSON, error: %s", err)}
-		}
-
-		return inputParams, err
-	}
+	return ReadInputParams(reader)
+}
 
-	return inputParams, CLIHandlerError{ErrorExitCode, "expecting parameters to be passed via stdin"}
+func (g *GeneratePlanSchemasAction) Execute(inputParams InputParams, outputWriter io.Writer) error {
+	return g.ExecuteWithContext(context.Background(), inputParams, outputWriter)
 }
 
-func (g *GeneratePlanSchemasAction) Execute(inputParams InputParams, outputWriter io.Writer) (err error) {
+func (g *GeneratePlanSchemasAction) ExecuteWithContext(ctx context.Context, inputParams InputParams, outputWriter io.Writer) (err error) {
 	var plan Plan
 	if err := json.Unmarshal([]byte(inputParams.GeneratePlanSchemas.Plan), &plan); err != nil {
 		return errors.Wrap(err, "error unmarshalling plan JSON")
@@ -77,10 +74,9 @@ func (g *GeneratePlanSchemasAction) Execute(inputParams InputParams, outputWrite
 	if err := plan.Validate(); err != nil {
 		return errors.Wrap(err, "error validating plan JSON")
 	}
-	schema, err := g.schemaGenerator.GeneratePlanSchema(GeneratePlanSchemaParams{Plan: plan})
+	schema, err := generatePlanSchema(ctx, g.schemaGenerator, GeneratePlanSchemaParams{Plan: plan})
 	if err != nil {
-		fmt.Fprint(outputWriter, err.Error())
-		return CLIHandlerError{ErrorExitCode, err.Error()}
+		return adapterFailure(err, outputWriter, ErrorExitCode)
 	}
 
 	err = json.NewEncoder(outputWriter).Encode(schema)
@@ -90,3 +86,10 @@ func (g *GeneratePlanSchemasAction) Execute(inputParams InputParams, outputWrite
 
 	return nil
 }
+
+func generatePlanSchema(ctx context.Context, schemaGenerator SchemaGenerator, params GeneratePlanSchemaParams) (PlanSchema, error) {
+	if generator, ok := schemaGenerator.(ContextSchemaGenerator); ok {
+		return generator.GeneratePlanSchemaWithContext(ctx, params)
+	}
+	return schemaGenerator.GeneratePlanSchema(params)
+}
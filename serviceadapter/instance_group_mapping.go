@@ -17,24 +17,88 @@ package serviceadapter
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
 )
 
+// JobPropertiesFunc returns the properties of job on the plan instance group
+// instanceGroup.
+type JobPropertiesFunc func(instanceGroup InstanceGroup, job string) (map[string]interface{}, error)
+
+// InstanceGroupOverrides are applied to an instance group generated from
+// the plan.
+type InstanceGroupOverrides struct {
+	Env    map[string]interface{}
+	Update *bosh.Update
+	// VMExtensions are added to those of the plan.
+	VMExtensions []string
+	// PersistentDisk is the size of the persistent disk in MB. It cannot be
+	// set for a plan instance group with a persistent disk type.
+	PersistentDisk int
+}
+
+// InstanceGroupMapping describes how GenerateInstanceGroups generates
+// instance groups from those of the plan.
+type InstanceGroupMapping struct {
+	// Jobs maps the names of the plan instance groups to deploy to their
+	// jobs. Other plan instance groups are ignored.
+	Jobs     map[string][]string
+	Stemcell string
+	// JobProperties returns the properties of each job. The jobs have no
+	// properties when it is nil.
+	JobProperties JobPropertiesFunc
+	// Overrides are keyed by instance group name, which must be that of a
+	// plan instance group and a key of Jobs, as only those are generated.
+	Overrides map[string]InstanceGroupOverrides
+}
+
 func GenerateInstanceGroupsWithNoProperties(
 	instanceGroups []InstanceGroup,
 	serviceReleases ServiceReleases,
 	stemcell string,
 	deploymentInstanceGroupsToJobs map[string][]string,
 ) ([]bosh.InstanceGroup, error) {
+	boshInstanceGroups, _, err := GenerateInstanceGroups(instanceGroups, serviceReleases, InstanceGroupMapping{
+		Jobs:     deploymentInstanceGroupsToJobs,
+		Stemcell: stemcell,
+	})
+	return boshInstanceGroups, err
+}
+
+// GenerateInstanceGroups generates the instance groups of mapping.Jobs from
+// the plan instance groups, and returns the names of the plan instance
+// groups it ignored.
+func GenerateInstanceGroups(
+	instanceGroups []InstanceGroup,
+	serviceReleases ServiceReleases,
+	mapping InstanceGroupMapping,
+) ([]bosh.InstanceGroup, []string, error) {
 	if len(instanceGroups) == 0 {
-		return nil, fmt.Errorf("no instance groups provided")
+		return nil, nil, fmt.Errorf("no instance groups provided")
+	}
+
+	var unappliedOverrides []string
+	for name := range mapping.Overrides {
+		_, mapped := mapping.Jobs[name]
+		if !mapped || !slices.ContainsFunc(instanceGroups, func(ig InstanceGroup) bool { return ig.Name == name }) {
+			unappliedOverrides = append(unappliedOverrides, name)
+		}
+	}
+	if len(unappliedOverrides) > 0 {
+		slices.Sort(unappliedOverrides)
+		return nil, nil, fmt.Errorf(
+			"overrides for instance groups that are not both in the plan and mapped to jobs: %s",
+			strings.Join(unappliedOverrides, ", "),
+		)
 	}
 
 	boshInstanceGroups := []bosh.InstanceGroup{}
+	var ignored []string
 	for _, instanceGroup := range instanceGroups {
-		if _, ok := deploymentInstanceGroupsToJobs[instanceGroup.Name]; !ok {
+		if _, ok := mapping.Jobs[instanceGroup.Name]; !ok {
+			ignored = append(ignored, instanceGroup.Name)
 			continue
 		}
 
@@ -44,9 +108,19 @@ func GenerateInstanceGroupsWithNoProperties(
 			networks = append(networks, bosh.Network{Name: network})
 		}
 
-		boshJobs, err := generateJobsForInstanceGroup(instanceGroup.Name, deploymentInstanceGroupsToJobs, serviceReleases)
+		boshJobs, err := generateJobsForInstanceGroup(instanceGroup.Name, mapping.Jobs, serviceReleases)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+
+		if mapping.JobProperties != nil {
+			for i, job := range boshJobs {
+				properties, err := mapping.JobProperties(instanceGroup, job.Name)
+				if err != nil {
+					return nil, nil, fmt.Errorf("generating properties of job '%s' on instance group '%s': %s", job.Name, instanceGroup.Name, err)
+				}
+				boshJobs[i].Properties = properties
+			}
 		}
 
 		var migrations []bosh.Migration
@@ -60,7 +134,7 @@ func GenerateInstanceGroupsWithNoProperties(
 		boshInstanceGroup := bosh.InstanceGroup{
 			Name:               instanceGroup.Name,
 			Instances:          instanceGroup.Instances,
-			Stemcell:           stemcell,
+			Stemcell:           mapping.Stemcell,
 			VMType:             instanceGroup.VMType,
 			VMExtensions:       instanceGroup.VMExtensions,
 			PersistentDiskType: instanceGroup.PersistentDiskType,
@@ -70,9 +144,39 @@ func GenerateInstanceGroupsWithNoProperties(
 			Lifecycle:          instanceGroup.Lifecycle,
 			MigratedFrom:       migrations,
 		}
+		if overrides, ok := mapping.Overrides[instanceGroup.Name]; ok {
+			if boshInstanceGroup, err = overrides.apply(boshInstanceGroup); err != nil {
+				return nil, nil, fmt.Errorf("overriding instance group '%s': %s", instanceGroup.Name, err)
+			}
+		}
 		boshInstanceGroups = append(boshInstanceGroups, boshInstanceGroup)
 	}
-	return boshInstanceGroups, nil
+	return boshInstanceGroups, ignored, nil
+}
+
+func (o InstanceGroupOverrides) apply(instanceGroup bosh.InstanceGroup) (bosh.InstanceGroup, error) {
+	if o.Env != nil {
+		instanceGroup.Env = o.Env
+	}
+	if o.Update != nil {
+		instanceGroup.Update = o.Update
+	}
+	if len(o.VMExtensions) > 0 {
+		vmExtensions := append([]string{}, instanceGroup.VMExtensions...)
+		for _, extension := range o.VMExtensions {
+			if !slices.Contains(vmExtensions, extension) {
+				vmExtensions = append(vmExtensions, extension)
+			}
+		}
+		instanceGroup.VMExtensions = vmExtensions
+	}
+	if o.PersistentDisk > 0 {
+		if instanceGroup.PersistentDiskType != "" {
+			return instanceGroup, fmt.Errorf("persistent disk cannot be set, the plan sets persistent disk type '%s'", instanceGroup.PersistentDiskType)
+		}
+		instanceGroup.PersistentDisk = o.PersistentDisk
+	}
+	return instanceGroup, nil
 }
 
 func FindReleaseForJob(jobName string, releases ServiceReleases) (ServiceRelease, error) {
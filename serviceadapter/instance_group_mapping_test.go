@@ -16,6 +16,8 @@
 package serviceadapter_test
 
 import (
+	"errors"
+
 	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
 	. "github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
 
@@ -156,3 +158,128 @@ var _ = Describe("Instance Groups Mapping", func() {
 		})
 	})
 })
+
+var _ = Describe("GenerateInstanceGroups", func() {
+	var (
+		instanceGroups  []InstanceGroup
+		serviceReleases ServiceReleases
+		mapping         InstanceGroupMapping
+	)
+
+	BeforeEach(func() {
+		instanceGroups = []InstanceGroup{
+			{
+				Name:         "redis",
+				VMType:       "small",
+				VMExtensions: []string{"public-ip"},
+				Instances:    3,
+				Networks:     []string{"default"},
+				AZs:          []string{"z1"},
+			},
+			{
+				Name:               "sentinel",
+				VMType:             "tiny",
+				PersistentDiskType: "ten",
+				Instances:          1,
+				Networks:           []string{"default"},
+				AZs:                []string{"z1"},
+			},
+			{Name: "errand", VMType: "tiny", Instances: 1, Networks: []string{"default"}, AZs: []string{"z1"}},
+		}
+		serviceReleases = ServiceReleases{
+			{Name: "redis", Version: "1", Jobs: []string{"redis-server", "redis-sentinel"}},
+		}
+		mapping = InstanceGroupMapping{
+			Jobs: map[string][]string{
+				"redis":    {"redis-server"},
+				"sentinel": {"redis-sentinel"},
+			},
+			Stemcell: "jammy",
+			JobProperties: func(instanceGroup InstanceGroup, job string) (map[string]interface{}, error) {
+				return map[string]interface{}{"instances": instanceGroup.Instances, "job": job}, nil
+			},
+			Overrides: map[string]InstanceGroupOverrides{
+				"redis": {
+					Env:            map[string]interface{}{"persistent_disk_fs": "ext4"},
+					Update:         &bosh.Update{Canaries: 1, MaxInFlight: 1},
+					VMExtensions:   []string{"public-ip", "100GB_ephemeral_disk"},
+					PersistentDisk: 10240,
+				},
+				"sentinel": {VMExtensions: []string{"public-ip"}},
+			},
+		}
+	})
+
+	It("generates instance groups with job properties and overrides", func() {
+		generated, ignored, err := GenerateInstanceGroups(instanceGroups, serviceReleases, mapping)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ignored).To(Equal([]string{"errand"}))
+
+		Expect(generated).To(Equal([]bosh.InstanceGroup{
+			{
+				Name:         "redis",
+				Instances:    3,
+				VMType:       "small",
+				VMExtensions: []string{"public-ip", "100GB_ephemeral_disk"},
+				Stemcell:     "jammy",
+				AZs:          []string{"z1"},
+				Networks:     []bosh.Network{{Name: "default"}},
+				Jobs: []bosh.Job{{
+					Name:       "redis-server",
+					Release:    "redis",
+					Properties: map[string]interface{}{"instances": 3, "job": "redis-server"},
+				}},
+				PersistentDisk: 10240,
+				Env:            map[string]interface{}{"persistent_disk_fs": "ext4"},
+				Update:         &bosh.Update{Canaries: 1, MaxInFlight: 1},
+			},
+			{
+				Name:               "sentinel",
+				Instances:          1,
+				VMType:             "tiny",
+				VMExtensions:       []string{"public-ip"},
+				PersistentDiskType: "ten",
+				Stemcell:           "jammy",
+				AZs:                []string{"z1"},
+				Networks:           []bosh.Network{{Name: "default"}},
+				Jobs: []bosh.Job{{
+					Name:       "redis-sentinel",
+					Release:    "redis",
+					Properties: map[string]interface{}{"instances": 1, "job": "redis-sentinel"},
+				}},
+			},
+		}))
+	})
+
+	It("does not modify the VM extensions of the plan", func() {
+		_, _, err := GenerateInstanceGroups(instanceGroups, serviceReleases, mapping)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instanceGroups[0].VMExtensions).To(Equal(VMExtensions{"public-ip"}))
+	})
+
+	It("does not set a persistent disk over the disk type of the plan", func() {
+		mapping.Overrides["sentinel"] = InstanceGroupOverrides{PersistentDisk: 1024}
+
+		_, _, err := GenerateInstanceGroups(instanceGroups, serviceReleases, mapping)
+		Expect(err).To(MatchError("overriding instance group 'sentinel': persistent disk cannot be set, the plan sets persistent disk type 'ten'"))
+	})
+
+	It("rejects overrides for instance groups that are not both mapped and in the plan", func() {
+		mapping.Jobs["proxy"] = []string{"redis-sentinel"}
+		mapping.Overrides["proxy"] = InstanceGroupOverrides{PersistentDisk: 1024}
+		mapping.Overrides["redis-typo"] = InstanceGroupOverrides{PersistentDisk: 1024}
+		mapping.Overrides["errand"] = InstanceGroupOverrides{PersistentDisk: 1024}
+
+		_, _, err := GenerateInstanceGroups(instanceGroups, serviceReleases, mapping)
+		Expect(err).To(MatchError("overrides for instance groups that are not both in the plan and mapped to jobs: errand, proxy, redis-typo"))
+	})
+
+	It("returns the errors of the job properties function", func() {
+		mapping.JobProperties = func(InstanceGroup, string) (map[string]interface{}, error) {
+			return nil, errors.New("no password")
+		}
+
+		_, _, err := GenerateInstanceGroups(instanceGroups, serviceReleases, mapping)
+		Expect(err).To(MatchError("generating properties of job 'redis-server' on instance group 'redis': no password"))
+	})
+})
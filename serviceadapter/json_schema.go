@@ -0,0 +1,421 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// schemaValidator validates values against a draft-04 JSON Schema, filling
+// in missing properties that have a default when applyDefaults is set.
+type schemaValidator struct {
+	root          interface{}
+	applyDefaults bool
+	errors        []string
+	// following holds the $refs being followed, and where, to catch those
+	// that refer back to themselves without descending into the value.
+	following map[refAt]bool
+}
+
+type refAt struct {
+	ref, path string
+}
+
+// validateAgainstSchema returns value with defaults applied, and a message
+// for every way in which it does not match schema.
+func validateAgainstSchema(schema map[string]interface{}, value interface{}, applyDefaults bool) (interface{}, []string, error) {
+	var genericSchema, genericValue interface{}
+	if err := toGenericJSON(schema, &genericSchema); err != nil {
+		return nil, nil, fmt.Errorf("invalid JSON schema: %s", err)
+	}
+	if err := toGenericJSON(value, &genericValue); err != nil {
+		return nil, nil, fmt.Errorf("invalid value: %s", err)
+	}
+
+	v := &schemaValidator{root: genericSchema, applyDefaults: applyDefaults, following: map[refAt]bool{}}
+	result := v.validate(genericSchema, genericValue, "")
+	return result, v.errors, nil
+}
+
+func toGenericJSON(in interface{}, out *interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (v *schemaValidator) fail(path, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if path != "" {
+		message = path + ": " + message
+	}
+	v.errors = append(v.errors, message)
+}
+
+func (v *schemaValidator) validate(rawSchema, value interface{}, path string) interface{} {
+	schema, ok := rawSchema.(map[string]interface{})
+	if !ok {
+		return value
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		if v.following[refAt{ref, path}] {
+			v.fail(path, "circular $ref '%s'", ref)
+			return value
+		}
+		resolved, err := v.resolve(ref)
+		if err != nil {
+			v.fail(path, "%s", err)
+			return value
+		}
+		v.following[refAt{ref, path}] = true
+		defer delete(v.following, refAt{ref, path})
+		return v.validate(resolved, value, path)
+	}
+
+	if types, ok := schema["type"]; ok && !matchesType(types, value) {
+		v.fail(path, "expected %s, got %s", describeTypes(types), jsonType(value))
+		return value
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && !containsJSON(enum, value) {
+		var options []string
+		for _, option := range enum {
+			optionJSON, _ := json.Marshal(option)
+			options = append(options, string(optionJSON))
+		}
+		v.fail(path, "must be one of %s", strings.Join(options, ", "))
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		value = v.validateObject(schema, typed, path)
+	case []interface{}:
+		value = v.validateArray(schema, typed, path)
+	case string:
+		v.validateString(schema, typed, path)
+	case float64:
+		v.validateNumber(schema, typed, path)
+	}
+
+	v.validateCombinators(schema, value, path)
+	return value
+}
+
+func (v *schemaValidator) validateObject(schema, object map[string]interface{}, path string) map[string]interface{} {
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	if v.applyDefaults {
+		for _, name := range sortedMapKeys(properties) {
+			propertySchema, _ := properties[name].(map[string]interface{})
+			if defaultValue, ok := propertySchema["default"]; ok {
+				if _, set := object[name]; !set {
+					var copied interface{}
+					toGenericJSON(defaultValue, &copied)
+					object[name] = copied
+				}
+			}
+		}
+	}
+
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if _, set := object[fmt.Sprint(name)]; !set {
+				v.fail(path, "missing required property '%s'", name)
+			}
+		}
+	}
+
+	if limit, ok := schemaInt(schema, "minProperties"); ok && len(object) < limit {
+		v.fail(path, "must have at least %d properties", limit)
+	}
+	if limit, ok := schemaInt(schema, "maxProperties"); ok && len(object) > limit {
+		v.fail(path, "must have at most %d properties", limit)
+	}
+
+	patternProperties, _ := schema["patternProperties"].(map[string]interface{})
+	for _, name := range sortedMapKeys(object) {
+		propertyPath := joinPath(path, name)
+		matched := false
+
+		if propertySchema, ok := properties[name]; ok {
+			matched = true
+			object[name] = v.validate(propertySchema, object[name], propertyPath)
+		}
+		for _, pattern := range sortedMapKeys(patternProperties) {
+			if re, err := regexp.Compile(pattern); err == nil && re.MatchString(name) {
+				matched = true
+				object[name] = v.validate(patternProperties[pattern], object[name], propertyPath)
+			}
+		}
+
+		if !matched {
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					v.fail(path, "property '%s' is not allowed", name)
+				}
+			case map[string]interface{}:
+				object[name] = v.validate(additional, object[name], propertyPath)
+			}
+		}
+	}
+
+	return object
+}
+
+func (v *schemaValidator) validateArray(schema map[string]interface{}, array []interface{}, path string) []interface{} {
+	if limit, ok := schemaInt(schema, "minItems"); ok && len(array) < limit {
+		v.fail(path, "must have at least %d items", limit)
+	}
+	if limit, ok := schemaInt(schema, "maxItems"); ok && len(array) > limit {
+		v.fail(path, "must have at most %d items", limit)
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		for i := range array {
+			for j := 0; j < i; j++ {
+				if reflect.DeepEqual(array[i], array[j]) {
+					v.fail(path, "items %d and %d must not be equal", j, i)
+				}
+			}
+		}
+	}
+
+	switch items := schema["items"].(type) {
+	case map[string]interface{}:
+		for i := range array {
+			array[i] = v.validate(items, array[i], joinPath(path, strconv.Itoa(i)))
+		}
+	case []interface{}:
+		for i := range array {
+			itemPath := joinPath(path, strconv.Itoa(i))
+			if i < len(items) {
+				array[i] = v.validate(items[i], array[i], itemPath)
+				continue
+			}
+			switch additional := schema["additionalItems"].(type) {
+			case bool:
+				if !additional {
+					v.fail(path, "must have at most %d items", len(items))
+					return array
+				}
+			case map[string]interface{}:
+				array[i] = v.validate(additional, array[i], itemPath)
+			}
+		}
+	}
+	return array
+}
+
+func (v *schemaValidator) validateString(schema map[string]interface{}, s, path string) {
+	length := utf8.RuneCountInString(s)
+	if limit, ok := schemaInt(schema, "minLength"); ok && length < limit {
+		v.fail(path, "must be at least %d characters long", limit)
+	}
+	if limit, ok := schemaInt(schema, "maxLength"); ok && length > limit {
+		v.fail(path, "must be at most %d characters long", limit)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			v.fail(path, "invalid pattern '%s' in schema", pattern)
+		} else if !re.MatchString(s) {
+			v.fail(path, "must match pattern '%s'", pattern)
+		}
+	}
+}
+
+func (v *schemaValidator) validateNumber(schema map[string]interface{}, n float64, path string) {
+	if minimum, ok := schema["minimum"].(float64); ok {
+		if exclusive, _ := schema["exclusiveMinimum"].(bool); exclusive && n <= minimum {
+			v.fail(path, "must be greater than %v", minimum)
+		} else if n < minimum {
+			v.fail(path, "must be at least %v", minimum)
+		}
+	}
+	if maximum, ok := schema["maximum"].(float64); ok {
+		if exclusive, _ := schema["exclusiveMaximum"].(bool); exclusive && n >= maximum {
+			v.fail(path, "must be less than %v", maximum)
+		} else if n > maximum {
+			v.fail(path, "must be at most %v", maximum)
+		}
+	}
+	if multipleOf, ok := schema["multipleOf"].(float64); ok && multipleOf > 0 {
+		if quotient := n / multipleOf; quotient != math.Trunc(quotient) {
+			v.fail(path, "must be a multiple of %v", multipleOf)
+		}
+	}
+}
+
+func (v *schemaValidator) validateCombinators(schema map[string]interface{}, value interface{}, path string) {
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, subschema := range allOf {
+			v.validate(subschema, value, path)
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		if v.countMatches(anyOf, value, path) == 0 {
+			v.fail(path, "must match at least one of the allowed schemas")
+		}
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		if v.countMatches(oneOf, value, path) != 1 {
+			v.fail(path, "must match exactly one of the allowed schemas")
+		}
+	}
+	if not, ok := schema["not"]; ok {
+		if v.countMatches([]interface{}{not}, value, path) == 1 {
+			v.fail(path, "must not match the disallowed schema")
+		}
+	}
+}
+
+// countMatches returns how many of the schemas value matches, without
+// applying defaults or recording errors.
+func (v *schemaValidator) countMatches(schemas []interface{}, value interface{}, path string) int {
+	matches := 0
+	for _, subschema := range schemas {
+		var copied interface{}
+		toGenericJSON(value, &copied)
+		probe := &schemaValidator{root: v.root, following: v.following}
+		probe.validate(subschema, copied, path)
+		if len(probe.errors) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+// resolve resolves a $ref to a location in the root schema, such as
+// #/definitions/backup
+func (v *schemaValidator) resolve(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported $ref '%s', only references within the schema are supported", ref)
+	}
+
+	node := v.root
+	for _, token := range strings.Split(strings.TrimPrefix(strings.TrimPrefix(ref, "#"), "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot resolve $ref '%s'", ref)
+		}
+		if node, ok = object[token]; !ok {
+			return nil, fmt.Errorf("cannot resolve $ref '%s'", ref)
+		}
+	}
+	return node, nil
+}
+
+func matchesType(types, value interface{}) bool {
+	switch t := types.(type) {
+	case string:
+		return matchesSingleType(t, value)
+	case []interface{}:
+		for _, single := range t {
+			if name, ok := single.(string); ok && matchesSingleType(name, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesSingleType(name string, value interface{}) bool {
+	actual := jsonType(value)
+	switch name {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		return actual == "number"
+	case "any":
+		return true
+	}
+	return actual == name
+}
+
+func describeTypes(types interface{}) string {
+	if list, ok := types.([]interface{}); ok {
+		var names []string
+		for _, name := range list {
+			names = append(names, fmt.Sprint(name))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(types)
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func containsJSON(values []interface{}, value interface{}) bool {
+	for _, candidate := range values {
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func schemaInt(schema map[string]interface{}, keyword string) (int, bool) {
+	n, ok := schema[keyword].(float64)
+	return int(n), ok
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,123 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sort"
+)
+
+type loggerContextKey struct{}
+
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// LoggerFromContext returns the logger of the invocation ctx belongs to,
+// which carries its correlation fields: action, invocation_id and, once the
+// input params are parsed, the deployment, instance or binding they are
+// about. Context-aware implementers can use it to log alongside the
+// handler. It discards everything when CommandLineHandler.Logger is not set.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return discardLogger
+}
+
+func contextWithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+func newInvocationID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// inputCorrelationAttrs returns the fields identifying what the input
+// params of action are about.
+func inputCorrelationAttrs(action string, inputParams InputParams) []any {
+	switch action {
+	case "generate-manifest":
+		var serviceDeployment ServiceDeployment
+		json.Unmarshal([]byte(inputParams.GenerateManifest.ServiceDeployment), &serviceDeployment)
+		return []any{slog.String("deployment_name", serviceDeployment.DeploymentName)}
+	case "create-binding":
+		return []any{slog.String("binding_id", inputParams.CreateBinding.BindingId)}
+	case "delete-binding":
+		return []any{slog.String("binding_id", inputParams.DeleteBinding.BindingId)}
+	case "dashboard-url":
+		return []any{slog.String("instance_id", inputParams.DashboardUrl.InstanceId)}
+	}
+	return nil
+}
+
+// inputSummary describes the input params of action without their
+// contents: secrets, manifests and parameter values are never logged, only
+// whether they were passed and the names of the parameters.
+func inputSummary(action string, inputParams InputParams) slog.Attr {
+	var attrs []any
+	switch action {
+	case "generate-manifest":
+		params := inputParams.GenerateManifest
+		attrs = append(attrs,
+			slog.Bool("previous_plan", params.PreviousPlan != "" && params.PreviousPlan != "null"),
+			slog.Bool("previous_manifest", params.PreviousManifest != ""),
+			slog.Any("previous_secrets", secretNames(params.PreviousSecrets)),
+			slog.Bool("previous_configs", params.PreviousConfigs != ""),
+			slog.Bool("uaa_client", params.ServiceInstanceUAAClient != ""),
+		)
+		attrs = append(attrs, requestParameterAttrs(params.RequestParameters)...)
+	case "create-binding":
+		params := inputParams.CreateBinding
+		attrs = append(attrs, slog.Any("secrets", secretNames(params.Secrets)), slog.Bool("dns_addresses", params.DNSAddresses != ""))
+		attrs = append(attrs, requestParameterAttrs(params.RequestParameters)...)
+	case "delete-binding":
+		params := inputParams.DeleteBinding
+		attrs = append(attrs, slog.Any("secrets", secretNames(params.Secrets)), slog.Bool("dns_addresses", params.DNSAddresses != ""))
+		attrs = append(attrs, requestParameterAttrs(params.RequestParameters)...)
+	}
+	if inputParams.Timeout != "" {
+		attrs = append(attrs, slog.String("timeout", inputParams.Timeout))
+	}
+	return slog.Group("input", attrs...)
+}
+
+// secretNames returns the names of the secrets in secretsJSON, but not
+// their values.
+func secretNames(secretsJSON string) []string {
+	var secrets ManifestSecrets
+	json.Unmarshal([]byte(secretsJSON), &secrets)
+	names := []string{}
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func requestParameterAttrs(requestParamsJSON string) []any {
+	var requestParams RequestParameters
+	json.Unmarshal([]byte(requestParamsJSON), &requestParams)
+	attrs := []any{slog.Any("parameters", sortedMapKeys(requestParams.ArbitraryParams()))}
+	if planID, ok := requestParams["plan_id"].(string); ok {
+		attrs = append(attrs, slog.String("plan_id", planID))
+	}
+	return attrs
+}
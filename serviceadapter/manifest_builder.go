@@ -0,0 +1,356 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+)
+
+// ManifestBuilder builds the manifest of a service deployment, checking the
+// releases, stemcells, instance groups and jobs that are referenced as they
+// are added. Its methods can be chained: the first error is kept and
+// returned by Build, and later calls do nothing.
+type ManifestBuilder struct {
+	manifest        bosh.BoshManifest
+	serviceReleases ServiceReleases
+	ignored         []string
+	err             error
+}
+
+// NewManifestBuilder returns a builder for a manifest with the name, the
+// releases and the stemcells of serviceDeployment. Stemcells are aliased by
+// their OS, and instance groups use the first one unless they set another.
+func NewManifestBuilder(serviceDeployment ServiceDeployment) *ManifestBuilder {
+	b := &ManifestBuilder{
+		manifest: bosh.BoshManifest{
+			Name:           serviceDeployment.DeploymentName,
+			Releases:       []bosh.Release{},
+			Stemcells:      []bosh.Stemcell{},
+			InstanceGroups: []bosh.InstanceGroup{},
+		},
+		serviceReleases: serviceDeployment.Releases,
+	}
+	for _, release := range serviceDeployment.Releases {
+		if b.release(release.Name) != nil {
+			return b.fail("release '%s' is provided more than once", release.Name)
+		}
+		b.manifest.Releases = append(b.manifest.Releases, bosh.Release{Name: release.Name, Version: release.Version})
+	}
+	for _, stemcell := range serviceDeployment.Stemcells {
+		if b.stemcell(stemcell.OS) != nil {
+			return b.fail("stemcell OS '%s' is provided more than once", stemcell.OS)
+		}
+		b.manifest.Stemcells = append(b.manifest.Stemcells, bosh.Stemcell{
+			Alias:   stemcell.OS,
+			OS:      stemcell.OS,
+			Version: stemcell.Version,
+			Name:    stemcell.Name,
+		})
+	}
+	return b
+}
+
+// AddPlanInstanceGroups adds the instance groups of plan that are in
+// deploymentInstanceGroupsToJobs, with their jobs and no properties, as
+// GenerateInstanceGroupsWithNoProperties does, and the update block of plan.
+func (b *ManifestBuilder) AddPlanInstanceGroups(plan Plan, deploymentInstanceGroupsToJobs map[string][]string) *ManifestBuilder {
+	return b.AddMappedInstanceGroups(plan, InstanceGroupMapping{Jobs: deploymentInstanceGroupsToJobs})
+}
+
+// AddMappedInstanceGroups adds the instance groups generated from plan by
+// GenerateInstanceGroups, and the update block of plan. The stemcell of the
+// mapping defaults to the first one. The plan instance groups the mapping
+// ignores are returned by IgnoredInstanceGroups.
+func (b *ManifestBuilder) AddMappedInstanceGroups(plan Plan, mapping InstanceGroupMapping) *ManifestBuilder {
+	if b.err != nil {
+		return b
+	}
+	if mapping.Stemcell == "" {
+		if len(b.manifest.Stemcells) == 0 {
+			return b.fail("no stemcells provided")
+		}
+		mapping.Stemcell = b.manifest.Stemcells[0].Alias
+	}
+	instanceGroups, ignored, err := GenerateInstanceGroups(plan.InstanceGroups, b.serviceReleases, mapping)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	b.ignored = append(b.ignored, ignored...)
+	for _, instanceGroup := range instanceGroups {
+		b.AddInstanceGroup(instanceGroup)
+	}
+	if plan.Update != nil {
+		b.WithUpdate(&bosh.Update{
+			Canaries:                      plan.Update.Canaries,
+			CanaryWatchTime:               plan.Update.CanaryWatchTime,
+			UpdateWatchTime:               plan.Update.UpdateWatchTime,
+			MaxInFlight:                   plan.Update.MaxInFlight,
+			Serial:                        plan.Update.Serial,
+			InitialDeployAZUpdateStrategy: plan.Update.InitialDeployAZUpdateStrategy,
+		})
+	}
+	return b
+}
+
+// AddInstanceGroup adds instanceGroup. An empty stemcell alias is set to
+// the first stemcell, and jobs with no release to the release providing
+// them.
+func (b *ManifestBuilder) AddInstanceGroup(instanceGroup bosh.InstanceGroup) *ManifestBuilder {
+	if b.err != nil {
+		return b
+	}
+	if instanceGroup.Name == "" {
+		return b.fail("instance group name must not be empty")
+	}
+	if b.instanceGroup(instanceGroup.Name) != nil {
+		return b.fail("instance group '%s' is added more than once", instanceGroup.Name)
+	}
+	if instanceGroup.Stemcell == "" && len(b.manifest.Stemcells) > 0 {
+		instanceGroup.Stemcell = b.manifest.Stemcells[0].Alias
+	}
+	if b.stemcell(instanceGroup.Stemcell) == nil {
+		return b.fail("instance group '%s': stemcell '%s' is not provided", instanceGroup.Name, instanceGroup.Stemcell)
+	}
+
+	jobs := instanceGroup.Jobs
+	instanceGroup.Jobs = nil
+	b.manifest.InstanceGroups = append(b.manifest.InstanceGroups, instanceGroup)
+	for _, job := range jobs {
+		b.AddJob(instanceGroup.Name, job)
+	}
+	return b
+}
+
+// AddJob adds job to the instance group instanceGroup. A job with no
+// release is set to the release providing it.
+func (b *ManifestBuilder) AddJob(instanceGroup string, job bosh.Job) *ManifestBuilder {
+	if b.err != nil {
+		return b
+	}
+	group := b.instanceGroup(instanceGroup)
+	if group == nil {
+		return b.fail("instance group '%s' is not added", instanceGroup)
+	}
+	if job, err := b.resolveJob(job); err != nil {
+		b.err = fmt.Errorf("instance group '%s': %s", instanceGroup, err)
+	} else if findJob(group.Jobs, job.Name) != nil {
+		b.fail("instance group '%s': job '%s' is added more than once", instanceGroup, job.Name)
+	} else {
+		group.Jobs = append(group.Jobs, job)
+	}
+	return b
+}
+
+// AddJobProperties sets properties on job in instanceGroup, replacing any
+// property already set with the same key.
+func (b *ManifestBuilder) AddJobProperties(instanceGroup, job string, properties map[string]interface{}) *ManifestBuilder {
+	return b.UpdateJob(instanceGroup, job, func(j bosh.Job) bosh.Job {
+		if j.Properties == nil {
+			j.Properties = map[string]interface{}{}
+		}
+		for key, value := range properties {
+			j.Properties[key] = value
+		}
+		return j
+	})
+}
+
+// UpdateJob replaces job in instanceGroup with what update returns, for
+// instance to add links with the helpers of bosh.Job:
+//
+//	builder.UpdateJob("redis", "redis", func(job bosh.Job) bosh.Job {
+//		return job.AddSharedProvidesLink("redis")
+//	})
+func (b *ManifestBuilder) UpdateJob(instanceGroup, job string, update func(bosh.Job) bosh.Job) *ManifestBuilder {
+	if b.err != nil {
+		return b
+	}
+	group := b.instanceGroup(instanceGroup)
+	if group == nil {
+		return b.fail("instance group '%s' is not added", instanceGroup)
+	}
+	existing := findJob(group.Jobs, job)
+	if existing == nil {
+		return b.fail("instance group '%s': job '%s' is not added", instanceGroup, job)
+	}
+	*existing = update(*existing)
+	return b
+}
+
+// AddVariable adds variable to the variables block.
+func (b *ManifestBuilder) AddVariable(variable bosh.Variable) *ManifestBuilder {
+	if b.err != nil {
+		return b
+	}
+	for _, existing := range b.manifest.Variables {
+		if existing.Name == variable.Name {
+			return b.fail("variable '%s' is added more than once", variable.Name)
+		}
+	}
+	b.manifest.Variables = append(b.manifest.Variables, variable)
+	return b
+}
+
+// AddAddon adds addon. Jobs with no release are set to the release
+// providing them.
+func (b *ManifestBuilder) AddAddon(addon bosh.Addon) *ManifestBuilder {
+	if b.err != nil {
+		return b
+	}
+	for _, existing := range b.manifest.Addons {
+		if existing.Name == addon.Name {
+			return b.fail("addon '%s' is added more than once", addon.Name)
+		}
+	}
+	jobs := make([]bosh.Job, 0, len(addon.Jobs))
+	for _, job := range addon.Jobs {
+		job, err := b.resolveJob(job)
+		if err != nil {
+			b.err = fmt.Errorf("addon '%s': %s", addon.Name, err)
+			return b
+		}
+		jobs = append(jobs, job)
+	}
+	addon.Jobs = jobs
+	b.manifest.Addons = append(b.manifest.Addons, addon)
+	return b
+}
+
+// WithUpdate sets the update block of the manifest.
+func (b *ManifestBuilder) WithUpdate(update *bosh.Update) *ManifestBuilder {
+	if b.err != nil {
+		return b
+	}
+	if update != nil && update.MaxInFlight != nil {
+		if err := bosh.ValidateMaxInFlight(update.MaxInFlight); err != nil {
+			b.err = fmt.Errorf("update: %s", err)
+			return b
+		}
+	}
+	b.manifest.Update = update
+	return b
+}
+
+// WithTags sets the tags of the manifest.
+func (b *ManifestBuilder) WithTags(tags map[string]interface{}) *ManifestBuilder {
+	if b.err == nil {
+		b.manifest.Tags = tags
+	}
+	return b
+}
+
+// WithFeatures sets the features block of the manifest.
+func (b *ManifestBuilder) WithFeatures(features bosh.BoshFeatures) *ManifestBuilder {
+	if b.err == nil {
+		b.manifest.Features = features
+	}
+	return b
+}
+
+// IgnoredInstanceGroups returns the names of the plan instance groups that
+// AddPlanInstanceGroups and AddMappedInstanceGroups did not add, as they
+// were not mapped to any jobs. Adapters may want to tell the operator.
+func (b *ManifestBuilder) IgnoredInstanceGroups() []string {
+	return b.ignored
+}
+
+// Build returns a copy of the manifest, which later calls on the builder do
+// not change, or the first error of the builder or of
+// bosh.BoshManifest.Validate.
+func (b *ManifestBuilder) Build() (bosh.BoshManifest, error) {
+	if b.err != nil {
+		return bosh.BoshManifest{}, b.err
+	}
+	if err := b.manifest.Validate(); err != nil {
+		return bosh.BoshManifest{}, err
+	}
+
+	manifest := b.manifest
+	manifest.Releases = slices.Clone(b.manifest.Releases)
+	manifest.Stemcells = slices.Clone(b.manifest.Stemcells)
+	manifest.Variables = slices.Clone(b.manifest.Variables)
+	manifest.Addons = slices.Clone(b.manifest.Addons)
+	manifest.InstanceGroups = slices.Clone(b.manifest.InstanceGroups)
+	for i, instanceGroup := range manifest.InstanceGroups {
+		jobs := slices.Clone(instanceGroup.Jobs)
+		for j := range jobs {
+			jobs[j].Properties = maps.Clone(jobs[j].Properties)
+		}
+		manifest.InstanceGroups[i].Jobs = jobs
+	}
+	return manifest, nil
+}
+
+func (b *ManifestBuilder) fail(format string, args ...interface{}) *ManifestBuilder {
+	b.err = fmt.Errorf(format, args...)
+	return b
+}
+
+// resolveJob sets the release of job if empty, and checks it is one of the
+// service releases.
+func (b *ManifestBuilder) resolveJob(job bosh.Job) (bosh.Job, error) {
+	if job.Release == "" {
+		release, err := FindReleaseForJob(job.Name, b.serviceReleases)
+		if err != nil {
+			return job, err
+		}
+		job.Release = release.Name
+	} else if b.release(job.Release) == nil {
+		return job, fmt.Errorf("job '%s': release '%s' is not provided", job.Name, job.Release)
+	}
+	return job, nil
+}
+
+func (b *ManifestBuilder) release(name string) *bosh.Release {
+	for i := range b.manifest.Releases {
+		if b.manifest.Releases[i].Name == name {
+			return &b.manifest.Releases[i]
+		}
+	}
+	return nil
+}
+
+func (b *ManifestBuilder) stemcell(alias string) *bosh.Stemcell {
+	for i := range b.manifest.Stemcells {
+		if b.manifest.Stemcells[i].Alias == alias {
+			return &b.manifest.Stemcells[i]
+		}
+	}
+	return nil
+}
+
+func (b *ManifestBuilder) instanceGroup(name string) *bosh.InstanceGroup {
+	for i := range b.manifest.InstanceGroups {
+		if b.manifest.InstanceGroups[i].Name == name {
+			return &b.manifest.InstanceGroups[i]
+		}
+	}
+	return nil
+}
+
+func findJob(jobs []bosh.Job, name string) *bosh.Job {
+	for i := range jobs {
+		if jobs[i].Name == name {
+			return &jobs[i]
+		}
+	}
+	return nil
+}
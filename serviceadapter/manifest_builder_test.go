@@ -0,0 +1,201 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+var _ = Describe("ManifestBuilder", func() {
+	var (
+		serviceDeployment serviceadapter.ServiceDeployment
+		plan              serviceadapter.Plan
+		builder           *serviceadapter.ManifestBuilder
+	)
+
+	BeforeEach(func() {
+		serviceDeployment = serviceadapter.ServiceDeployment{
+			DeploymentName: "service-instance_an-instance",
+			Releases: serviceadapter.ServiceReleases{
+				{Name: "redis", Version: "1.0", Jobs: []string{"redis-server", "sentinel"}},
+				{Name: "bpm", Version: "2.0", Jobs: []string{"bpm"}},
+			},
+			Stemcells: []serviceadapter.Stemcell{{OS: "ubuntu-jammy", Version: "1.1"}},
+		}
+		plan = serviceadapter.Plan{
+			InstanceGroups: []serviceadapter.InstanceGroup{{
+				Name:      "redis",
+				VMType:    "small",
+				Instances: 2,
+				Networks:  []string{"default"},
+				AZs:       []string{"z1"},
+			}},
+			Update: &serviceadapter.Update{Canaries: 1, MaxInFlight: 2, CanaryWatchTime: "1000", UpdateWatchTime: "1000"},
+		}
+		builder = serviceadapter.NewManifestBuilder(serviceDeployment)
+	})
+
+	It("builds a manifest from the service deployment and plan", func() {
+		manifest, err := builder.
+			AddPlanInstanceGroups(plan, map[string][]string{"redis": {"redis-server", "bpm"}}).
+			AddJobProperties("redis", "redis-server", map[string]interface{}{"password": "((password))"}).
+			UpdateJob("redis", "redis-server", func(job bosh.Job) bosh.Job {
+				return job.AddSharedProvidesLink("redis")
+			}).
+			AddVariable(bosh.PasswordVariable("password", bosh.PasswordOptions{})).
+			AddAddon(bosh.Addon{Name: "monitoring", Jobs: []bosh.Job{{Name: "sentinel"}}}).
+			WithTags(map[string]interface{}{"product": "redis"}).
+			Build()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest.Name).To(Equal("service-instance_an-instance"))
+		Expect(manifest.Releases).To(Equal([]bosh.Release{{Name: "redis", Version: "1.0"}, {Name: "bpm", Version: "2.0"}}))
+		Expect(manifest.Stemcells).To(Equal([]bosh.Stemcell{{Alias: "ubuntu-jammy", OS: "ubuntu-jammy", Version: "1.1"}}))
+		Expect(manifest.InstanceGroups).To(HaveLen(1))
+
+		instanceGroup := manifest.InstanceGroups[0]
+		Expect(instanceGroup.Stemcell).To(Equal("ubuntu-jammy"))
+		Expect(instanceGroup.Instances).To(Equal(2))
+		Expect(instanceGroup.Jobs).To(HaveLen(2))
+		Expect(instanceGroup.Jobs[0].Release).To(Equal("redis"))
+		Expect(instanceGroup.Jobs[0].Properties).To(Equal(map[string]interface{}{"password": "((password))"}))
+		Expect(instanceGroup.Jobs[0].Provides).To(HaveKey("redis"))
+		Expect(instanceGroup.Jobs[1].Release).To(Equal("bpm"))
+
+		Expect(manifest.Addons[0].Jobs[0].Release).To(Equal("redis"))
+		Expect(manifest.Update.MaxInFlight).To(Equal(2))
+		Expect(manifest.Tags).To(HaveKeyWithValue("product", "redis"))
+	})
+
+	It("adds instance groups generated with a mapping", func() {
+		manifest, err := builder.AddMappedInstanceGroups(plan, serviceadapter.InstanceGroupMapping{
+			Jobs: map[string][]string{"redis": {"redis-server"}},
+			JobProperties: func(instanceGroup serviceadapter.InstanceGroup, job string) (map[string]interface{}, error) {
+				return map[string]interface{}{"port": 6379}, nil
+			},
+			Overrides: map[string]serviceadapter.InstanceGroupOverrides{
+				"redis": {Env: map[string]interface{}{"bosh": map[string]interface{}{"keep_root_password": true}}},
+			},
+		}).Build()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest.InstanceGroups[0].Stemcell).To(Equal("ubuntu-jammy"))
+		Expect(manifest.InstanceGroups[0].Jobs[0].Properties).To(Equal(map[string]interface{}{"port": 6379}))
+		Expect(manifest.InstanceGroups[0].Env).To(HaveKey("bosh"))
+		Expect(builder.IgnoredInstanceGroups()).To(BeEmpty())
+	})
+
+	It("returns the plan instance groups that were not mapped", func() {
+		plan.InstanceGroups = append(plan.InstanceGroups, serviceadapter.InstanceGroup{Name: "proxy", Instances: 1})
+
+		_, err := builder.AddPlanInstanceGroups(plan, map[string][]string{"redis": {"redis-server"}}).Build()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(builder.IgnoredInstanceGroups()).To(Equal([]string{"proxy"}))
+	})
+
+	It("adds instance groups on the first stemcell by default", func() {
+		manifest, err := builder.AddInstanceGroup(bosh.InstanceGroup{
+			Name:      "redis",
+			Instances: 1,
+			AZs:       []string{"z1"},
+			Networks:  []bosh.Network{{Name: "default"}},
+			Jobs:      []bosh.Job{{Name: "redis-server"}},
+		}).Build()
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifest.InstanceGroups[0].Stemcell).To(Equal("ubuntu-jammy"))
+		Expect(manifest.InstanceGroups[0].Jobs).To(Equal([]bosh.Job{{Name: "redis-server", Release: "redis"}}))
+	})
+
+	It("builds a manifest that later calls do not change", func() {
+		builder.
+			AddPlanInstanceGroups(plan, map[string][]string{"redis": {"redis-server"}}).
+			AddJobProperties("redis", "redis-server", map[string]interface{}{"port": 6379})
+		manifest, err := builder.Build()
+		Expect(err).NotTo(HaveOccurred())
+
+		builder.
+			AddJobProperties("redis", "redis-server", map[string]interface{}{"port": 6380}).
+			UpdateJob("redis", "redis-server", func(job bosh.Job) bosh.Job {
+				return job.AddSharedProvidesLink("redis")
+			})
+
+		Expect(manifest.InstanceGroups[0].Jobs[0].Properties).To(Equal(map[string]interface{}{"port": 6379}))
+		Expect(manifest.InstanceGroups[0].Jobs[0].Provides).To(BeEmpty())
+	})
+
+	It("keeps the first error and ignores later calls", func() {
+		_, err := builder.
+			AddPlanInstanceGroups(plan, map[string][]string{"redis": {"redis-server"}}).
+			AddJob("redis", bosh.Job{Name: "unknown-job"}).
+			AddJob("missing-group", bosh.Job{Name: "redis-server"}).
+			Build()
+
+		Expect(err).To(MatchError("instance group 'redis': job 'unknown-job' not provided"))
+	})
+
+	DescribeTable("checking references as they are added",
+		func(build func(*serviceadapter.ManifestBuilder) *serviceadapter.ManifestBuilder, message string) {
+			_, err := build(builder.AddPlanInstanceGroups(plan, map[string][]string{"redis": {"redis-server"}})).Build()
+			Expect(err).To(MatchError(message))
+		},
+		Entry("instance groups added twice", func(b *serviceadapter.ManifestBuilder) *serviceadapter.ManifestBuilder {
+			return b.AddInstanceGroup(bosh.InstanceGroup{Name: "redis"})
+		}, "instance group 'redis' is added more than once"),
+		Entry("undeclared stemcells", func(b *serviceadapter.ManifestBuilder) *serviceadapter.ManifestBuilder {
+			return b.AddInstanceGroup(bosh.InstanceGroup{Name: "sentinel", Stemcell: "windows"})
+		}, "instance group 'sentinel': stemcell 'windows' is not provided"),
+		Entry("undeclared releases", func(b *serviceadapter.ManifestBuilder) *serviceadapter.ManifestBuilder {
+			return b.AddJob("redis", bosh.Job{Name: "sentinel", Release: "sentinel"})
+		}, "instance group 'redis': job 'sentinel': release 'sentinel' is not provided"),
+		Entry("jobs added twice", func(b *serviceadapter.ManifestBuilder) *serviceadapter.ManifestBuilder {
+			return b.AddJob("redis", bosh.Job{Name: "redis-server"})
+		}, "instance group 'redis': job 'redis-server' is added more than once"),
+		Entry("properties of jobs not added", func(b *serviceadapter.ManifestBuilder) *serviceadapter.ManifestBuilder {
+			return b.AddJobProperties("redis", "sentinel", map[string]interface{}{"port": 26379})
+		}, "instance group 'redis': job 'sentinel' is not added"),
+		Entry("variables added twice", func(b *serviceadapter.ManifestBuilder) *serviceadapter.ManifestBuilder {
+			return b.AddVariable(bosh.Variable{Name: "password"}).AddVariable(bosh.Variable{Name: "password"})
+		}, "variable 'password' is added more than once"),
+		Entry("addon jobs not provided", func(b *serviceadapter.ManifestBuilder) *serviceadapter.ManifestBuilder {
+			return b.AddAddon(bosh.Addon{Name: "monitoring", Jobs: []bosh.Job{{Name: "node-exporter"}}})
+		}, "addon 'monitoring': job 'node-exporter' not provided"),
+		Entry("invalid max_in_flight", func(b *serviceadapter.ManifestBuilder) *serviceadapter.ManifestBuilder {
+			return b.WithUpdate(&bosh.Update{MaxInFlight: "all"})
+		}, "update: MaxInFlight must be either an integer or a percentage. Got all"),
+	)
+
+	It("validates the manifest it builds", func() {
+		_, err := builder.
+			AddPlanInstanceGroups(plan, map[string][]string{"redis": {"redis-server"}}).
+			AddJobProperties("redis", "redis-server", map[string]interface{}{"password": "((password))"}).
+			Build()
+
+		Expect(err).To(MatchError("invalid manifest: /instance_groups/name=redis/jobs/name=redis-server/properties/password: variable 'password' is not declared in variables"))
+	})
+
+	It("rejects service deployments providing a stemcell OS twice", func() {
+		serviceDeployment.Stemcells = append(serviceDeployment.Stemcells, serviceadapter.Stemcell{OS: "ubuntu-jammy", Version: "1.2"})
+
+		_, err := serviceadapter.NewManifestBuilder(serviceDeployment).Build()
+		Expect(err).To(MatchError("stemcell OS 'ubuntu-jammy' is provided more than once"))
+	})
+})
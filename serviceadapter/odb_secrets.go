@@ -0,0 +1,258 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+)
+
+const (
+	defaultPasswordLength = 32
+	defaultHexBytes       = 16
+	defaultRSAKeyBits     = 2048
+
+	passwordAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+var (
+	odbSecretPlaceholderPattern = regexp.MustCompile(`\(\(` + ODBSecretPrefix + `:([^()\s]*)\)\)`)
+	odbSecretNamePattern        = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+)
+
+// ODBSecretPlaceholder returns the ((odb_secret:name)) reference to use in
+// the manifest for the ODB managed secret name.
+func ODBSecretPlaceholder(name string) string {
+	return fmt.Sprintf("((%s:%s))", ODBSecretPrefix, name)
+}
+
+// ODBSecrets declares the ODB managed secrets of a generated manifest. Each
+// declaration returns the placeholder to put in job properties, and the
+// value of the secret is taken from the previous secrets if the previous
+// deployment had it, or generated otherwise, so that updates do not rotate
+// secrets. Generation errors are returned by AddTo.
+type ODBSecrets struct {
+	previous ManifestSecrets
+	values   ODBManagedSecrets
+	errs     []error
+}
+
+// NewODBSecrets returns an ODBSecrets reusing the values of
+// GenerateManifestParams.PreviousSecrets.
+func NewODBSecrets(previousSecrets ManifestSecrets) *ODBSecrets {
+	return &ODBSecrets{previous: previousSecrets, values: ODBManagedSecrets{}}
+}
+
+// Password declares a secret of 32 random alphanumeric characters.
+func (s *ODBSecrets) Password(name string) string {
+	return s.Declare(name, func() (interface{}, error) {
+		return randomString(passwordAlphabet, defaultPasswordLength)
+	})
+}
+
+// Hex declares a secret of bytes random bytes, hex encoded. It defaults to
+// 16 bytes when bytes is not positive.
+func (s *ODBSecrets) Hex(name string, bytes int) string {
+	if bytes <= 0 {
+		bytes = defaultHexBytes
+	}
+	return s.Declare(name, func() (interface{}, error) {
+		b := make([]byte, bytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		return hex.EncodeToString(b), nil
+	})
+}
+
+// RSAKey declares a PEM encoded PKCS #1 RSA private key of bits bits. It
+// defaults to 2048 bits when bits is not positive. The public key can be
+// derived from the value with RSAPublicKeyPEM.
+func (s *ODBSecrets) RSAKey(name string, bits int) string {
+	if bits <= 0 {
+		bits = defaultRSAKeyBits
+	}
+	return s.Declare(name, func() (interface{}, error) {
+		key, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, err
+		}
+		return string(pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		})), nil
+	})
+}
+
+// Declare declares a secret whose value is generated by generate when the
+// previous secrets do not have it. Declaring a name again returns the same
+// placeholder and keeps the first value.
+func (s *ODBSecrets) Declare(name string, generate func() (interface{}, error)) string {
+	placeholder := ODBSecretPlaceholder(name)
+	if _, ok := s.values[name]; ok {
+		return placeholder
+	}
+	if !odbSecretNamePattern.MatchString(name) {
+		s.errs = append(s.errs, fmt.Errorf("invalid ODB managed secret name %q", name))
+		return placeholder
+	}
+
+	if value, ok := s.Previous(name); ok {
+		s.values[name] = value
+		return placeholder
+	}
+	value, err := generate()
+	if err != nil {
+		s.errs = append(s.errs, errors.Wrapf(err, "generating ODB managed secret %q", name))
+		return placeholder
+	}
+	s.values[name] = value
+	return placeholder
+}
+
+// Previous returns the value the secret name had in the previous
+// deployment. ODB passes it keyed by either the placeholder itself or the
+// path it stored the secret at, ((/odb/<service>/<deployment>/<name>)). It
+// is not found when several paths end with name.
+func (s *ODBSecrets) Previous(name string) (string, bool) {
+	if value, ok := s.previous[ODBSecretPlaceholder(name)]; ok {
+		return value, true
+	}
+	return secretByName(s.previous, "((/odb/", name)
+}
+
+// Value returns the value of the declared secret name, for adapters that
+// need it themselves, such as to derive a public key.
+func (s *ODBSecrets) Value(name string) (interface{}, bool) {
+	value, ok := s.values[name]
+	return value, ok
+}
+
+// Secrets returns the values of the declared secrets.
+func (s *ODBSecrets) Secrets() ODBManagedSecrets {
+	secrets := ODBManagedSecrets{}
+	for name, value := range s.values {
+		secrets[name] = value
+	}
+	return secrets
+}
+
+// AddTo adds the declared secrets to the ODBManagedSecrets of output, then
+// checks with VerifyODBSecrets that the manifest references no undeclared
+// secret. It returns any error from declaring the secrets first.
+func (s *ODBSecrets) AddTo(output *GenerateManifestOutput) error {
+	if len(s.errs) > 0 {
+		return s.errs[0]
+	}
+	if output.ODBManagedSecrets == nil {
+		output.ODBManagedSecrets = ODBManagedSecrets{}
+	}
+	for name, value := range s.values {
+		output.ODBManagedSecrets[name] = value
+	}
+	return VerifyODBSecrets(*output)
+}
+
+// VerifyODBSecrets checks that every ((odb_secret:name)) placeholder in the
+// manifest of output has a value in its ODBManagedSecrets, as ODB fails the
+// deployment otherwise.
+func VerifyODBSecrets(output GenerateManifestOutput) error {
+	missing, err := missingODBSecrets(output.Manifest, output.ODBManagedSecrets)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("manifest references ODB managed secrets with no value: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func missingODBSecrets(manifest bosh.BoshManifest, secrets ODBManagedSecrets) ([]string, error) {
+	manifestBytes, err := yaml.Marshal(manifest)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshalling bosh manifest")
+	}
+	seen := map[string]bool{}
+	var missing []string
+	for _, match := range odbSecretPlaceholderPattern.FindAllStringSubmatch(string(manifestBytes), -1) {
+		name := match[1]
+		if _, ok := secrets[name]; ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		missing = append(missing, name)
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
+
+// RSAPublicKeyPEM returns the PEM encoded PKIX public key of a private key
+// declared with RSAKey.
+func RSAPublicKeyPEM(privateKeyPEM string) (string, error) {
+	block, _ := pem.Decode([]byte(privateKeyPEM))
+	if block == nil {
+		return "", errors.New("no PEM data found in private key")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return "", errors.Wrap(err, "parsing private key")
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", errors.Wrap(err, "marshalling public key")
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})), nil
+}
+
+// secretByName returns the value of the only absolute reference in secrets
+// starting with prefix whose last path segment is name.
+func secretByName(secrets ManifestSecrets, prefix, name string) (string, bool) {
+	var value string
+	found := 0
+	for reference, v := range secrets {
+		if strings.HasPrefix(reference, prefix) && strings.HasSuffix(reference, "/"+name+"))") {
+			value = v
+			found++
+		}
+	}
+	return value, found == 1
+}
+
+func randomString(alphabet string, length int) (string, error) {
+	size := big.NewInt(int64(len(alphabet)))
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", err
+		}
+		b[i] = alphabet[n.Int64()]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,143 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter_test
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+var _ = Describe("ODBSecrets", func() {
+	var secrets *serviceadapter.ODBSecrets
+
+	BeforeEach(func() {
+		secrets = serviceadapter.NewODBSecrets(nil)
+	})
+
+	It("returns placeholders for the declared secrets", func() {
+		Expect(secrets.Password("admin_password")).To(Equal("((odb_secret:admin_password))"))
+		Expect(secrets.Hex("cookie", 8)).To(Equal("((odb_secret:cookie))"))
+		Expect(serviceadapter.ODBSecretPlaceholder("key")).To(Equal("((odb_secret:key))"))
+	})
+
+	It("generates values for new secrets", func() {
+		secrets.Password("password")
+		secrets.Hex("cookie", 8)
+		secrets.RSAKey("key", 1024)
+
+		values := secrets.Secrets()
+		Expect(values["password"]).To(MatchRegexp(`^[a-zA-Z0-9]{32}$`))
+		Expect(values["cookie"]).To(MatchRegexp(`^[0-9a-f]{16}$`))
+
+		block, _ := pem.Decode([]byte(values["key"].(string)))
+		Expect(block).NotTo(BeNil())
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.N.BitLen()).To(Equal(1024))
+
+		publicKey, err := serviceadapter.RSAPublicKeyPEM(values["key"].(string))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(publicKey).To(HavePrefix("-----BEGIN PUBLIC KEY-----"))
+	})
+
+	It("reuses the values of the previous deployment", func() {
+		secrets = serviceadapter.NewODBSecrets(serviceadapter.ManifestSecrets{
+			"((/odb/service-id/service-instance_id/password))": "previous-password",
+			"((odb_secret:cookie))":                            "previous-cookie",
+			"((/some/other/key))":                              "not-an-odb-secret",
+		})
+
+		secrets.Password("password")
+		secrets.Hex("cookie", 8)
+		secrets.Hex("key", 8)
+
+		values := secrets.Secrets()
+		Expect(values["password"]).To(Equal("previous-password"))
+		Expect(values["cookie"]).To(Equal("previous-cookie"))
+		Expect(values["key"]).NotTo(Equal("not-an-odb-secret"))
+	})
+
+	It("does not reuse a value when several previous secrets have the name", func() {
+		secrets = serviceadapter.NewODBSecrets(serviceadapter.ManifestSecrets{
+			"((/odb/service-id/service-instance_a/password))": "a-password",
+			"((/odb/service-id/service-instance_b/password))": "another-password",
+		})
+
+		secrets.Password("password")
+
+		Expect(secrets.Secrets()["password"]).NotTo(Or(Equal("a-password"), Equal("another-password")))
+	})
+
+	It("keeps the first value of a secret declared twice", func() {
+		secrets.Password("password")
+		first, _ := secrets.Value("password")
+		secrets.Hex("password", 4)
+
+		Expect(secrets.Secrets()).To(HaveKeyWithValue("password", first))
+	})
+
+	Describe("AddTo", func() {
+		var output serviceadapter.GenerateManifestOutput
+
+		BeforeEach(func() {
+			output = serviceadapter.GenerateManifestOutput{
+				Manifest: bosh.BoshManifest{
+					Name: "a-deployment",
+					InstanceGroups: []bosh.InstanceGroup{{
+						Name: "redis",
+						Jobs: []bosh.Job{{
+							Name: "redis",
+							Properties: map[string]interface{}{
+								"password": secrets.Password("password"),
+								"cookie":   secrets.Hex("cookie", 0),
+							},
+						}},
+					}},
+				},
+				ODBManagedSecrets: serviceadapter.ODBManagedSecrets{"other": "a-value"},
+			}
+		})
+
+		It("adds the declared secrets to the output", func() {
+			Expect(secrets.AddTo(&output)).To(Succeed())
+
+			Expect(output.ODBManagedSecrets).To(HaveKeyWithValue("other", "a-value"))
+			Expect(output.ODBManagedSecrets).To(HaveKey("password"))
+			Expect(output.ODBManagedSecrets).To(HaveKey("cookie"))
+		})
+
+		It("fails when the manifest references undeclared secrets", func() {
+			output.Manifest.Properties = map[string]interface{}{
+				"token":  "((odb_secret:token))",
+				"tokens": []string{"((odb_secret:token))", "((odb_secret:another_token))"},
+			}
+
+			Expect(secrets.AddTo(&output)).To(MatchError("manifest references ODB managed secrets with no value: another_token, token"))
+		})
+
+		It("fails when a secret name is invalid", func() {
+			secrets.Password("not a name")
+
+			Expect(secrets.AddTo(&output)).To(MatchError(`invalid ODB managed secret name "not a name"`))
+		})
+	})
+})
@@ -0,0 +1,392 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/internal/linediff"
+)
+
+// Recording is an invocation recorded by a CommandLineHandler with a
+// RecordDir. Invocation holds what was passed in and written out, with
+// secrets redacted, unless the handler has a RecordingKey, in which case it
+// is encrypted in Encrypted instead.
+type Recording struct {
+	Action       string              `json:"action"`
+	InvocationID string              `json:"invocation_id"`
+	RecordedAt   time.Time           `json:"recorded_at"`
+	Duration     time.Duration       `json:"duration"`
+	ExitCode     int                 `json:"exit_code"`
+	Invocation   *RecordedInvocation `json:"invocation,omitempty"`
+	Encrypted    []byte              `json:"encrypted,omitempty"`
+}
+
+type RecordedInvocation struct {
+	// Args are the positional arguments of the action, if it was not passed
+	// its input params via stdin.
+	Args        []string    `json:"args"`
+	InputParams InputParams `json:"input_params"`
+	Stdout      string      `json:"stdout"`
+	Stderr      string      `json:"stderr"`
+}
+
+// LoadRecording reads a recording written by a CommandLineHandler.
+func LoadRecording(path string) (Recording, error) {
+	var recording Recording
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return recording, fmt.Errorf("reading recording: %s", err)
+	}
+	if err := json.Unmarshal(data, &recording); err != nil {
+		return recording, fmt.Errorf("unmarshalling recording %s: %s", path, err)
+	}
+	return recording, nil
+}
+
+// Open returns the recorded invocation, decrypting it with key if needed.
+func (r Recording) Open(key []byte) (RecordedInvocation, error) {
+	if r.Encrypted == nil {
+		if r.Invocation == nil {
+			return RecordedInvocation{}, fmt.Errorf("recording has no invocation")
+		}
+		return *r.Invocation, nil
+	}
+
+	var invocation RecordedInvocation
+	if key == nil {
+		return invocation, fmt.Errorf("recording is encrypted, a key is needed to open it")
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return invocation, err
+	}
+	if len(r.Encrypted) < gcm.NonceSize() {
+		return invocation, fmt.Errorf("decrypting recording: too short")
+	}
+	nonce, ciphertext := r.Encrypted[:gcm.NonceSize()], r.Encrypted[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(r.Action))
+	if err != nil {
+		return invocation, fmt.Errorf("decrypting recording: %s", err)
+	}
+	err = json.Unmarshal(plaintext, &invocation)
+	return invocation, err
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recording key: %s", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func (r *Recording) seal(invocation RecordedInvocation, key []byte) error {
+	plaintext, err := json.Marshal(invocation)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	r.Encrypted = gcm.Seal(nonce, nonce, plaintext, []byte(r.Action))
+	return nil
+}
+
+// invocationRecorder collects what a Recording is made of while the
+// handler runs an action.
+type invocationRecorder struct {
+	action       string
+	invocationID string
+	args         []string
+	inputParams  InputParams
+	start        time.Time
+	stdout       bytes.Buffer
+	stderr       bytes.Buffer
+}
+
+func (r *invocationRecorder) recording(err error, redactor *Redactor, key []byte) (Recording, error) {
+	recording := Recording{
+		Action:       r.action,
+		InvocationID: r.invocationID,
+		RecordedAt:   r.start.UTC(),
+		Duration:     time.Since(r.start),
+	}
+	if err != nil {
+		recording.ExitCode = errorDetail(err, nil).ExitCode
+	}
+
+	invocation := RecordedInvocation{
+		Args:        r.args,
+		InputParams: r.inputParams,
+		Stdout:      r.stdout.String(),
+		Stderr:      r.stderr.String(),
+	}
+	if key != nil {
+		return recording, recording.seal(invocation, key)
+	}
+
+	invocation = redactInvocation(invocation, redactor)
+	recording.Invocation = &invocation
+	return recording, nil
+}
+
+func (h CommandLineHandler) record(recorder *invocationRecorder, err error, redactor *Redactor) error {
+	recording, err := recorder.recording(err, redactor, h.RecordingKey)
+	if err != nil {
+		return err
+	}
+	if h.recordSink != nil {
+		h.recordSink(recording)
+		return nil
+	}
+
+	if err := os.MkdirAll(h.RecordDir, 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(recording, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s-%s.json", recording.RecordedAt.Format("20060102T150405.000Z"), recording.Action, recording.InvocationID)
+	return os.WriteFile(filepath.Join(h.RecordDir, name), data, 0600)
+}
+
+// redactInvocation blanks the secrets passed in the input params, whatever
+// their length, and redacts every other secret the redactor knows of.
+func redactInvocation(invocation RecordedInvocation, redactor *Redactor) RecordedInvocation {
+	params := &invocation.InputParams
+	params.GenerateManifest.PreviousSecrets = redactSecretsJSON(params.GenerateManifest.PreviousSecrets)
+	params.CreateBinding.Secrets = redactSecretsJSON(params.CreateBinding.Secrets)
+	params.DeleteBinding.Secrets = redactSecretsJSON(params.DeleteBinding.Secrets)
+	if uaaClient := params.GenerateManifest.ServiceInstanceUAAClient; uaaClient != "" {
+		var client map[string]interface{}
+		if json.Unmarshal([]byte(uaaClient), &client) == nil && client["client_secret"] != nil {
+			client["client_secret"] = Redacted
+			redacted, _ := json.Marshal(client)
+			params.GenerateManifest.ServiceInstanceUAAClient = string(redacted)
+		}
+	}
+
+	for _, field := range []*string{
+		&params.GenerateManifest.ServiceDeployment,
+		&params.GenerateManifest.Plan,
+		&params.GenerateManifest.PreviousPlan,
+		&params.GenerateManifest.PreviousManifest,
+		&params.GenerateManifest.RequestParameters,
+		&params.GenerateManifest.PreviousConfigs,
+		&params.GenerateManifest.ServiceInstanceUAAClient,
+		&params.DashboardUrl.Plan,
+		&params.DashboardUrl.Manifest,
+		&params.CreateBinding.BoshVms,
+		&params.CreateBinding.Manifest,
+		&params.CreateBinding.RequestParameters,
+		&params.CreateBinding.DNSAddresses,
+		&params.DeleteBinding.BoshVms,
+		&params.DeleteBinding.Manifest,
+		&params.DeleteBinding.RequestParameters,
+		&params.DeleteBinding.DNSAddresses,
+		&params.GeneratePlanSchemas.Plan,
+		&invocation.Stdout,
+		&invocation.Stderr,
+	} {
+		*field = redactor.Redact(*field)
+	}
+
+	if params.Custom != nil {
+		custom := map[string]json.RawMessage{}
+		for name, raw := range params.Custom {
+			custom[name] = json.RawMessage(redactor.Redact(string(raw)))
+		}
+		params.Custom = custom
+	}
+
+	args := make([]string, len(invocation.Args))
+	for i, arg := range invocation.Args {
+		args[i] = redactor.Redact(arg)
+	}
+	invocation.Args = args
+	return invocation
+}
+
+func redactSecretsJSON(secretsJSON string) string {
+	var secrets map[string]interface{}
+	if secretsJSON == "" || json.Unmarshal([]byte(secretsJSON), &secrets) != nil {
+		return secretsJSON
+	}
+	for name := range secrets {
+		secrets[name] = Redacted
+	}
+	redacted, _ := json.Marshal(secrets)
+	return string(redacted)
+}
+
+// ReplayResult compares a recorded invocation with its replay.
+type ReplayResult struct {
+	Recorded         RecordedInvocation
+	RecordedExitCode int
+	Replayed         RecordedInvocation
+	ReplayedExitCode int
+}
+
+// Identical tells whether the replay exited with the same code and wrote the
+// same stdout as the recorded invocation. Stderr is not compared, as it
+// holds timings and invocation IDs.
+func (r ReplayResult) Identical() bool {
+	return r.RecordedExitCode == r.ReplayedExitCode && r.Recorded.Stdout == r.Replayed.Stdout
+}
+
+// Diff describes how the replay differs from the recorded invocation, line
+// by line, or returns "" when they are identical.
+func (r ReplayResult) Diff() string {
+	if r.Identical() {
+		return ""
+	}
+	var diff strings.Builder
+	if r.RecordedExitCode != r.ReplayedExitCode {
+		fmt.Fprintf(&diff, "exit code:\n- %d\n+ %d\n", r.RecordedExitCode, r.ReplayedExitCode)
+	}
+	if r.Recorded.Stdout != r.Replayed.Stdout {
+		fmt.Fprintf(&diff, "stdout:\n%s", linediff.Diff(r.Recorded.Stdout, r.Replayed.Stdout))
+	}
+	return diff.String()
+}
+
+// Replay executes the recorded invocation against h and compares the
+// outcome. Recordings made with a RecordingKey are decrypted with the
+// handler's RecordingKey. Replays are not recorded themselves.
+//
+// Secrets of redacted recordings are replayed as [REDACTED], so the
+// outcome may differ for that reason alone, and their stdout is compared
+// with secrets redacted.
+func (h CommandLineHandler) Replay(ctx context.Context, recording Recording) (ReplayResult, error) {
+	recorded, err := recording.Open(h.RecordingKey)
+	if err != nil {
+		return ReplayResult{}, err
+	}
+
+	var stdin io.Reader = strings.NewReader("")
+	if len(recorded.Args) == 0 {
+		inputParamsJSON, err := json.Marshal(recorded.InputParams)
+		if err != nil {
+			return ReplayResult{}, err
+		}
+		stdin = bytes.NewReader(inputParamsJSON)
+	}
+
+	var replay Recording
+	h.RecordDir = ""
+	h.recordSink = func(r Recording) { replay = r }
+
+	args := append([]string{"service-adapter", recording.Action}, recorded.Args...)
+	var stdout bytes.Buffer
+	handleErr := h.HandleWithContext(ctx, args, &stdout, io.Discard, stdin)
+	if replay.Action == "" {
+		// invocations of actions that are not implemented are not recorded
+		result := ReplayResult{
+			Recorded:         recorded,
+			RecordedExitCode: recording.ExitCode,
+			Replayed:         RecordedInvocation{Args: recorded.Args, InputParams: recorded.InputParams, Stdout: stdout.String()},
+		}
+		if handleErr != nil {
+			result.ReplayedExitCode = errorDetail(handleErr, nil).ExitCode
+		}
+		return result, nil
+	}
+
+	replayed, err := replay.Open(h.RecordingKey)
+	if err != nil {
+		return ReplayResult{}, fmt.Errorf("replaying: %s", err)
+	}
+	return ReplayResult{
+		Recorded:         recorded,
+		RecordedExitCode: recording.ExitCode,
+		Replayed:         replayed,
+		ReplayedExitCode: replay.ExitCode,
+	}, nil
+}
+
+// ReplayAction is the replay subcommand, which replays the recording at the
+// path given as its argument and writes the differences to stdout. It fails
+// when the replay is not identical.
+type ReplayAction struct {
+	handler CommandLineHandler
+}
+
+func (a *ReplayAction) IsImplemented() bool {
+	return true
+}
+
+func (a *ReplayAction) Capabilities() ActionCapabilities {
+	return ActionCapabilities{InputProtocols: []string{PositionalArgsProtocol}}
+}
+
+func (a *ReplayAction) ParseArgs(reader io.Reader, args []string) (InputParams, error) {
+	if len(args) != 1 {
+		return InputParams{}, NewMissingArgsError("<recording-path>")
+	}
+	return InputParams{Custom: map[string]json.RawMessage{"recording_path": mustMarshal(args[0])}}, nil
+}
+
+func (a *ReplayAction) Execute(inputParams InputParams, outputWriter io.Writer) error {
+	return a.ExecuteWithContext(context.Background(), inputParams, outputWriter)
+}
+
+func (a *ReplayAction) ExecuteWithContext(ctx context.Context, inputParams InputParams, outputWriter io.Writer) error {
+	var path string
+	if err := json.Unmarshal(inputParams.Custom["recording_path"], &path); err != nil {
+		return CLIHandlerError{ErrorExitCode, "recording path missing"}
+	}
+	recording, err := LoadRecording(path)
+	if err != nil {
+		return CLIHandlerError{ErrorExitCode, err.Error()}
+	}
+	result, err := a.handler.Replay(ctx, recording)
+	if err != nil {
+		return CLIHandlerError{ErrorExitCode, err.Error()}
+	}
+
+	if result.Identical() {
+		fmt.Fprintf(outputWriter, "replayed %s: identical\n", recording.Action)
+		return nil
+	}
+	fmt.Fprintf(outputWriter, "replayed %s: differs\n%s", recording.Action, result.Diff())
+	return CLIHandlerError{ErrorExitCode, fmt.Sprintf("replayed %s differs from the recording", recording.Action)}
+}
+
+func mustMarshal(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
@@ -0,0 +1,228 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	Redacted = "[REDACTED]"
+
+	// minRedactedLength is the length below which values are not redacted,
+	// as replacing them everywhere would mangle the output beyond use.
+	minRedactedLength = 4
+)
+
+// Redactor knows the secret values seen during an invocation and scrubs
+// them from text. The handler redacts everything it writes to stderr, its
+// logs and the errors it reports, but not the output of a successful
+// action. A nil Redactor redacts nothing.
+type Redactor struct {
+	mu       sync.RWMutex
+	secrets  map[string]bool
+	replacer *strings.Replacer
+}
+
+func NewRedactor() *Redactor {
+	return &Redactor{secrets: map[string]bool{}}
+}
+
+type redactorContextKey struct{}
+
+// RedactorFromContext returns the Redactor of the invocation ctx belongs
+// to, so that implementers can add secrets of their own. It returns nil
+// outside of an invocation.
+func RedactorFromContext(ctx context.Context) *Redactor {
+	redactor, _ := ctx.Value(redactorContextKey{}).(*Redactor)
+	return redactor
+}
+
+func contextWithRedactor(ctx context.Context, redactor *Redactor) context.Context {
+	return context.WithValue(ctx, redactorContextKey{}, redactor)
+}
+
+// Add registers secret values. Values shorter than 4 characters are ignored.
+func (r *Redactor) Add(values ...string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, value := range values {
+		if len(value) >= minRedactedLength && !r.secrets[value] {
+			r.secrets[value] = true
+			r.replacer = nil
+		}
+	}
+}
+
+// AddValues registers the strings found in v, a value decoded from JSON or
+// YAML such as the credentials of a binding, as secrets.
+func (r *Redactor) AddValues(v interface{}) {
+	switch value := v.(type) {
+	case string:
+		r.Add(value)
+	case map[string]interface{}:
+		for _, element := range value {
+			r.AddValues(element)
+		}
+	case map[interface{}]interface{}:
+		for _, element := range value {
+			r.AddValues(element)
+		}
+	case []interface{}:
+		for _, element := range value {
+			r.AddValues(element)
+		}
+	case map[string]string:
+		for _, element := range value {
+			r.Add(element)
+		}
+	case ManifestSecrets:
+		r.AddValues(map[string]string(value))
+	case ODBManagedSecrets:
+		r.AddValues(map[string]interface{}(value))
+	case []string:
+		r.Add(value...)
+	}
+}
+
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	return r.stringReplacer().Replace(s)
+}
+
+func (r *Redactor) stringReplacer() *strings.Replacer {
+	r.mu.RLock()
+	replacer := r.replacer
+	r.mu.RUnlock()
+	if replacer != nil {
+		return replacer
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	secrets := make([]string, 0, len(r.secrets))
+	for secret := range r.secrets {
+		secrets = append(secrets, secret)
+	}
+	// longest first, so that a secret containing another is redacted whole
+	sort.Slice(secrets, func(i, j int) bool {
+		if len(secrets[i]) != len(secrets[j]) {
+			return len(secrets[i]) > len(secrets[j])
+		}
+		return secrets[i] < secrets[j]
+	})
+	var oldnew []string
+	for _, secret := range secrets {
+		oldnew = append(oldnew, secret, Redacted)
+	}
+	r.replacer = strings.NewReplacer(oldnew...)
+	return r.replacer
+}
+
+// redactError returns err with its message redacted, or err itself if its
+// message contains no secret.
+func (r *Redactor) redactError(err error) error {
+	if err == nil {
+		return nil
+	}
+	message := r.Redact(err.Error())
+	if message == err.Error() {
+		return err
+	}
+	detail := errorDetail(err, nil)
+	return CLIHandlerError{detail.ExitCode, message}
+}
+
+func (r *Redactor) redactErrorDetail(detail ErrorDetail) ErrorDetail {
+	detail.Message = r.Redact(detail.Message)
+	detail.OperatorDetail = r.Redact(detail.OperatorDetail)
+	for i, cause := range detail.Causes {
+		detail.Causes[i] = r.Redact(cause)
+	}
+	return detail
+}
+
+// redactingWriter redacts every write separately, so a secret split across
+// writes is not redacted.
+type redactingWriter struct {
+	io.Writer
+	redactor *Redactor
+}
+
+func (w redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.Writer, w.redactor.Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// redactingHandler redacts the message and string attributes of log
+// records. Attributes added with WithAttrs are redacted with the secrets
+// known at the time.
+type redactingHandler struct {
+	slog.Handler
+	redactor *Redactor
+}
+
+func (h redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, h.redactor.Redact(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.redactAttr(attr))
+		return true
+	})
+	return h.Handler.Handle(ctx, redacted)
+}
+
+func (h redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.redactAttr(attr)
+	}
+	return redactingHandler{Handler: h.Handler.WithAttrs(redacted), redactor: h.redactor}
+}
+
+func (h redactingHandler) WithGroup(name string) slog.Handler {
+	return redactingHandler{Handler: h.Handler.WithGroup(name), redactor: h.redactor}
+}
+
+func (h redactingHandler) redactAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, h.redactor.Redact(value.String()))
+	case slog.KindGroup:
+		var group []any
+		for _, groupAttr := range value.Group() {
+			group = append(group, h.redactAttr(groupAttr))
+		}
+		return slog.Group(attr.Key, group...)
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			return slog.String(attr.Key, h.redactor.Redact(err.Error()))
+		}
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
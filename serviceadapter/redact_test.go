@@ -0,0 +1,71 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+var _ = Describe("Redactor", func() {
+	var redactor *serviceadapter.Redactor
+
+	BeforeEach(func() {
+		redactor = serviceadapter.NewRedactor()
+	})
+
+	It("redacts every occurrence of the secrets added", func() {
+		redactor.Add("hunter2", "s3cr3t")
+
+		Expect(redactor.Redact("hunter2 and s3cr3t, again hunter2")).To(Equal("[REDACTED] and [REDACTED], again [REDACTED]"))
+	})
+
+	It("redacts secrets containing other secrets whole", func() {
+		redactor.Add("pass", "password-1234")
+
+		Expect(redactor.Redact("password-1234")).To(Equal("[REDACTED]"))
+	})
+
+	It("ignores values too short to be redacted", func() {
+		redactor.Add("", "1", "abc")
+
+		Expect(redactor.Redact("abc 1")).To(Equal("abc 1"))
+	})
+
+	It("adds the strings found in decoded values", func() {
+		redactor.AddValues(map[string]interface{}{
+			"password": "hunter2",
+			"port":     5432,
+			"uris":     []interface{}{"redis://admin@host"},
+			"nested":   map[interface{}]interface{}{"key": "s3cr3t"},
+		})
+		redactor.AddValues(serviceadapter.ManifestSecrets{"/admin": "adminpass"})
+
+		Expect(redactor.Redact("hunter2 5432 redis://admin@host s3cr3t adminpass")).To(Equal("[REDACTED] 5432 [REDACTED] [REDACTED] [REDACTED]"))
+	})
+
+	It("redacts nothing when nil", func() {
+		redactor = serviceadapter.RedactorFromContext(context.Background())
+		Expect(redactor).To(BeNil())
+
+		redactor.Add("hunter2")
+		Expect(redactor.Redact("hunter2")).To(Equal("hunter2"))
+	})
+})
@@ -0,0 +1,76 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// requestParametersValidator validates arbitrary request parameters against
+// the schema the SchemaGenerator returns for the plan.
+type requestParametersValidator struct {
+	schemaGenerator SchemaGenerator
+}
+
+// validate returns a copy of params with the arbitrary parameters validated
+// against the schema chosen by schemaFor and, if applyDefaults is set,
+// defaulted. Parameters that fail validation yield an InvalidParametersError.
+func (r requestParametersValidator) validate(
+	ctx context.Context,
+	plan Plan,
+	schemaFor func(PlanSchema) JSONSchemas,
+	params RequestParameters,
+	applyDefaults bool,
+) (RequestParameters, error) {
+	planSchema, err := generatePlanSchema(ctx, r.schemaGenerator, GeneratePlanSchemaParams{Plan: plan})
+	if err != nil {
+		return nil, fmt.Errorf("generating plan schema to validate parameters: %s", err)
+	}
+	schema := schemaFor(planSchema).Parameters
+	if len(schema) == 0 {
+		return params, nil
+	}
+
+	arbitraryParams, ok := params["parameters"].(map[string]interface{})
+	if params["parameters"] != nil && !ok {
+		return nil, NewInvalidParametersError(errors.New("expected an object"))
+	}
+	if arbitraryParams == nil {
+		arbitraryParams = map[string]interface{}{}
+	}
+
+	validated, problems, err := validateAgainstSchema(schema, arbitraryParams, applyDefaults)
+	if err != nil {
+		return nil, fmt.Errorf("validating parameters: %s", err)
+	}
+	if len(problems) > 0 {
+		return nil, NewInvalidParametersError(errors.New(strings.Join(problems, "; ")))
+	}
+
+	validatedParams := RequestParameters{}
+	for key, value := range params {
+		validatedParams[key] = value
+	}
+	validatedParams["parameters"] = validated
+	return validatedParams, nil
+}
+
+func instanceCreateSchema(s PlanSchema) JSONSchemas { return s.ServiceInstance.Create }
+func instanceUpdateSchema(s PlanSchema) JSONSchemas { return s.ServiceInstance.Update }
+func bindingCreateSchema(s PlanSchema) JSONSchemas  { return s.ServiceBinding.Create }
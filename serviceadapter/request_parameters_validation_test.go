@@ -0,0 +1,309 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter/fakes"
+)
+
+var _ = Describe("Request parameters validation", func() {
+	var (
+		fakeManifestGenerator *fakes.FakeManifestGenerator
+		fakeBinder            *fakes.FakeBinder
+		fakeSchemaGenerator   *fakes.FakeSchemaGenerator
+		outputBuffer          *gbytes.Buffer
+	)
+
+	schemaFrom := func(schemaJSON string) serviceadapter.JSONSchemas {
+		var parameters map[string]interface{}
+		Expect(json.Unmarshal([]byte(schemaJSON), &parameters)).To(Succeed())
+		return serviceadapter.JSONSchemas{Parameters: parameters}
+	}
+
+	createSchema := `{
+		"type": "object",
+		"required": ["billing-account"],
+		"properties": {
+			"billing-account": {"type": "string", "pattern": "^[0-9]+$"},
+			"maxclients": {"type": "integer", "minimum": 1, "maximum": 10000, "default": 1000}
+		}
+	}`
+
+	paramsFrom := func(parametersJSON string) serviceadapter.RequestParameters {
+		var params serviceadapter.RequestParameters
+		Expect(json.Unmarshal([]byte(`{"plan_id": "small", "parameters": `+parametersJSON+`}`), &params)).To(Succeed())
+		return params
+	}
+
+	BeforeEach(func() {
+		fakeManifestGenerator = new(fakes.FakeManifestGenerator)
+		fakeBinder = new(fakes.FakeBinder)
+		fakeSchemaGenerator = new(fakes.FakeSchemaGenerator)
+		outputBuffer = gbytes.NewBuffer()
+
+		fakeSchemaGenerator.GeneratePlanSchemaReturns(serviceadapter.PlanSchema{
+			ServiceInstance: serviceadapter.ServiceInstanceSchema{
+				Create: schemaFrom(createSchema),
+				Update: schemaFrom(`{"type": "object", "properties": {"maxclients": {"type": "integer", "default": 1000}}, "additionalProperties": false}`),
+			},
+			ServiceBinding: serviceadapter.ServiceBindingSchema{
+				Create: schemaFrom(`{"type": "object", "properties": {"role": {"enum": ["read", "write"], "default": "read"}}}`),
+			},
+		}, nil)
+	})
+
+	Describe("generate-manifest", func() {
+		var (
+			action      *serviceadapter.GenerateManifestAction
+			inputParams serviceadapter.InputParams
+		)
+
+		BeforeEach(func() {
+			action = serviceadapter.NewGenerateManifestAction(fakeManifestGenerator).WithRequestParametersValidation(fakeSchemaGenerator)
+			inputParams = serviceadapter.InputParams{
+				GenerateManifest: serviceadapter.GenerateManifestJSONParams{
+					ServiceDeployment: toJson(defaultServiceDeployment()),
+					Plan:              toJson(defaultPlan()),
+					RequestParameters: toJson(paramsFrom(`{"billing-account": "1234"}`)),
+					PreviousManifest:  "",
+					PreviousPlan:      "null",
+				},
+			}
+		})
+
+		It("validates against the create schema for the plan and applies defaults", func() {
+			Expect(action.Execute(inputParams, outputBuffer)).To(Succeed())
+
+			Expect(fakeSchemaGenerator.GeneratePlanSchemaArgsForCall(0).Plan).To(Equal(defaultPlan()))
+			Expect(fakeManifestGenerator.GenerateManifestArgsForCall(0).RequestParams).To(Equal(serviceadapter.RequestParameters{
+				"plan_id":    "small",
+				"parameters": map[string]interface{}{"billing-account": "1234", "maxclients": float64(1000)},
+			}))
+		})
+
+		It("validates and decodes durations with a schema from JSONSchemasFor", func() {
+			type timeoutParams struct {
+				Timeout time.Duration `json:"timeout" default:"30s"`
+			}
+			schemas, err := serviceadapter.JSONSchemasFor(timeoutParams{})
+			Expect(err).NotTo(HaveOccurred())
+			fakeSchemaGenerator.GeneratePlanSchemaReturns(serviceadapter.PlanSchema{
+				ServiceInstance: serviceadapter.ServiceInstanceSchema{Create: schemas},
+			}, nil)
+
+			for parametersJSON, expected := range map[string]time.Duration{`{"timeout": "1m"}`: time.Minute, `{}`: 30 * time.Second} {
+				inputParams.GenerateManifest.RequestParameters = toJson(paramsFrom(parametersJSON))
+				Expect(action.Execute(inputParams, outputBuffer)).To(Succeed())
+
+				var params timeoutParams
+				requestParams := fakeManifestGenerator.GenerateManifestArgsForCall(fakeManifestGenerator.GenerateManifestCallCount() - 1).RequestParams
+				Expect(requestParams.DecodeArbitraryParams(&params)).To(Succeed())
+				Expect(params.Timeout).To(Equal(expected))
+			}
+		})
+
+		It("rejects invalid parameters, telling the user why", func() {
+			inputParams.GenerateManifest.RequestParameters = toJson(paramsFrom(`{"maxclients": 0.5}`))
+
+			err := action.Execute(inputParams, outputBuffer)
+
+			message := "invalid parameters: missing required property 'billing-account'; maxclients: expected integer, got number"
+			Expect(err).To(BeACLIError(serviceadapter.ErrorExitCode, message))
+			Expect(string(outputBuffer.Contents())).To(Equal(message))
+			Expect(fakeManifestGenerator.GenerateManifestCallCount()).To(Equal(0))
+		})
+
+		It("validates updates against the update schema without applying defaults", func() {
+			inputParams.GenerateManifest.PreviousManifest = toYaml(defaultPreviousManifest())
+			inputParams.GenerateManifest.RequestParameters = toJson(paramsFrom(`{}`))
+			Expect(action.Execute(inputParams, outputBuffer)).To(Succeed())
+			Expect(fakeManifestGenerator.GenerateManifestArgsForCall(0).RequestParams.ArbitraryParams()).To(BeEmpty())
+
+			inputParams.GenerateManifest.RequestParameters = toJson(paramsFrom(`{"billing-account": "1234"}`))
+			err := action.Execute(inputParams, outputBuffer)
+			Expect(err).To(BeACLIError(serviceadapter.ErrorExitCode, "invalid parameters: property 'billing-account' is not allowed"))
+		})
+
+		It("does not validate updates without parameters, such as upgrades", func() {
+			inputParams.GenerateManifest.PreviousManifest = toYaml(defaultPreviousManifest())
+			inputParams.GenerateManifest.RequestParameters = toJson(serviceadapter.RequestParameters{})
+
+			Expect(action.Execute(inputParams, outputBuffer)).To(Succeed())
+			Expect(fakeSchemaGenerator.GeneratePlanSchemaCallCount()).To(Equal(0))
+		})
+
+		It("skips validation when the plan has no schema", func() {
+			fakeSchemaGenerator.GeneratePlanSchemaReturns(serviceadapter.PlanSchema{}, nil)
+			inputParams.GenerateManifest.RequestParameters = toJson(paramsFrom(`{"anything": true}`))
+
+			Expect(action.Execute(inputParams, outputBuffer)).To(Succeed())
+		})
+
+		It("fails with a generic error when the schema cannot be generated", func() {
+			fakeSchemaGenerator.GeneratePlanSchemaReturns(serviceadapter.PlanSchema{}, errors.New("no schema for you"))
+
+			err := action.Execute(inputParams, outputBuffer)
+			Expect(err).To(BeACLIError(serviceadapter.ErrorExitCode, "generating plan schema to validate parameters: no schema for you"))
+		})
+
+		It("reports parameters the generator itself rejects to the user", func() {
+			action = serviceadapter.NewGenerateManifestAction(fakeManifestGenerator)
+			fakeManifestGenerator.GenerateManifestReturns(serviceadapter.GenerateManifestOutput{}, serviceadapter.NewInvalidParametersError(errors.New("too big")))
+
+			err := action.Execute(inputParams, outputBuffer)
+			Expect(err).To(BeACLIError(serviceadapter.ErrorExitCode, "invalid parameters: too big"))
+			Expect(string(outputBuffer.Contents())).To(Equal("invalid parameters: too big"))
+		})
+
+		DescribeTable("draft-04 keywords",
+			func(schemaJSON, parametersJSON, message string) {
+				fakeSchemaGenerator.GeneratePlanSchemaReturns(serviceadapter.PlanSchema{
+					ServiceInstance: serviceadapter.ServiceInstanceSchema{Create: schemaFrom(schemaJSON)},
+				}, nil)
+				inputParams.GenerateManifest.RequestParameters = toJson(paramsFrom(parametersJSON))
+
+				err := action.Execute(inputParams, outputBuffer)
+				if message == "" {
+					Expect(err).NotTo(HaveOccurred())
+				} else {
+					Expect(err).To(BeACLIError(serviceadapter.ErrorExitCode, "invalid parameters: "+message))
+				}
+			},
+			Entry("type", `{"properties": {"a": {"type": ["string", "null"]}}}`, `{"a": 1}`, "a: expected string or null, got number"),
+			Entry("enum", `{"properties": {"a": {"enum": ["x", 1]}}}`, `{"a": "y"}`, `a: must be one of "x", 1`),
+			Entry("minLength", `{"properties": {"a": {"minLength": 3}}}`, `{"a": "ab"}`, "a: must be at least 3 characters long"),
+			Entry("maxLength", `{"properties": {"a": {"maxLength": 1}}}`, `{"a": "ab"}`, "a: must be at most 1 characters long"),
+			Entry("pattern", `{"properties": {"a": {"pattern": "^x"}}}`, `{"a": "y"}`, "a: must match pattern '^x'"),
+			Entry("exclusiveMinimum", `{"properties": {"a": {"minimum": 1, "exclusiveMinimum": true}}}`, `{"a": 1}`, "a: must be greater than 1"),
+			Entry("maximum", `{"properties": {"a": {"maximum": 1}}}`, `{"a": 2}`, "a: must be at most 1"),
+			Entry("multipleOf", `{"properties": {"a": {"multipleOf": 5}}}`, `{"a": 12}`, "a: must be a multiple of 5"),
+			Entry("items", `{"properties": {"a": {"items": {"type": "integer"}}}}`, `{"a": [1, "2"]}`, "a.1: expected integer, got string"),
+			Entry("minItems", `{"properties": {"a": {"minItems": 2}}}`, `{"a": [1]}`, "a: must have at least 2 items"),
+			Entry("uniqueItems", `{"properties": {"a": {"uniqueItems": true}}}`, `{"a": [1, 1]}`, "a: items 0 and 1 must not be equal"),
+			Entry("additionalProperties schema", `{"additionalProperties": {"type": "boolean"}}`, `{"a": "yes"}`, "a: expected boolean, got string"),
+			Entry("patternProperties", `{"patternProperties": {"^x-": {"type": "string"}}, "additionalProperties": false}`, `{"x-a": "ok", "b": 1}`, "property 'b' is not allowed"),
+			Entry("maxProperties", `{"maxProperties": 1}`, `{"a": 1, "b": 2}`, "must have at most 1 properties"),
+			Entry("anyOf", `{"properties": {"a": {"anyOf": [{"type": "string"}, {"type": "boolean"}]}}}`, `{"a": 1}`, "a: must match at least one of the allowed schemas"),
+			Entry("oneOf", `{"properties": {"a": {"oneOf": [{"type": "integer"}, {"type": "number"}]}}}`, `{"a": 1}`, "a: must match exactly one of the allowed schemas"),
+			Entry("not", `{"properties": {"a": {"not": {"type": "string"}}}}`, `{"a": "x"}`, "a: must not match the disallowed schema"),
+			Entry("allOf", `{"properties": {"a": {"allOf": [{"minimum": 1}, {"maximum": 3}]}}}`, `{"a": 4}`, "a: must be at most 3"),
+			Entry("$ref", `{"definitions": {"size": {"enum": ["s", "m"]}}, "properties": {"a": {"$ref": "#/definitions/size"}}}`, `{"a": "l"}`, `a: must be one of "s", "m"`),
+			Entry("$ref to itself", `{"$ref": "#"}`, `{}`, "circular $ref '#'"),
+			Entry("circular $refs", `{"definitions": {"a": {"$ref": "#/definitions/b"}, "b": {"allOf": [{"$ref": "#/definitions/a"}]}}, "properties": {"a": {"$ref": "#/definitions/a"}}}`, `{"a": 1}`, "a: circular $ref '#/definitions/a'"),
+			Entry("circular $ref in not", `{"not": {"$ref": "#"}}`, `{}`, "must not match the disallowed schema"),
+			Entry("recursive $ref", `{"definitions": {"node": {"type": "object", "properties": {"children": {"items": {"$ref": "#/definitions/node"}}}}}, "properties": {"tree": {"$ref": "#/definitions/node"}}}`, `{"tree": {"children": [{"children": [1]}]}}`, "tree.children.0.children.0: expected object, got number"),
+			Entry("valid nested params", `{"properties": {"a": {"type": "object", "properties": {"b": {"type": "integer"}}}}}`, `{"a": {"b": 2}}`, ""),
+		)
+	})
+
+	Describe("create-binding", func() {
+		var (
+			action      *serviceadapter.CreateBindingAction
+			inputParams serviceadapter.InputParams
+		)
+
+		BeforeEach(func() {
+			action = serviceadapter.NewCreateBindingAction(fakeBinder).WithRequestParametersValidation(fakeSchemaGenerator)
+			inputParams = serviceadapter.InputParams{
+				CreateBinding: serviceadapter.CreateBindingJSONParams{
+					BindingId:         "binding-id",
+					BoshVms:           toJson(bosh.BoshVMs{}),
+					Manifest:          toYaml(defaultManifest()),
+					RequestParameters: toJson(paramsFrom(`{}`)),
+				},
+			}
+		})
+
+		It("validates against the binding schema and applies defaults", func() {
+			Expect(action.Execute(inputParams, outputBuffer)).To(Succeed())
+
+			Expect(fakeSchemaGenerator.GeneratePlanSchemaArgsForCall(0).Plan).To(Equal(serviceadapter.Plan{}))
+			Expect(fakeBinder.CreateBindingArgsForCall(0).RequestParams.ArbitraryParams()).To(Equal(map[string]interface{}{"role": "read"}))
+		})
+
+		It("rejects invalid parameters, telling the user why", func() {
+			inputParams.CreateBinding.RequestParameters = toJson(paramsFrom(`{"role": "admin"}`))
+
+			err := action.Execute(inputParams, outputBuffer)
+			Expect(err).To(BeACLIError(serviceadapter.ErrorExitCode, `invalid parameters: role: must be one of "read", "write"`))
+			Expect(fakeBinder.CreateBindingCallCount()).To(Equal(0))
+		})
+
+		It("generates the binding schema for the zero plan", func() {
+			fakeSchemaGenerator.GeneratePlanSchemaStub = func(params serviceadapter.GeneratePlanSchemaParams) (serviceadapter.PlanSchema, error) {
+				roles := `["read", "write"]`
+				if planRoles, ok := params.Plan.Properties["binding_roles"].(string); ok {
+					roles = planRoles
+				}
+				return serviceadapter.PlanSchema{
+					ServiceBinding: serviceadapter.ServiceBindingSchema{
+						Create: schemaFrom(`{"type": "object", "properties": {"role": {"enum": ` + roles + `}}}`),
+					},
+				}, nil
+			}
+			inputParams.CreateBinding.RequestParameters = toJson(paramsFrom(`{"role": "admin"}`))
+
+			err := action.Execute(inputParams, outputBuffer)
+			Expect(err).To(BeACLIError(serviceadapter.ErrorExitCode, `invalid parameters: role: must be one of "read", "write"`))
+			Expect(fakeSchemaGenerator.GeneratePlanSchemaArgsForCall(0).Plan.Properties).To(BeEmpty())
+		})
+	})
+
+	Describe("CommandLineHandler", func() {
+		var handler serviceadapter.CommandLineHandler
+
+		BeforeEach(func() {
+			handler = serviceadapter.CommandLineHandler{
+				ManifestGenerator: fakeManifestGenerator,
+				Binder:            fakeBinder,
+				SchemaGenerator:   fakeSchemaGenerator,
+			}
+		})
+
+		createBinding := func() error {
+			inputParams := serviceadapter.InputParams{
+				CreateBinding: serviceadapter.CreateBindingJSONParams{
+					BindingId:         "binding-id",
+					BoshVms:           toJson(bosh.BoshVMs{}),
+					Manifest:          toYaml(defaultManifest()),
+					RequestParameters: toJson(paramsFrom(`{"role": "admin"}`)),
+				},
+			}
+			return handler.Handle([]string{"adapter", "create-binding"}, outputBuffer, gbytes.NewBuffer(), bytes.NewBufferString(toJson(inputParams)))
+		}
+
+		It("does not validate parameters by default", func() {
+			Expect(createBinding()).To(Succeed())
+			Expect(fakeSchemaGenerator.GeneratePlanSchemaCallCount()).To(Equal(0))
+		})
+
+		It("validates parameters when ValidateRequestParameters is set", func() {
+			handler.ValidateRequestParameters = true
+			Expect(createBinding()).To(BeACLIError(serviceadapter.ErrorExitCode, "invalid parameters"))
+		})
+	})
+})
@@ -0,0 +1,186 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+)
+
+var secretReferencePattern = regexp.MustCompile(`\(\(([^()\s]+)\)\)`)
+
+// UnresolvedSecretsError lists the ((variable)) references ResolveSecrets
+// found no value for.
+type UnresolvedSecretsError struct {
+	References []string
+}
+
+func (e UnresolvedSecretsError) Error() string {
+	return fmt.Sprintf("no value for secret references: %s", strings.Join(e.References, ", "))
+}
+
+// ResolvedManifest returns the manifest with the secret references in it
+// resolved from Secrets, see ResolveSecrets.
+func (p CreateBindingParams) ResolvedManifest() (bosh.BoshManifest, error) {
+	return ResolveSecrets(p.Manifest, p.Secrets)
+}
+
+// ResolvedManifest returns the manifest with the secret references in it
+// resolved from Secrets, see ResolveSecrets.
+func (p DeleteBindingParams) ResolvedManifest() (bosh.BoshManifest, error) {
+	return ResolveSecrets(p.Manifest, p.Secrets)
+}
+
+// ResolveSecrets returns a copy of manifest with the ((variable))
+// references outside of its variables block replaced by their values in
+// secrets, so that a binder can read credentials with JobProperty.
+//
+// A reference is looked up by its key in secrets, and a relative one such as
+// ((password)) or ((odb_secret:password)) also by the absolute path ODB
+// resolved it to, ((/<...>/password)). Values that are JSON objects, as
+// ODB passes certificates and other structured credentials, replace a whole
+// property and can be indexed as in ((certificate.ca)) or
+// ((odb_secret:certificate.ca)).
+//
+// References with no value are left as they are and reported by an
+// UnresolvedSecretsError, returned along with the manifest.
+func ResolveSecrets(manifest bosh.BoshManifest, secrets ManifestSecrets) (bosh.BoshManifest, error) {
+	manifestBytes, err := yaml.Marshal(manifest)
+	if err != nil {
+		return bosh.BoshManifest{}, errors.Wrap(err, "error marshalling bosh manifest")
+	}
+	var tree map[interface{}]interface{}
+	if err := yaml.Unmarshal(manifestBytes, &tree); err != nil {
+		return bosh.BoshManifest{}, errors.Wrap(err, "error unmarshalling bosh manifest")
+	}
+
+	resolver := secretResolver{secrets: secrets, unresolved: map[string]bool{}}
+	for key, value := range tree {
+		if key != "variables" {
+			tree[key] = resolver.resolve(value)
+		}
+	}
+
+	if manifestBytes, err = yaml.Marshal(tree); err != nil {
+		return bosh.BoshManifest{}, errors.Wrap(err, "error marshalling resolved bosh manifest")
+	}
+	var resolved bosh.BoshManifest
+	if err := yaml.Unmarshal(manifestBytes, &resolved); err != nil {
+		return bosh.BoshManifest{}, errors.Wrap(err, "error unmarshalling resolved bosh manifest")
+	}
+
+	if len(resolver.unresolved) > 0 {
+		var references []string
+		for reference := range resolver.unresolved {
+			references = append(references, reference)
+		}
+		sort.Strings(references)
+		return resolved, UnresolvedSecretsError{References: references}
+	}
+	return resolved, nil
+}
+
+type secretResolver struct {
+	secrets    ManifestSecrets
+	unresolved map[string]bool
+}
+
+func (r secretResolver) resolve(node interface{}) interface{} {
+	switch n := node.(type) {
+	case string:
+		return r.resolveString(n)
+	case map[interface{}]interface{}:
+		for key, value := range n {
+			n[key] = r.resolve(value)
+		}
+	case []interface{}:
+		for i, value := range n {
+			n[i] = r.resolve(value)
+		}
+	}
+	return node
+}
+
+func (r secretResolver) resolveString(s string) interface{} {
+	if match := secretReferencePattern.FindStringSubmatch(s); match != nil && match[0] == s {
+		if value, ok := r.value(match[1]); ok {
+			return value
+		}
+		r.unresolved[s] = true
+		return s
+	}
+
+	return secretReferencePattern.ReplaceAllStringFunc(s, func(reference string) string {
+		value, ok := r.value(reference[2 : len(reference)-2])
+		if str, isString := value.(string); ok && isString {
+			return str
+		}
+		r.unresolved[reference] = true
+		return reference
+	})
+}
+
+// value returns the value of the reference name, such as "password",
+// "/a/path", "certificate.ca" or "odb_secret:certificate.ca". A name with
+// dots is looked up whole before it is taken to index a variable.
+func (r secretResolver) value(name string) (interface{}, bool) {
+	raw, ok := r.lookup(name)
+	var fields []string
+	if !ok {
+		parts := strings.Split(name, ".")
+		if len(parts) == 1 {
+			return nil, false
+		}
+		if raw, ok = r.lookup(parts[0]); !ok {
+			return nil, false
+		}
+		fields = parts[1:]
+	}
+
+	var value interface{} = raw
+	var object map[string]interface{}
+	if json.Unmarshal([]byte(raw), &object) == nil {
+		value = object
+	}
+	for _, field := range fields {
+		node, isObject := value.(map[string]interface{})
+		if !isObject {
+			return nil, false
+		}
+		if value, ok = node[field]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func (r secretResolver) lookup(variable string) (string, bool) {
+	if value, ok := r.secrets["(("+variable+"))"]; ok {
+		return value, true
+	}
+	if strings.HasPrefix(variable, "/") {
+		return "", false
+	}
+	return secretByName(r.secrets, "((/", strings.TrimPrefix(variable, ODBSecretPrefix+":"))
+}
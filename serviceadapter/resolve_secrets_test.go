@@ -0,0 +1,144 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+var _ = Describe("ResolveSecrets", func() {
+	var (
+		manifest bosh.BoshManifest
+		secrets  serviceadapter.ManifestSecrets
+	)
+
+	BeforeEach(func() {
+		Expect(yaml.Unmarshal([]byte(`
+name: a-deployment
+instance_groups:
+- name: redis
+  jobs:
+  - name: redis
+    properties:
+      password: ((password))
+      admin_password: ((/odb/service-id/a-deployment/admin_password))
+      url: redis://:((password))@redis.example.com
+      tls:
+        ca: ((certificate.ca))
+        certificate: ((certificate))
+variables:
+- name: password
+  type: password
+  options:
+    default: ((do_not_resolve))
+`), &manifest)).To(Succeed())
+
+		secrets = serviceadapter.ManifestSecrets{
+			"((/director/a-deployment/password))":             "a-password",
+			"((/odb/service-id/a-deployment/admin_password))": "an-admin-password",
+			"((certificate))": `{"ca":"a-ca","certificate":"a-certificate"}`,
+		}
+	})
+
+	It("resolves references in job properties", func() {
+		resolved, err := serviceadapter.ResolveSecrets(manifest, secrets)
+		Expect(err).NotTo(HaveOccurred())
+
+		password, _ := resolved.JobProperty("redis", "redis", "password")
+		Expect(password).To(Equal("a-password"))
+		adminPassword, _ := resolved.JobProperty("redis", "redis", "admin_password")
+		Expect(adminPassword).To(Equal("an-admin-password"))
+		url, _ := resolved.JobProperty("redis", "redis", "url")
+		Expect(url).To(Equal("redis://:a-password@redis.example.com"))
+		ca, _ := resolved.JobProperty("redis", "redis", "tls.ca")
+		Expect(ca).To(Equal("a-ca"))
+		certificate, _ := resolved.JobProperty("redis", "redis", "tls.certificate.certificate")
+		Expect(certificate).To(Equal("a-certificate"))
+
+		Expect(resolved.Variables[0].Options).To(HaveKeyWithValue("default", "((do_not_resolve))"))
+	})
+
+	It("does not modify the manifest passed", func() {
+		_, err := serviceadapter.ResolveSecrets(manifest, secrets)
+		Expect(err).NotTo(HaveOccurred())
+
+		password, _ := manifest.JobProperty("redis", "redis", "password")
+		Expect(password).To(Equal("((password))"))
+	})
+
+	It("resolves odb_secret references by the path ODB stored them at", func() {
+		manifest.InstanceGroups[0].Jobs[0].Properties["admin_password"] = "((odb_secret:admin_password))"
+
+		resolved, err := serviceadapter.ResolveSecrets(manifest, secrets)
+		Expect(err).NotTo(HaveOccurred())
+
+		adminPassword, _ := resolved.JobProperty("redis", "redis", "admin_password")
+		Expect(adminPassword).To(Equal("an-admin-password"))
+	})
+
+	It("indexes odb_secret references", func() {
+		secrets["((/odb/service-id/a-deployment/tls))"] = `{"ca":"an-odb-ca"}`
+		secrets["((/odb/service-id/a-deployment/dotted.name))"] = "a-dotted-secret"
+		manifest.InstanceGroups[0].Jobs[0].Properties["tls"] = map[interface{}]interface{}{"ca": "((odb_secret:tls.ca))"}
+		manifest.InstanceGroups[0].Jobs[0].Properties["dotted"] = "((odb_secret:dotted.name))"
+
+		resolved, err := serviceadapter.ResolveSecrets(manifest, secrets)
+		Expect(err).NotTo(HaveOccurred())
+
+		ca, _ := resolved.JobProperty("redis", "redis", "tls.ca")
+		Expect(ca).To(Equal("an-odb-ca"))
+		dotted, _ := resolved.JobProperty("redis", "redis", "dotted")
+		Expect(dotted).To(Equal("a-dotted-secret"))
+	})
+
+	It("leaves references with no value and reports them", func() {
+		delete(secrets, "((/director/a-deployment/password))")
+
+		resolved, err := serviceadapter.ResolveSecrets(manifest, secrets)
+
+		var unresolvedErr serviceadapter.UnresolvedSecretsError
+		Expect(errors.As(err, &unresolvedErr)).To(BeTrue())
+		Expect(unresolvedErr.References).To(Equal([]string{"((password))"}))
+		Expect(err).To(MatchError("no value for secret references: ((password))"))
+
+		password, _ := resolved.JobProperty("redis", "redis", "password")
+		Expect(password).To(Equal("((password))"))
+		ca, _ := resolved.JobProperty("redis", "redis", "tls.ca")
+		Expect(ca).To(Equal("a-ca"))
+	})
+
+	It("does not guess between secrets with the same name", func() {
+		secrets["((/director/another-deployment/password))"] = "another-password"
+
+		_, err := serviceadapter.ResolveSecrets(manifest, secrets)
+		Expect(err).To(MatchError("no value for secret references: ((password))"))
+	})
+
+	It("is available on the binding params", func() {
+		resolved, err := serviceadapter.CreateBindingParams{Manifest: manifest, Secrets: secrets}.ResolvedManifest()
+		Expect(err).NotTo(HaveOccurred())
+
+		password, _ := resolved.JobProperty("redis", "redis", "password")
+		Expect(password).To(Equal("a-password"))
+	})
+})
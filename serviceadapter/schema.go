@@ -0,0 +1,320 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const JSONSchemaDraft04 = "http://json-schema.org/draft-04/schema#"
+
+// JSONSchemasFor returns the JSON Schema of the parameters described by v,
+// a struct or a pointer to one. Properties are named after the fields' json
+// tags, and further described with these tags:
+//
+//	description:"Billing account to charge"
+//	default:"10"            (JSON for arrays, maps and structs)
+//	default:"30s"           (a time.ParseDuration string for time.Duration)
+//	enum:"small,medium,large"
+//	min:"1" max:"10"        (length for strings, items for arrays)
+//	pattern:"^[a-z]+$"
+//	required:"true"
+func JSONSchemasFor(v interface{}) (JSONSchemas, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return JSONSchemas{}, fmt.Errorf("expected a struct to generate a JSON schema from, got %v", t)
+	}
+
+	schema, err := objectSchema(t, map[reflect.Type]bool{})
+	if err != nil {
+		return JSONSchemas{}, err
+	}
+	schema["$schema"] = JSONSchemaDraft04
+	return JSONSchemas{Parameters: schema}, nil
+}
+
+// DecodeArbitraryParams decodes the arbitrary parameters into v, a pointer
+// to a struct as passed to JSONSchemasFor. Fields that are not set in the
+// parameters take the value of their default tag. Parameters are decoded as
+// by DecodeParameters with the given options.
+func (s RequestParameters) DecodeArbitraryParams(v interface{}, opts ...DecodeOption) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected a pointer to a struct to decode arbitrary params into, got %T", v)
+	}
+	if err := applyDefaultTags(value.Elem()); err != nil {
+		return err
+	}
+	return decodeInto(s["parameters"], v, opts)
+}
+
+// objectSchema describes the struct type t. expanding holds the struct types
+// being described further up, as recursive types cannot be described inline.
+func objectSchema(t reflect.Type, expanding map[reflect.Type]bool) (map[string]interface{}, error) {
+	if expanding[t] {
+		return nil, fmt.Errorf("recursive type %s is not supported", t)
+	}
+	expanding[t] = true
+	defer delete(expanding, t)
+
+	properties := map[string]interface{}{}
+	var required []string
+
+	err := forEachSchemaField(t, func(field reflect.StructField, name string) error {
+		property, err := fieldSchema(field, expanding)
+		if err != nil {
+			return err
+		}
+		properties[name] = property
+		if field.Tag.Get("required") == "true" {
+			required = append(required, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema, nil
+}
+
+// forEachSchemaField calls fn for every field encoding/json would encode,
+// including those of embedded structs, with the name it would use.
+func forEachSchemaField(t reflect.Type, fn func(field reflect.StructField, name string) error) error {
+	for _, field := range structFields(t) {
+		if err := fn(field.field, field.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fieldSchema(field reflect.StructField, expanding map[reflect.Type]bool) (map[string]interface{}, error) {
+	fieldErr := func(format string, args ...interface{}) error {
+		return fmt.Errorf("field %s: %s", field.Name, fmt.Sprintf(format, args...))
+	}
+
+	schema, err := typeSchema(field.Type, expanding)
+	if err != nil {
+		return nil, fieldErr("%s", err)
+	}
+
+	if description, ok := field.Tag.Lookup("description"); ok {
+		schema["description"] = description
+	}
+
+	if defaultValue, ok := field.Tag.Lookup("default"); ok {
+		value, err := parseTagValue(field.Type, defaultValue)
+		if err != nil {
+			return nil, fieldErr("invalid default '%s': %s", defaultValue, err)
+		}
+		schema["default"] = value
+	}
+
+	if enum, ok := field.Tag.Lookup("enum"); ok {
+		var values []interface{}
+		for _, option := range strings.Split(enum, ",") {
+			value, err := parseTagValue(field.Type, strings.TrimSpace(option))
+			if err != nil {
+				return nil, fieldErr("invalid enum value '%s': %s", option, err)
+			}
+			values = append(values, value)
+		}
+		schema["enum"] = values
+	}
+
+	if pattern, ok := field.Tag.Lookup("pattern"); ok {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fieldErr("invalid pattern '%s': %s", pattern, err)
+		}
+		schema["pattern"] = pattern
+	}
+
+	for _, bound := range []string{"min", "max"} {
+		tagValue, ok := field.Tag.Lookup(bound)
+		if !ok {
+			continue
+		}
+		keyword, value, err := boundKeyword(field.Type, bound, tagValue)
+		if err != nil {
+			return nil, fieldErr("invalid %s '%s': %s", bound, tagValue, err)
+		}
+		schema[keyword] = value
+	}
+
+	return schema, nil
+}
+
+func typeSchema(t reflect.Type, expanding map[reflect.Type]bool) (map[string]interface{}, error) {
+	if t.Kind() == reflect.Ptr {
+		return typeSchema(t.Elem(), expanding)
+	}
+
+	if t == durationType {
+		return map[string]interface{}{"type": "string"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}, nil
+	case reflect.Interface:
+		return map[string]interface{}{}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := typeSchema(t.Elem(), expanding)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		values, err := typeSchema(t.Elem(), expanding)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		return objectSchema(t, expanding)
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+func boundKeyword(t reflect.Type, bound, tagValue string) (string, interface{}, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var keywords [2]string
+	switch t.Kind() {
+	case reflect.String:
+		keywords = [2]string{"minLength", "maxLength"}
+	case reflect.Slice, reflect.Array:
+		keywords = [2]string{"minItems", "maxItems"}
+	case reflect.Map:
+		keywords = [2]string{"minProperties", "maxProperties"}
+	default:
+		if schema, _ := typeSchema(t, map[reflect.Type]bool{}); schema["type"] != "integer" && schema["type"] != "number" {
+			return "", nil, fmt.Errorf("min and max are not supported for type %s", t)
+		}
+		value, err := parseTagValue(t, tagValue)
+		if bound == "min" {
+			return "minimum", value, err
+		}
+		return "maximum", value, err
+	}
+
+	value, err := strconv.Atoi(tagValue)
+	if err == nil && value < 0 {
+		err = fmt.Errorf("must not be negative")
+	}
+	if bound == "min" {
+		return keywords[0], value, err
+	}
+	return keywords[1], value, err
+}
+
+// parseTagValue parses a default or enum tag value as a value of type t.
+func parseTagValue(t reflect.Type, tagValue string) (interface{}, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == durationType {
+		_, err := time.ParseDuration(tagValue)
+		return tagValue, err
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return tagValue, nil
+	case reflect.Bool:
+		return strconv.ParseBool(tagValue)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err := strconv.ParseInt(tagValue, 10, t.Bits())
+		return int(value), err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value, err := strconv.ParseUint(tagValue, 10, t.Bits())
+		return int(value), err
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(tagValue, t.Bits())
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(tagValue), &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// applyDefaultTags sets every field of the struct v that has a default tag
+// to that default.
+func applyDefaultTags(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldValue := v.Field(i)
+
+		if defaultValue, ok := field.Tag.Lookup("default"); ok {
+			value, err := parseTagValue(field.Type, defaultValue)
+			if err != nil {
+				return fmt.Errorf("field %s: invalid default '%s': %s", field.Name, defaultValue, err)
+			}
+			if duration, ok := value.(string); ok && (field.Type == durationType || field.Type == reflect.PointerTo(durationType)) {
+				parsed, _ := time.ParseDuration(duration)
+				value = int64(parsed)
+			}
+			valueJSON, _ := json.Marshal(value)
+			if err := json.Unmarshal(valueJSON, fieldValue.Addr().Interface()); err != nil {
+				return fmt.Errorf("field %s: invalid default '%s': %s", field.Name, defaultValue, err)
+			}
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			if err := applyDefaultTags(fieldValue); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
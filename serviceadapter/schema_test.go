@@ -0,0 +1,155 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+type backupParams struct {
+	Schedule  string        `json:"schedule" default:"@daily" pattern:"^@(daily|weekly)$"`
+	Retention int           `json:"retention" min:"1" max:"30" default:"7"`
+	Timeout   time.Duration `json:"timeout" default:"1h"`
+}
+
+type node struct {
+	Children []node `json:"children"`
+}
+
+type CommonParams struct {
+	BillingAccount string `json:"billing-account" description:"Billing account number" required:"true"`
+}
+
+type redisParams struct {
+	CommonParams
+	MaxClients  int               `json:"maxclients" description:"Maximum number of clients" min:"1" max:"10000" default:"1000"`
+	Persistence *bool             `json:"persistence,omitempty" default:"true"`
+	Policy      string            `json:"maxmemory-policy" enum:"noeviction, allkeys-lru" default:"noeviction"`
+	Ratio       float64           `json:"ratio" min:"0.5"`
+	Plugins     []string          `json:"plugins" max:"3" default:"[\"json\"]"`
+	Labels      map[string]string `json:"labels"`
+	Backup      backupParams      `json:"backup"`
+	Extra       interface{}       `json:"extra"`
+	Ignored     string            `json:"-"`
+	internal    string
+}
+
+var _ = Describe("JSONSchemasFor", func() {
+	It("generates a JSON schema from struct tags", func() {
+		schemas, err := serviceadapter.JSONSchemasFor(&redisParams{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(toJson(schemas)).To(MatchJSON(`{
+			"parameters": {
+				"$schema": "http://json-schema.org/draft-04/schema#",
+				"type": "object",
+				"required": ["billing-account"],
+				"properties": {
+					"billing-account": {"type": "string", "description": "Billing account number"},
+					"maxclients": {"type": "integer", "description": "Maximum number of clients", "minimum": 1, "maximum": 10000, "default": 1000},
+					"persistence": {"type": "boolean", "default": true},
+					"maxmemory-policy": {"type": "string", "enum": ["noeviction", "allkeys-lru"], "default": "noeviction"},
+					"ratio": {"type": "number", "minimum": 0.5},
+					"plugins": {"type": "array", "items": {"type": "string"}, "maxItems": 3, "default": ["json"]},
+					"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+					"backup": {
+						"type": "object",
+						"properties": {
+							"schedule": {"type": "string", "pattern": "^@(daily|weekly)$", "default": "@daily"},
+							"retention": {"type": "integer", "minimum": 1, "maximum": 30, "default": 7},
+							"timeout": {"type": "string", "default": "1h"}
+						}
+					},
+					"extra": {}
+				}
+			}
+		}`))
+	})
+
+	It("describes a struct type used by several fields", func() {
+		schemas, err := serviceadapter.JSONSchemasFor(struct {
+			Primary   backupParams `json:"primary"`
+			Secondary backupParams `json:"secondary"`
+		}{})
+		Expect(err).NotTo(HaveOccurred())
+
+		properties := schemas.Parameters["properties"].(map[string]interface{})
+		Expect(properties["secondary"]).To(Equal(properties["primary"]))
+	})
+
+	DescribeTable("returns an error for invalid structs",
+		func(v interface{}, message string) {
+			_, err := serviceadapter.JSONSchemasFor(v)
+			Expect(err).To(MatchError(message))
+		},
+		Entry("not a struct", "params", "expected a struct to generate a JSON schema from, got string"),
+		Entry("unsupported type", struct{ C chan int }{}, "field C: unsupported type chan int"),
+		Entry("invalid default", struct {
+			N int `default:"many"`
+		}{}, `field N: invalid default 'many': strconv.ParseInt: parsing "many": invalid syntax`),
+		Entry("invalid pattern", struct {
+			S string `pattern:"("`
+		}{}, "field S: invalid pattern '(': error parsing regexp: missing closing ): `(`"),
+		Entry("min on a bool", struct {
+			B bool `min:"1"`
+		}{}, "field B: invalid min '1': min and max are not supported for type bool"),
+		Entry("invalid duration default", struct {
+			D time.Duration `default:"30"`
+		}{}, `field D: invalid default '30': time: missing unit in duration "30"`),
+		Entry("recursive type", node{}, "field Children: recursive type serviceadapter_test.node is not supported"),
+	)
+})
+
+var _ = Describe("DecodeArbitraryParams", func() {
+	It("decodes the arbitrary params, defaulting fields that are not set", func() {
+		var requestParams serviceadapter.RequestParameters
+		Expect(json.Unmarshal([]byte(`{
+			"parameters": {"billing-account": "abc", "maxclients": 10, "backup": {"schedule": "@weekly"}}
+		}`), &requestParams)).To(Succeed())
+
+		var params redisParams
+		Expect(requestParams.DecodeArbitraryParams(&params)).To(Succeed())
+
+		persistence := true
+		Expect(params).To(Equal(redisParams{
+			CommonParams: CommonParams{BillingAccount: "abc"},
+			MaxClients:   10,
+			Persistence:  &persistence,
+			Policy:       "noeviction",
+			Plugins:      []string{"json"},
+			Backup:       backupParams{Schedule: "@weekly", Retention: 7, Timeout: time.Hour},
+		}))
+	})
+
+	It("returns an error when the params do not match the struct", func() {
+		requestParams := serviceadapter.RequestParameters{"parameters": map[string]interface{}{"maxclients": "lots"}}
+
+		var params redisParams
+		err := requestParams.DecodeArbitraryParams(&params)
+		Expect(err).To(MatchError(`invalid parameters: maxclients: expected integer, got string "lots"`))
+	})
+
+	It("requires a pointer to a struct", func() {
+		err := serviceadapter.RequestParameters{}.DecodeArbitraryParams(redisParams{})
+		Expect(err).To(MatchError("expected a pointer to a struct to decode arbitrary params into, got serviceadapter_test.redisParams"))
+	})
+})
@@ -0,0 +1,133 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadaptertest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/internal/linediff"
+)
+
+const diffContextLines = 3
+
+// UpdateGoldenEnvVar overwrites golden files with the actual outputs when
+// set to true.
+const UpdateGoldenEnvVar = "UPDATE_GOLDEN"
+
+// AssertGolden compares actual with the golden file <GoldenDir>/<name>,
+// or writes it when updating. Golden files with a .yml or .yaml extension
+// are compared as YAML, so that key order and formatting do not matter.
+func (h *Harness) AssertGolden(t T, name string, actual []byte) {
+	t.Helper()
+	dir := h.GoldenDir
+	if dir == "" {
+		dir = "testdata"
+	}
+	path := filepath.Join(dir, name)
+
+	if h.updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("creating golden file directory: %s", err)
+		}
+		if err := os.WriteFile(path, actual, 0644); err != nil {
+			t.Fatalf("writing golden file: %s", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("golden file %s does not exist, run the tests with %s=true to create it", path, UpdateGoldenEnvVar)
+		return
+	}
+	if err != nil {
+		t.Fatalf("reading golden file: %s", err)
+		return
+	}
+
+	if isYAML(path) {
+		if expected, err = normalizeYAML(expected); err != nil {
+			t.Fatalf("golden file %s is not valid YAML: %s", path, err)
+			return
+		}
+		if actual, err = normalizeYAML(actual); err != nil {
+			t.Fatalf("output compared with %s is not valid YAML: %s", path, err)
+			return
+		}
+	}
+
+	if !bytes.Equal(expected, actual) {
+		diff := trimDiff(linediff.Diff(string(expected), string(actual)), diffContextLines)
+		t.Errorf("output does not match golden file %s (- golden, + actual):\n%s", path, diff)
+	}
+}
+
+func (h *Harness) updating() bool {
+	return h.Update || os.Getenv(UpdateGoldenEnvVar) == "true"
+}
+
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yml" || ext == ".yaml"
+}
+
+// normalizeYAML re-marshals a document, which sorts the keys of its maps.
+func normalizeYAML(document []byte) ([]byte, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(document, &v); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(v)
+}
+
+// trimDiff keeps the changed lines of a diff and the given number of lines
+// around them.
+func trimDiff(diff string, context int) string {
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		if strings.HasPrefix(line, "  ") {
+			continue
+		}
+		for j := max(0, i-context); j <= min(len(lines)-1, i+context); j++ {
+			keep[j] = true
+		}
+	}
+
+	var trimmed strings.Builder
+	skipped := false
+	for i, line := range lines {
+		if !keep[i] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			trimmed.WriteString("  ...\n")
+			skipped = false
+		}
+		fmt.Fprintln(&trimmed, line)
+	}
+	if skipped {
+		trimmed.WriteString("  ...\n")
+	}
+	return trimmed.String()
+}
@@ -0,0 +1,262 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package serviceadaptertest drives a serviceadapter.CommandLineHandler in
+// process, for adapter authors to test the manifests and bindings it
+// generates against golden files.
+package serviceadaptertest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+// T is the subset of testing.TB used by the kit. It is implemented by
+// *testing.T and by GinkgoT().
+type T interface {
+	Helper()
+	Fatalf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// ManifestFixture holds the inputs of generate-manifest. Previous fields
+// are left nil for a new service instance.
+type ManifestFixture struct {
+	ServiceDeployment serviceadapter.ServiceDeployment
+	Plan              serviceadapter.Plan
+	RequestParams     serviceadapter.RequestParameters
+	PreviousManifest  *bosh.BoshManifest
+	PreviousPlan      *serviceadapter.Plan
+	PreviousSecrets   serviceadapter.ManifestSecrets
+	PreviousConfigs   serviceadapter.BOSHConfigs
+	UAAClient         map[string]string
+}
+
+// BindingFixture holds the inputs of create-binding and delete-binding.
+type BindingFixture struct {
+	BindingID     string
+	BoshVMs       bosh.BoshVMs
+	Manifest      bosh.BoshManifest
+	RequestParams serviceadapter.RequestParameters
+	Secrets       serviceadapter.ManifestSecrets
+	DNSAddresses  serviceadapter.DNSAddresses
+}
+
+// Result is what an invocation of the handler wrote and returned.
+type Result struct {
+	Stdout string
+	Stderr string
+	Err    error
+}
+
+// ExitCode is the code the adapter would have exited with.
+func (r Result) ExitCode() int {
+	if r.Err == nil {
+		return 0
+	}
+	var cliErr serviceadapter.CLIHandlerError
+	if errors.As(r.Err, &cliErr) {
+		return cliErr.ExitCode
+	}
+	return serviceadapter.ErrorExitCode
+}
+
+// Harness invokes Handler as ODB would, passing input params via stdin, and
+// compares the outputs with golden files in GoldenDir.
+type Harness struct {
+	Handler serviceadapter.CommandLineHandler
+
+	// GoldenDir defaults to testdata.
+	GoldenDir string
+
+	// Update overwrites golden files with the actual outputs instead of
+	// comparing them. It is also enabled by UpdateGoldenEnvVar. Tests that
+	// want a flag for it can register their own and set Update from it.
+	Update bool
+}
+
+func New(handler serviceadapter.CommandLineHandler) *Harness {
+	return &Harness{Handler: handler}
+}
+
+// Invoke runs action with inputParams passed via stdin.
+func (h *Harness) Invoke(t T, action string, inputParams serviceadapter.InputParams) Result {
+	t.Helper()
+	input, err := json.Marshal(inputParams)
+	if err != nil {
+		t.Fatalf("marshalling input params: %s", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	err = h.Handler.HandleWithContext(context.Background(), []string{"service-adapter", action}, &stdout, &stderr, bytes.NewReader(input))
+	return Result{Stdout: stdout.String(), Stderr: stderr.String(), Err: err}
+}
+
+// GenerateManifest runs generate-manifest with the fixture and fails t if
+// it fails.
+func (h *Harness) GenerateManifest(t T, fixture ManifestFixture) serviceadapter.MarshalledGenerateManifest {
+	t.Helper()
+	var output serviceadapter.MarshalledGenerateManifest
+	result := h.Invoke(t, "generate-manifest", serviceadapter.InputParams{
+		GenerateManifest: serviceadapter.GenerateManifestJSONParams{
+			ServiceDeployment:        toJSON(t, fixture.ServiceDeployment),
+			Plan:                     toJSON(t, fixture.Plan),
+			RequestParameters:        toJSON(t, requestParams(fixture.RequestParams)),
+			PreviousManifest:         toYAML(t, fixture.PreviousManifest),
+			PreviousPlan:             toJSON(t, fixture.PreviousPlan),
+			PreviousSecrets:          optionalJSON(t, fixture.PreviousSecrets),
+			PreviousConfigs:          optionalJSON(t, fixture.PreviousConfigs),
+			ServiceInstanceUAAClient: optionalJSON(t, fixture.UAAClient),
+		},
+	})
+	h.decodeStdout(t, "generate-manifest", result, &output)
+	return output
+}
+
+// CreateBinding runs create-binding with the fixture and fails t if it
+// fails.
+func (h *Harness) CreateBinding(t T, fixture BindingFixture) serviceadapter.Binding {
+	t.Helper()
+	var binding serviceadapter.Binding
+	result := h.Invoke(t, "create-binding", serviceadapter.InputParams{
+		CreateBinding: serviceadapter.CreateBindingJSONParams{
+			BindingId:         fixture.BindingID,
+			BoshVms:           toJSON(t, fixture.BoshVMs),
+			Manifest:          toYAML(t, fixture.Manifest),
+			RequestParameters: toJSON(t, requestParams(fixture.RequestParams)),
+			Secrets:           optionalJSON(t, fixture.Secrets),
+			DNSAddresses:      optionalJSON(t, fixture.DNSAddresses),
+		},
+	})
+	h.decodeStdout(t, "create-binding", result, &binding)
+	return binding
+}
+
+// DeleteBinding runs delete-binding with the fixture.
+func (h *Harness) DeleteBinding(t T, fixture BindingFixture) Result {
+	t.Helper()
+	return h.Invoke(t, "delete-binding", serviceadapter.InputParams{
+		DeleteBinding: serviceadapter.DeleteBindingJSONParams{
+			BindingId:         fixture.BindingID,
+			BoshVms:           toJSON(t, fixture.BoshVMs),
+			Manifest:          toYAML(t, fixture.Manifest),
+			RequestParameters: toJSON(t, requestParams(fixture.RequestParams)),
+			Secrets:           optionalJSON(t, fixture.Secrets),
+			DNSAddresses:      optionalJSON(t, fixture.DNSAddresses),
+		},
+	})
+}
+
+// AssertManifest generates the manifest for the fixture and compares it
+// with the golden file <GoldenDir>/<name>.yml.
+func (h *Harness) AssertManifest(t T, name string, fixture ManifestFixture) {
+	t.Helper()
+	output := h.GenerateManifest(t, fixture)
+	h.AssertGolden(t, name+".yml", []byte(output.Manifest))
+}
+
+// AssertBinding creates the binding for the fixture and compares it, as
+// YAML, with the golden file <GoldenDir>/<name>.yml.
+func (h *Harness) AssertBinding(t T, name string, fixture BindingFixture) {
+	t.Helper()
+	binding := h.CreateBinding(t, fixture)
+
+	// through JSON, so that the golden file has the keys ODB sees
+	var asJSON interface{}
+	if err := json.Unmarshal([]byte(toJSON(t, binding)), &asJSON); err != nil {
+		t.Fatalf("unmarshalling binding: %s", err)
+	}
+	h.AssertGolden(t, name+".yml", []byte(toYAML(t, asJSON)))
+}
+
+func (h *Harness) decodeStdout(t T, action string, result Result, v interface{}) {
+	t.Helper()
+	if result.Err != nil {
+		t.Fatalf("%s failed with exit code %d: %s\nstderr:\n%s", action, result.ExitCode(), result.Err, result.Stderr)
+	}
+	if err := json.Unmarshal([]byte(result.Stdout), v); err != nil {
+		t.Fatalf("unmarshalling output of %s: %s\nstdout:\n%s", action, err, result.Stdout)
+	}
+}
+
+// LoadFixture reads the file at path into v, as YAML if it has a .yml or
+// .yaml extension and as JSON otherwise. Use YAML for BOSH manifests and
+// JSON for the types of the serviceadapter package, which only have JSON
+// tags.
+func LoadFixture(t T, path string, v interface{}) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading fixture: %s", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		err = yaml.UnmarshalStrict(data, v)
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(v)
+	}
+	if err != nil && err != io.EOF {
+		t.Fatalf("unmarshalling fixture %s: %s", path, err)
+	}
+}
+
+func requestParams(params serviceadapter.RequestParameters) serviceadapter.RequestParameters {
+	if params == nil {
+		return serviceadapter.RequestParameters{}
+	}
+	return params
+}
+
+func toJSON(t T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshalling fixture: %s", err)
+	}
+	return string(data)
+}
+
+// optionalJSON leaves out optional input params that are not set, as ODB
+// does.
+func optionalJSON(t T, v interface{}) string {
+	t.Helper()
+	if data := toJSON(t, v); data != "null" {
+		return data
+	}
+	return ""
+}
+
+func toYAML(t T, v interface{}) string {
+	t.Helper()
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshalling fixture: %s", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,241 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadaptertest_test
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter/fakes"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter/serviceadaptertest"
+)
+
+var _ = Describe("Harness", func() {
+	var (
+		fakeManifestGenerator *fakes.FakeManifestGenerator
+		fakeBinder            *fakes.FakeBinder
+		harness               *serviceadaptertest.Harness
+		t                     *fakeT
+
+		manifestFixture serviceadaptertest.ManifestFixture
+		bindingFixture  serviceadaptertest.BindingFixture
+	)
+
+	BeforeEach(func() {
+		fakeManifestGenerator = new(fakes.FakeManifestGenerator)
+		fakeManifestGenerator.GenerateManifestStub = func(params serviceadapter.GenerateManifestParams) (serviceadapter.GenerateManifestOutput, error) {
+			return serviceadapter.GenerateManifestOutput{Manifest: bosh.BoshManifest{
+				Name:      params.ServiceDeployment.DeploymentName,
+				Releases:  []bosh.Release{{Name: "redis", Version: "1.0"}},
+				Stemcells: []bosh.Stemcell{{Alias: "default", OS: "ubuntu-jammy", Version: "latest"}},
+				InstanceGroups: []bosh.InstanceGroup{{
+					Name:       "redis",
+					Instances:  params.Plan.InstanceGroups[0].Instances,
+					Jobs:       []bosh.Job{{Name: "redis-server", Release: "redis"}},
+					VMType:     params.Plan.InstanceGroups[0].VMType,
+					Stemcell:   "default",
+					AZs:        params.Plan.InstanceGroups[0].AZs,
+					Networks:   []bosh.Network{{Name: "default"}},
+					Properties: map[string]interface{}{"port": params.RequestParams["port"]},
+				}},
+			}}, nil
+		}
+		fakeBinder = new(fakes.FakeBinder)
+		fakeBinder.CreateBindingReturns(serviceadapter.Binding{Credentials: map[string]interface{}{"host": "redis.example.com", "port": 6379}}, nil)
+
+		harness = serviceadaptertest.New(serviceadapter.CommandLineHandler{
+			ManifestGenerator: fakeManifestGenerator,
+			Binder:            fakeBinder,
+		})
+		t = new(fakeT)
+
+		manifestFixture = serviceadaptertest.ManifestFixture{
+			ServiceDeployment: serviceadapter.ServiceDeployment{
+				DeploymentName: "my-service-instance",
+				Releases:       serviceadapter.ServiceReleases{{Name: "redis", Version: "1.0", Jobs: []string{"redis-server"}}},
+				Stemcells:      []serviceadapter.Stemcell{{OS: "ubuntu-jammy", Version: "latest"}},
+			},
+			Plan: serviceadapter.Plan{
+				InstanceGroups: []serviceadapter.InstanceGroup{{
+					Name:      "redis",
+					VMType:    "small",
+					Networks:  []string{"default"},
+					AZs:       []string{"z1"},
+					Instances: 1,
+				}},
+			},
+			RequestParams: serviceadapter.RequestParameters{"port": 6379},
+		}
+		bindingFixture = serviceadaptertest.BindingFixture{
+			BindingID: "binding-id",
+			BoshVMs:   bosh.BoshVMs{"redis": []string{"10.0.0.1"}},
+			Secrets:   serviceadapter.ManifestSecrets{"((password))": "secret-value"},
+		}
+	})
+
+	It("passes the fixture to the adapter as ODB would", func() {
+		previousManifest := bosh.BoshManifest{Name: "my-service-instance"}
+		manifestFixture.PreviousManifest = &previousManifest
+		manifestFixture.UAAClient = map[string]string{"client_id": "id", "client_secret": "secret"}
+
+		t.run(func() { harness.GenerateManifest(t, manifestFixture) })
+
+		Expect(t.fatal).To(BeEmpty())
+		params := fakeManifestGenerator.GenerateManifestArgsForCall(0)
+		Expect(params.ServiceDeployment).To(Equal(manifestFixture.ServiceDeployment))
+		Expect(params.RequestParams).To(Equal(serviceadapter.RequestParameters{"port": float64(6379)}))
+		Expect(params.PreviousManifest.Name).To(Equal("my-service-instance"))
+		Expect(params.PreviousPlan).To(BeNil())
+		Expect(params.PreviousSecrets).To(Equal(serviceadapter.ManifestSecrets{}))
+		Expect(params.ServiceInstanceUAAClient.ClientSecret).To(Equal("secret"))
+
+		t.run(func() { harness.CreateBinding(t, bindingFixture) })
+
+		Expect(t.fatal).To(BeEmpty())
+		bindingParams := fakeBinder.CreateBindingArgsForCall(0)
+		Expect(bindingParams.BindingID).To(Equal("binding-id"))
+		Expect(bindingParams.DeploymentTopology).To(Equal(bosh.BoshVMs{"redis": []string{"10.0.0.1"}}))
+		Expect(bindingParams.Secrets).To(Equal(serviceadapter.ManifestSecrets{"((password))": "secret-value"}))
+		Expect(bindingParams.DNSAddresses).To(BeNil())
+	})
+
+	It("fails with the exit code and stderr when the adapter fails", func() {
+		fakeBinder.DeleteBindingReturns(serviceadapter.NewBindingNotFoundError(nil))
+		result := harness.DeleteBinding(t, bindingFixture)
+		Expect(result.ExitCode()).To(Equal(serviceadapter.BindingNotFoundErrorExitCode))
+
+		fakeManifestGenerator.GenerateManifestReturns(serviceadapter.GenerateManifestOutput{}, errors.New("no capacity"))
+		fakeManifestGenerator.GenerateManifestStub = nil
+
+		t.run(func() { harness.AssertManifest(t, "manifest", manifestFixture) })
+
+		Expect(t.fatal).To(HavePrefix("generate-manifest failed with exit code 1: no capacity\nstderr:\n"))
+	})
+
+	Describe("golden files", func() {
+		It("passes when the outputs match the golden files", func() {
+			t.run(func() {
+				harness.AssertManifest(t, "manifest", manifestFixture)
+				harness.AssertBinding(t, "binding", bindingFixture)
+			})
+
+			Expect(t.fatal).To(BeEmpty())
+			Expect(t.errors).To(BeEmpty())
+		})
+
+		It("shows where the output differs from the golden file as YAML", func() {
+			manifestFixture.RequestParams["port"] = 6380
+
+			t.run(func() { harness.AssertManifest(t, "manifest", manifestFixture) })
+
+			Expect(t.fatal).To(BeEmpty())
+			Expect(t.errors).To(ConsistOf(Equal(`output does not match golden file testdata/manifest.yml (- golden, + actual):
+  ...
+    networks:
+    - name: default
+    properties:
+-     port: 6379
++     port: 6380
+    stemcell: default
+    vm_type: small
+  name: my-service-instance
+  ...
+`)))
+		})
+
+		It("writes the golden files when updating", func() {
+			harness.GoldenDir = filepath.Join(GinkgoT().TempDir(), "golden")
+			harness.Update = true
+
+			t.run(func() { harness.AssertBinding(t, "binding", bindingFixture) })
+
+			Expect(t.fatal).To(BeEmpty())
+			Expect(filepath.Join(harness.GoldenDir, "binding.yml")).To(BeAnExistingFile())
+			written, err := os.ReadFile(filepath.Join(harness.GoldenDir, "binding.yml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(written).To(MatchYAML("credentials: {host: redis.example.com, port: 6379}"))
+
+			harness.Update = false
+			t.run(func() { harness.AssertBinding(t, "binding", bindingFixture) })
+			Expect(t.errors).To(BeEmpty())
+		})
+
+		It("writes the golden files when the environment asks for it", func() {
+			harness.GoldenDir = GinkgoT().TempDir()
+			os.Setenv(serviceadaptertest.UpdateGoldenEnvVar, "true")
+			DeferCleanup(os.Unsetenv, serviceadaptertest.UpdateGoldenEnvVar)
+
+			t.run(func() { harness.AssertBinding(t, "binding", bindingFixture) })
+
+			Expect(t.fatal).To(BeEmpty())
+			Expect(filepath.Join(harness.GoldenDir, "binding.yml")).To(BeAnExistingFile())
+			Expect(flag.Lookup("update-golden")).To(BeNil(), "importing the package registers no flags")
+		})
+
+		It("fails when the golden file does not exist", func() {
+			t.run(func() { harness.AssertBinding(t, "missing", bindingFixture) })
+
+			Expect(t.fatal).To(Equal("golden file testdata/missing.yml does not exist, run the tests with UPDATE_GOLDEN=true to create it"))
+		})
+
+		It("compares files that are not YAML byte for byte", func() {
+			t.run(func() { harness.AssertGolden(t, "binding.yml.txt", []byte("credentials: {}")) })
+
+			Expect(t.fatal).To(ContainSubstring("does not exist"))
+
+			harness.GoldenDir = GinkgoT().TempDir()
+			Expect(os.WriteFile(filepath.Join(harness.GoldenDir, "output.txt"), []byte("a\nb\n"), 0644)).To(Succeed())
+			t.run(func() { harness.AssertGolden(t, "output.txt", []byte("a\nc\n")) })
+
+			Expect(t.errors).To(ConsistOf(HaveSuffix("  a\n- b\n+ c\n")))
+		})
+	})
+
+	Describe("LoadFixture", func() {
+		It("loads fixtures from JSON and YAML files", func() {
+			var plan serviceadapter.Plan
+			var manifest bosh.BoshManifest
+			dir := GinkgoT().TempDir()
+			Expect(os.WriteFile(filepath.Join(dir, "plan.json"), []byte(`{"properties": {"persistence": true}, "instance_groups": []}`), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "manifest.yml"), []byte("name: previous"), 0644)).To(Succeed())
+
+			t.run(func() {
+				serviceadaptertest.LoadFixture(t, filepath.Join(dir, "plan.json"), &plan)
+				serviceadaptertest.LoadFixture(t, filepath.Join(dir, "manifest.yml"), &manifest)
+			})
+
+			Expect(t.fatal).To(BeEmpty())
+			Expect(plan.Properties).To(Equal(serviceadapter.Properties{"persistence": true}))
+			Expect(manifest.Name).To(Equal("previous"))
+		})
+
+		It("rejects unknown fields", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "plan.json")
+			Expect(os.WriteFile(path, []byte(`{"instance_group": []}`), 0644)).To(Succeed())
+
+			t.run(func() { serviceadaptertest.LoadFixture(t, path, new(serviceadapter.Plan)) })
+
+			Expect(t.fatal).To(ContainSubstring(`unknown field "instance_group"`))
+		})
+	})
+})
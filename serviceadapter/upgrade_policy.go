@@ -0,0 +1,311 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+)
+
+// PlanTransition is the change generate-manifest is about to make to a
+// service instance. PreviousPlan and PreviousManifest are nil when the
+// instance is being created.
+type PlanTransition struct {
+	PreviousPlan     *Plan
+	Plan             Plan
+	PreviousManifest *bosh.BoshManifest
+	Manifest         bosh.BoshManifest
+}
+
+// NewPlanTransition returns the transition from the previous plan and
+// manifest in params to params.Plan and the newly generated manifest.
+func NewPlanTransition(params GenerateManifestParams, manifest bosh.BoshManifest) PlanTransition {
+	return PlanTransition{
+		PreviousPlan:     params.PreviousPlan,
+		Plan:             params.Plan,
+		PreviousManifest: params.PreviousManifest,
+		Manifest:         manifest,
+	}
+}
+
+// Violation describes why a transition is not allowed. Message is meant to
+// be shown to the user.
+type Violation struct {
+	Rule          string
+	InstanceGroup string
+	Message       string
+}
+
+type Violations []Violation
+
+// PlanChangeNotAllowedCode is the AdapterError code of generate-manifest
+// failures caused by Violations.
+const PlanChangeNotAllowedCode = "plan_change_not_allowed"
+
+func (v Violations) Error() string {
+	var messages []string
+	for _, violation := range v {
+		messages = append(messages, violation.Message)
+	}
+	return fmt.Sprintf("plan change not allowed: %s", strings.Join(messages, "; "))
+}
+
+type UpgradeRule interface {
+	Check(transition PlanTransition) Violations
+}
+
+type UpgradeRuleFunc func(transition PlanTransition) Violations
+
+func (f UpgradeRuleFunc) Check(transition PlanTransition) Violations {
+	return f(transition)
+}
+
+// UpgradePolicy is a set of rules that every transition must satisfy.
+type UpgradePolicy []UpgradeRule
+
+// Evaluate checks the transition against every rule and returns the
+// Violations found, or nil.
+func (p UpgradePolicy) Evaluate(transition PlanTransition) error {
+	var violations Violations
+	for _, rule := range p {
+		violations = append(violations, rule.Check(transition)...)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return violations
+}
+
+// NoInstanceGroupRemoval rejects removing an instance group from the
+// manifest, unless a new instance group lists it in migrated_from. Errands
+// hold no state and can be removed.
+func NoInstanceGroupRemoval() UpgradeRule {
+	return UpgradeRuleFunc(func(transition PlanTransition) Violations {
+		if transition.PreviousManifest == nil {
+			return nil
+		}
+
+		var violations Violations
+		for _, previous := range transition.PreviousManifest.InstanceGroups {
+			if previous.Lifecycle == "errand" {
+				continue
+			}
+			if _, found := transition.successorOf(previous.Name); !found {
+				violations = append(violations, Violation{
+					Rule:          "no-instance-group-removal",
+					InstanceGroup: previous.Name,
+					Message:       fmt.Sprintf("instance group '%s' cannot be removed", previous.Name),
+				})
+			}
+		}
+		return violations
+	})
+}
+
+// MinimumInstances rejects scaling any of the named instance groups down to
+// fewer than min instances.
+func MinimumInstances(min int, instanceGroups ...string) UpgradeRule {
+	return forEachInstanceGroup(instanceGroups, func(previous, current bosh.InstanceGroup) *Violation {
+		if current.Instances >= min || current.Instances >= previous.Instances {
+			return nil
+		}
+		return &Violation{
+			Rule:          "minimum-instances",
+			InstanceGroup: current.Name,
+			Message:       fmt.Sprintf("instance group '%s' cannot be scaled down to %d instances, the minimum is %d", current.Name, current.Instances, min),
+		}
+	})
+}
+
+// PreserveQuorum rejects scaling any of the named instance groups down by
+// so many instances at once that a majority of the previous instances would
+// be lost.
+func PreserveQuorum(instanceGroups ...string) UpgradeRule {
+	return forEachInstanceGroup(instanceGroups, func(previous, current bosh.InstanceGroup) *Violation {
+		quorum := previous.Instances/2 + 1
+		if current.Instances >= quorum || current.Instances >= previous.Instances {
+			return nil
+		}
+		return &Violation{
+			Rule:          "preserve-quorum",
+			InstanceGroup: current.Name,
+			Message: fmt.Sprintf(
+				"instance group '%s' cannot be scaled down from %d to %d instances at once, as it would lose quorum; scale down to no fewer than %d instances first",
+				current.Name, previous.Instances, current.Instances, quorum,
+			),
+		}
+	})
+}
+
+// NoPersistentDiskDowngrade rejects changing the persistent disk of an
+// instance group to a smaller one, or removing it. A disk is either a
+// persistent_disk_type or a persistent_disk size in MB. diskTypes lists the
+// persistent disk types from smallest to largest; changes involving types
+// not in the list, or between a type and a size, are allowed.
+func NoPersistentDiskDowngrade(diskTypes ...string) UpgradeRule {
+	sizes := map[string]int{}
+	for i, diskType := range diskTypes {
+		sizes[diskType] = i + 1
+	}
+	hasDisk := func(instanceGroup bosh.InstanceGroup) bool {
+		return instanceGroup.PersistentDiskType != "" || instanceGroup.PersistentDisk > 0
+	}
+
+	return forEachInstanceGroup(nil, func(previous, current bosh.InstanceGroup) *Violation {
+		if !hasDisk(previous) {
+			return nil
+		}
+		previousSize, knownPrevious := sizes[previous.PersistentDiskType]
+		currentSize, knownCurrent := sizes[current.PersistentDiskType]
+
+		switch {
+		case !hasDisk(current):
+			return &Violation{
+				Rule:          "no-persistent-disk-downgrade",
+				InstanceGroup: current.Name,
+				Message:       fmt.Sprintf("instance group '%s' cannot have its persistent disk removed", current.Name),
+			}
+		case current.PersistentDisk > 0 && current.PersistentDisk < previous.PersistentDisk:
+			return &Violation{
+				Rule:          "no-persistent-disk-downgrade",
+				InstanceGroup: current.Name,
+				Message: fmt.Sprintf(
+					"instance group '%s' cannot have its persistent disk shrunk from %d MB to %d MB",
+					current.Name, previous.PersistentDisk, current.PersistentDisk,
+				),
+			}
+		case knownPrevious && knownCurrent && currentSize < previousSize:
+			return &Violation{
+				Rule:          "no-persistent-disk-downgrade",
+				InstanceGroup: current.Name,
+				Message: fmt.Sprintf(
+					"instance group '%s' cannot have its persistent disk type changed from '%s' to the smaller '%s'",
+					current.Name, previous.PersistentDiskType, current.PersistentDiskType,
+				),
+			}
+		}
+		return nil
+	})
+}
+
+// NoAZRemoval rejects removing an availability zone from an instance group.
+func NoAZRemoval() UpgradeRule {
+	return forEachInstanceGroup(nil, func(previous, current bosh.InstanceGroup) *Violation {
+		currentAZs := map[string]bool{}
+		for _, az := range current.AZs {
+			currentAZs[az] = true
+		}
+
+		var removed []string
+		for _, az := range previous.AZs {
+			if !currentAZs[az] {
+				removed = append(removed, az)
+			}
+		}
+		if len(removed) == 0 {
+			return nil
+		}
+		return &Violation{
+			Rule:          "no-az-removal",
+			InstanceGroup: current.Name,
+			Message:       fmt.Sprintf("instance group '%s' cannot be removed from availability zones: %s", current.Name, strings.Join(removed, ", ")),
+		}
+	})
+}
+
+// NoPlanPropertyChange rejects changing the value of any of the named plan
+// properties, such as a persistence setting that the deployed data depends
+// on, when the plan of an instance changes.
+func NoPlanPropertyChange(properties ...string) UpgradeRule {
+	return UpgradeRuleFunc(func(transition PlanTransition) Violations {
+		if transition.PreviousPlan == nil {
+			return nil
+		}
+
+		var violations Violations
+		for _, property := range properties {
+			previous, current := transition.PreviousPlan.Properties[property], transition.Plan.Properties[property]
+			if reflect.DeepEqual(previous, current) {
+				continue
+			}
+			violations = append(violations, Violation{
+				Rule:    "no-plan-property-change",
+				Message: fmt.Sprintf("plan property '%s' cannot be changed from %v to %v", property, previous, current),
+			})
+		}
+		return violations
+	})
+}
+
+// forEachInstanceGroup checks every instance group in the new manifest that
+// also exists in the previous one, by name or through migrated_from. When
+// names is not empty only those instance groups are checked.
+func forEachInstanceGroup(names []string, check func(previous, current bosh.InstanceGroup) *Violation) UpgradeRule {
+	return UpgradeRuleFunc(func(transition PlanTransition) Violations {
+		if transition.PreviousManifest == nil {
+			return nil
+		}
+
+		var violations Violations
+		for _, current := range transition.Manifest.InstanceGroups {
+			if len(names) > 0 && !slices.Contains(names, current.Name) {
+				continue
+			}
+			previous, found := transition.predecessorOf(current)
+			if !found {
+				continue
+			}
+			if violation := check(previous, current); violation != nil {
+				violations = append(violations, *violation)
+			}
+		}
+		return violations
+	})
+}
+
+func (t PlanTransition) predecessorOf(current bosh.InstanceGroup) (bosh.InstanceGroup, bool) {
+	for _, previous := range t.PreviousManifest.InstanceGroups {
+		if previous.Name == current.Name {
+			return previous, true
+		}
+	}
+	for _, migration := range current.MigratedFrom {
+		for _, previous := range t.PreviousManifest.InstanceGroups {
+			if previous.Name == migration.Name {
+				return previous, true
+			}
+		}
+	}
+	return bosh.InstanceGroup{}, false
+}
+
+func (t PlanTransition) successorOf(previousName string) (bosh.InstanceGroup, bool) {
+	for _, current := range t.Manifest.InstanceGroups {
+		if current.Name == previousName {
+			return current, true
+		}
+		for _, migration := range current.MigratedFrom {
+			if migration.Name == previousName {
+				return current, true
+			}
+		}
+	}
+	return bosh.InstanceGroup{}, false
+}
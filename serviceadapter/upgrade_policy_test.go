@@ -0,0 +1,211 @@
+// Copyright (C) 2016-Present Pivotal Software, Inc. All rights reserved.
+
+// This program and the accompanying materials are made available under
+// the terms of the under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceadapter_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/on-demand-services-sdk/bosh"
+	"github.com/pivotal-cf/on-demand-services-sdk/serviceadapter"
+)
+
+var _ = Describe("UpgradePolicy", func() {
+	var (
+		previousManifest bosh.BoshManifest
+		manifest         bosh.BoshManifest
+		policy           serviceadapter.UpgradePolicy
+	)
+
+	instanceGroup := func(name string, instances int, diskType string, azs ...string) bosh.InstanceGroup {
+		return bosh.InstanceGroup{Name: name, Instances: instances, PersistentDiskType: diskType, AZs: azs}
+	}
+
+	BeforeEach(func() {
+		previousManifest = bosh.BoshManifest{InstanceGroups: []bosh.InstanceGroup{
+			instanceGroup("zookeeper", 5, "medium", "z1", "z2", "z3"),
+			instanceGroup("kafka", 3, "large", "z1", "z2"),
+		}}
+		manifest = bosh.BoshManifest{InstanceGroups: []bosh.InstanceGroup{
+			instanceGroup("zookeeper", 5, "medium", "z1", "z2", "z3"),
+			instanceGroup("kafka", 3, "large", "z1", "z2"),
+		}}
+		policy = serviceadapter.UpgradePolicy{
+			serviceadapter.NoInstanceGroupRemoval(),
+			serviceadapter.MinimumInstances(3, "zookeeper"),
+			serviceadapter.PreserveQuorum("zookeeper"),
+			serviceadapter.NoPersistentDiskDowngrade("small", "medium", "large"),
+			serviceadapter.NoAZRemoval(),
+		}
+	})
+
+	evaluate := func() error {
+		return policy.Evaluate(serviceadapter.NewPlanTransition(
+			serviceadapter.GenerateManifestParams{PreviousManifest: &previousManifest},
+			manifest,
+		))
+	}
+
+	violations := func() serviceadapter.Violations {
+		err := evaluate()
+		var v serviceadapter.Violations
+		Expect(errors.As(err, &v)).To(BeTrue(), "expected violations, got %v", err)
+		return v
+	}
+
+	It("allows an unchanged deployment", func() {
+		Expect(evaluate()).To(Succeed())
+	})
+
+	It("allows anything when creating an instance", func() {
+		manifest.InstanceGroups[0].Instances = 1
+		err := policy.Evaluate(serviceadapter.PlanTransition{Manifest: manifest})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("allows scaling up, adding AZs and larger disks", func() {
+		manifest.InstanceGroups[1] = instanceGroup("kafka", 5, "xlarge", "z1", "z2", "z3")
+		manifest.InstanceGroups[0].PersistentDiskType = "large"
+		Expect(evaluate()).To(Succeed())
+	})
+
+	It("rejects removing an instance group", func() {
+		manifest.InstanceGroups = manifest.InstanceGroups[:1]
+
+		Expect(violations()).To(ConsistOf(serviceadapter.Violation{
+			Rule:          "no-instance-group-removal",
+			InstanceGroup: "kafka",
+			Message:       "instance group 'kafka' cannot be removed",
+		}))
+	})
+
+	It("allows removing an errand", func() {
+		errand := instanceGroup("smoke-tests", 1, "")
+		errand.Lifecycle = "errand"
+		previousManifest.InstanceGroups = append(previousManifest.InstanceGroups, errand)
+
+		Expect(evaluate()).To(Succeed())
+	})
+
+	It("rejects changing the named plan properties", func() {
+		policy = serviceadapter.UpgradePolicy{serviceadapter.NoPlanPropertyChange("persistence", "tls")}
+		transition := serviceadapter.PlanTransition{
+			PreviousPlan: &serviceadapter.Plan{Properties: serviceadapter.Properties{"persistence": true, "tls": true, "size": 1}},
+			Plan:         serviceadapter.Plan{Properties: serviceadapter.Properties{"persistence": false, "tls": true, "size": 2}},
+		}
+
+		Expect(policy.Evaluate(transition)).To(MatchError("plan change not allowed: plan property 'persistence' cannot be changed from true to false"))
+		Expect(policy.Evaluate(serviceadapter.PlanTransition{Plan: transition.Plan})).To(Succeed())
+	})
+
+	It("allows renaming an instance group with migrated_from, checking it against its predecessor", func() {
+		manifest.InstanceGroups[1] = instanceGroup("broker", 3, "large", "z1")
+		manifest.InstanceGroups[1].MigratedFrom = []bosh.Migration{{Name: "kafka"}}
+
+		Expect(violations()).To(ConsistOf(serviceadapter.Violation{
+			Rule:          "no-az-removal",
+			InstanceGroup: "broker",
+			Message:       "instance group 'broker' cannot be removed from availability zones: z2",
+		}))
+	})
+
+	It("rejects scaling below the minimum or losing quorum", func() {
+		manifest.InstanceGroups[0].Instances = 2
+
+		Expect(violations()).To(ConsistOf(
+			serviceadapter.Violation{
+				Rule:          "minimum-instances",
+				InstanceGroup: "zookeeper",
+				Message:       "instance group 'zookeeper' cannot be scaled down to 2 instances, the minimum is 3",
+			},
+			serviceadapter.Violation{
+				Rule:          "preserve-quorum",
+				InstanceGroup: "zookeeper",
+				Message:       "instance group 'zookeeper' cannot be scaled down from 5 to 2 instances at once, as it would lose quorum; scale down to no fewer than 3 instances first",
+			},
+		))
+	})
+
+	It("only applies instance rules to the named instance groups", func() {
+		manifest.InstanceGroups[1].Instances = 1
+		Expect(evaluate()).To(Succeed())
+	})
+
+	It("rejects smaller or removed persistent disks", func() {
+		manifest.InstanceGroups[0].PersistentDiskType = "small"
+		manifest.InstanceGroups[1].PersistentDiskType = ""
+
+		Expect(violations()).To(ConsistOf(
+			serviceadapter.Violation{
+				Rule:          "no-persistent-disk-downgrade",
+				InstanceGroup: "zookeeper",
+				Message:       "instance group 'zookeeper' cannot have its persistent disk type changed from 'medium' to the smaller 'small'",
+			},
+			serviceadapter.Violation{
+				Rule:          "no-persistent-disk-downgrade",
+				InstanceGroup: "kafka",
+				Message:       "instance group 'kafka' cannot have its persistent disk removed",
+			},
+		))
+	})
+
+	It("allows switching between a persistent disk type and a sized persistent disk", func() {
+		manifest.InstanceGroups[0].PersistentDiskType = ""
+		manifest.InstanceGroups[0].PersistentDisk = 10240
+		Expect(evaluate()).To(Succeed())
+
+		previousManifest.InstanceGroups[1].PersistentDiskType = ""
+		previousManifest.InstanceGroups[1].PersistentDisk = 10240
+		Expect(evaluate()).To(Succeed())
+	})
+
+	It("rejects a smaller sized persistent disk", func() {
+		previousManifest.InstanceGroups[0].PersistentDiskType = ""
+		previousManifest.InstanceGroups[0].PersistentDisk = 10240
+		manifest.InstanceGroups[0].PersistentDiskType = ""
+		manifest.InstanceGroups[0].PersistentDisk = 1024
+
+		Expect(violations()).To(ConsistOf(serviceadapter.Violation{
+			Rule:          "no-persistent-disk-downgrade",
+			InstanceGroup: "zookeeper",
+			Message:       "instance group 'zookeeper' cannot have its persistent disk shrunk from 10240 MB to 1024 MB",
+		}))
+	})
+
+	It("supports custom rules and reports all violations in the error", func() {
+		policy = serviceadapter.UpgradePolicy{
+			serviceadapter.NoInstanceGroupRemoval(),
+			serviceadapter.UpgradeRuleFunc(func(transition serviceadapter.PlanTransition) serviceadapter.Violations {
+				if transition.Plan.Properties["frozen"] == true {
+					return serviceadapter.Violations{{Rule: "frozen", Message: "this plan cannot be changed"}}
+				}
+				return nil
+			}),
+		}
+		manifest.InstanceGroups = nil
+
+		err := policy.Evaluate(serviceadapter.NewPlanTransition(serviceadapter.GenerateManifestParams{
+			Plan:             serviceadapter.Plan{Properties: serviceadapter.Properties{"frozen": true}},
+			PreviousManifest: &previousManifest,
+		}, manifest))
+
+		Expect(err).To(MatchError(
+			"plan change not allowed: instance group 'zookeeper' cannot be removed; instance group 'kafka' cannot be removed; this plan cannot be changed",
+		))
+	})
+})